@@ -0,0 +1,37 @@
+// Command migratestore copies users, API tokens, identity links, carts,
+// ledgers, games, results, saved scenarios and classrooms from one store
+// backend to another, e.g. from the flat files into SQLite:
+//
+//	go run ./cmd/migratestore -from file -from-path . -to sqlite -to-path ecology.db
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+)
+
+func main() {
+	from := flag.String("from", store.BackendFile, "source backend (file or sqlite)")
+	fromPath := flag.String("from-path", ".", "source data directory or database file")
+	to := flag.String("to", store.BackendSQLite, "destination backend (file or sqlite)")
+	toPath := flag.String("to-path", "ecology.db", "destination data directory or database file")
+	flag.Parse()
+
+	src, err := store.Open(*from, *fromPath)
+	if err != nil {
+		log.Fatalf("Error opening source store: %v", err)
+	}
+	defer src.Close()
+	dst, err := store.Open(*to, *toPath)
+	if err != nil {
+		log.Fatalf("Error opening destination store: %v", err)
+	}
+	defer dst.Close()
+
+	if err := store.Copy(dst, src); err != nil {
+		log.Fatalf("Error copying store: %v", err)
+	}
+	log.Printf("Copied %s store at %s to %s store at %s", *from, *fromPath, *to, *toPath)
+}
@@ -0,0 +1,32 @@
+// Command mockoidc runs a mock OpenID Connect provider for trying the OIDC
+// login locally, without a real identity provider:
+//
+//	go run ./cmd/mockoidc -addr :9000 -client-id ecology-map
+//
+// It approves every login, so never run it where others can reach it.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, as configured in oidc_providers.json")
+	clientID := flag.String("client-id", "ecology-map", "client ID the server uses")
+	clientSecret := flag.String("client-secret", "", "client secret the server uses, if any")
+	domain := flag.String("email-domain", "example.edu", "domain of users' email addresses")
+	flag.Parse()
+
+	is, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("Error creating issuer: %v", err)
+	}
+	is.EmailDomain = *domain
+	log.Printf("Mock OIDC issuer %s listening on %s", is.URL, *addr)
+	log.Fatal(http.ListenAndServe(*addr, is))
+}
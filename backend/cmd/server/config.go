@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+)
+
+// Config is where the server listens and keeps its data. Every field can be
+// set from the environment; unset variables keep the defaults, which match
+// running the server from the backend directory.
+type Config struct {
+	Addr string // LISTEN_ADDR
+
+	// StoreBackend picks where users, sessions, carts, ledgers and games are
+	// kept: "file" (flat files under StorePath) or "sqlite" (a database file
+	// at StorePath, ecology.db by default).
+	StoreBackend string // STORE_BACKEND
+	StorePath    string // STORE_PATH
+
+	// AdminUsers are given the admin role at startup, which lets them act
+	// on other players' data and use the /admin routes.
+	AdminUsers []string // ADMIN_USERS, comma-separated
+
+	// Sessions end after SessionIdleTimeout without a request, or
+	// SessionAbsoluteTimeout after login.
+	SessionIdleTimeout     time.Duration // SESSION_IDLE_TIMEOUT
+	SessionAbsoluteTimeout time.Duration // SESSION_ABSOLUTE_TIMEOUT
+
+	GameConfigFile   string // GAME_CONFIG
+	AchievementsFile string // ACHIEVEMENTS_FILE
+	GameScenarioDir  string // GAME_SCENARIO_DIR: event scenarios for games
+	SavedScenarioDir string // SCENARIO_DIR: saved portfolios from before the store, imported at startup
+
+	// Notifier delivers messages such as password reset tokens: "log"
+	// writes them to the server log, "file" appends them to NotifierFile.
+	Notifier     string // NOTIFIER
+	NotifierFile string // NOTIFIER_FILE
+
+	// OIDCProvidersFile lists the OpenID Connect providers users can log in
+	// with; without it only passwords work. After a provider login the
+	// browser is sent to OIDCReturnURL, usually the frontend, or shown JSON
+	// if it is empty.
+	OIDCProvidersFile string // OIDC_PROVIDERS_FILE
+	OIDCReturnURL     string // OIDC_RETURN_URL
+
+	// ScoringProfilesFile keeps the scoring profiles admins add and which
+	// one is active.
+	ScoringProfilesFile string // SCORING_PROFILES_FILE
+
+	// GameTickInterval is how often scheduled games advance by a quarter.
+	GameTickInterval time.Duration // GAME_TICK_INTERVAL
+	// ShutdownTimeout bounds how long in-flight requests get to finish.
+	ShutdownTimeout time.Duration // SHUTDOWN_TIMEOUT
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden.
+func DefaultConfig() Config {
+	return Config{
+		Addr:                   ":8080",
+		StoreBackend:           "file",
+		SessionIdleTimeout:     session.DefaultIdleTimeout,
+		SessionAbsoluteTimeout: session.DefaultAbsoluteTimeout,
+		GameConfigFile:         "game_config.json",
+		AchievementsFile:       "achievements.json",
+		ScoringProfilesFile:    "scoring_profiles.json",
+		Notifier:               "log",
+		NotifierFile:           "notifications.log",
+		OIDCProvidersFile:      "oidc_providers.json",
+		GameScenarioDir:        "./game_scenarios",
+		SavedScenarioDir:       "./scenarios",
+		GameTickInterval:       time.Minute,
+		ShutdownTimeout:        10 * time.Second,
+	}
+}
+
+// ConfigFromEnv returns the default configuration with any environment
+// overrides applied.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	setString(&cfg.Addr, "LISTEN_ADDR")
+	setString(&cfg.StoreBackend, "STORE_BACKEND")
+	setString(&cfg.StorePath, "STORE_PATH")
+	setList(&cfg.AdminUsers, "ADMIN_USERS")
+	setDuration(&cfg.SessionIdleTimeout, "SESSION_IDLE_TIMEOUT")
+	setDuration(&cfg.SessionAbsoluteTimeout, "SESSION_ABSOLUTE_TIMEOUT")
+	setString(&cfg.GameConfigFile, "GAME_CONFIG")
+	setString(&cfg.AchievementsFile, "ACHIEVEMENTS_FILE")
+	setString(&cfg.ScoringProfilesFile, "SCORING_PROFILES_FILE")
+	setString(&cfg.Notifier, "NOTIFIER")
+	setString(&cfg.NotifierFile, "NOTIFIER_FILE")
+	setString(&cfg.OIDCProvidersFile, "OIDC_PROVIDERS_FILE")
+	setString(&cfg.OIDCReturnURL, "OIDC_RETURN_URL")
+	setString(&cfg.GameScenarioDir, "GAME_SCENARIO_DIR")
+	setString(&cfg.SavedScenarioDir, "SCENARIO_DIR")
+	setDuration(&cfg.GameTickInterval, "GAME_TICK_INTERVAL")
+	setDuration(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT")
+	return cfg
+}
+
+func setString(field *string, key string) {
+	if v := os.Getenv(key); v != "" {
+		*field = v
+	}
+}
+
+func setList(field *[]string, key string) {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	if len(list) > 0 {
+		*field = list
+	}
+}
+
+func setDuration(field *time.Duration, key string) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Ignoring %s=%q: not a positive duration", key, v)
+		return
+	}
+	*field = d
+}
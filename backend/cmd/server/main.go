@@ -1,31 +1,242 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/classroom"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/handlers"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/notify"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/scenario"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
 )
 
+// sessionSweepInterval is how often expired sessions are cleared out.
+const sessionSweepInterval = 5 * time.Minute
+
 func main() {
-	// Example usage of your “load users, define routes, start server” logic
-	err := user.LoadUserPasswords("users.txt")
+	cfg := ConfigFromEnv()
+
+	st, err := store.Open(cfg.StoreBackend, cfg.StorePath)
+	if err != nil {
+		log.Fatalf("Error opening store: %v\n", err)
+	}
+	store.SetDefault(st)
+	notifier, err := notify.Open(cfg.Notifier, cfg.NotifierFile)
+	if err != nil {
+		st.Close()
+		log.Fatalf("Error opening notifier: %v\n", err)
+	}
+	notify.SetDefault(notifier)
+	oidc.SetReturnURL(cfg.OIDCReturnURL)
+	session.SetTimeouts(cfg.SessionIdleTimeout, cfg.SessionAbsoluteTimeout)
+
+	if err := loadState(cfg); err != nil {
+		st.Close()
+		log.Fatalf("Error loading %v\n", err)
+	}
+	// Games started with auto_advance move forward one quarter per interval.
+	stopScheduler := game.StartScheduler(cfg.GameTickInterval)
+	stopSweeper := session.StartSweeper(sessionSweepInterval)
+
+	registerRoutes()
+	srv := &http.Server{Addr: cfg.Addr}
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("Starting server on %s ...\n", cfg.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		stopSweeper()
+		stopScheduler()
+		st.Close()
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down ...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+	stopSweeper()
+	stopScheduler()
+	if err := flushState(); err != nil {
+		log.Printf("Error flushing state: %v", err)
+	}
+	if err := st.Close(); err != nil {
+		log.Printf("Error closing store: %v", err)
+	}
+	fmt.Println("Server stopped")
+}
+
+// loadState loads everything the server keeps in memory, in dependency
+// order, and reports which step failed. The store must already be set.
+func loadState(cfg Config) error {
+	steps := []struct {
+		name string
+		load func() error
+	}{
+		{"users", user.LoadUsers},
+		{"user keys", migrateKeys},
+		{"admins", func() error { return grantAdmins(cfg.AdminUsers) }},
+		{"sessions", session.LoadSessions},
+		{"api tokens", apitoken.Load},
+		{"oidc providers", func() error { return oidc.LoadProviders(cfg.OIDCProvidersFile) }},
+		{"identity links", oidc.LoadLinks},
+		{"classrooms", classroom.Load},
+		{"game config", func() error { return economy.LoadConfig(cfg.GameConfigFile) }},
+		{"scoring profiles", func() error { return data.LoadScoringProfiles(cfg.ScoringProfilesFile) }},
+		{"game scenarios", func() error { return game.LoadScenarios(cfg.GameScenarioDir) }},
+		{"carts", loadCarts},
+		{"games", game.LoadAllGames},
+		{"achievements", func() error { return game.LoadAchievements(cfg.AchievementsFile) }},
+		{"game results", game.LoadAllResults},
+		{"saved scenarios", func() error { return loadScenarios(cfg.SavedScenarioDir) }},
+	}
+	for _, step := range steps {
+		if err := step.load(); err != nil {
+			return fmt.Errorf("%s: %v", step.name, err)
+		}
+	}
+	return nil
+}
+
+// loadCarts loads every cart and prints the integrity report. Carts that
+// couldn't be recovered are reported, not fatal, so one bad cart doesn't keep
+// everyone else out.
+func loadCarts() error {
+	report, err := cart.LoadAllCarts()
 	if err != nil {
-		log.Fatalf("Error loading users: %v\n", err)
+		return err
+	}
+	fmt.Printf("Cart integrity: %s\n", report)
+	for _, issue := range report.Issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	for _, username := range report.Lost {
+		fmt.Printf("  %s: cart could not be recovered\n", username)
+	}
+	return nil
+}
+
+// migrateKeys moves data still stored under usernames over to user IDs.
+func migrateKeys() error {
+	renamed, err := user.MigrateKeys()
+	if err != nil {
+		return err
+	}
+	for username, id := range renamed {
+		fmt.Printf("Moved data for %s to user ID %s\n", username, id)
+	}
+	return nil
+}
+
+// loadScenarios loads the saved scenarios and imports any still in the
+// directory they were kept in before the store held them.
+func loadScenarios(legacyDir string) error {
+	if err := scenario.Load(); err != nil {
+		return err
 	}
+	imported, err := scenario.ImportDir(legacyDir)
+	if imported > 0 {
+		fmt.Printf("Imported saved scenarios of %d users from %s\n", imported, legacyDir)
+	}
+	return err
+}
 
+// grantAdmins gives the configured users the admin role. Names that aren't
+// registered yet are reported and skipped.
+func grantAdmins(usernames []string) error {
+	for _, username := range usernames {
+		if _, ok := user.Lookup(username); !ok {
+			fmt.Printf("Admin user %s is not registered; skipping\n", username)
+			continue
+		}
+		if err := user.SetRole(username, user.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushState writes every in-memory cart and game back to the store.
+func flushState() error {
+	if err := cart.FlushAll(); err != nil {
+		return fmt.Errorf("carts: %v", err)
+	}
+	if err := game.FlushAll(); err != nil {
+		return fmt.Errorf("games: %v", err)
+	}
+	return nil
+}
+
+// registerRoutes registers every handler on the default mux. Routes that read
+// or change a player's data require a logged-in session or an API token; the
+// /instructor and /admin routes also require the instructor or admin role.
+func registerRoutes() {
 	http.HandleFunc("/register", handlers.RegisterHandler)
 	http.HandleFunc("/login", handlers.LoginHandler)
 	http.HandleFunc("/profile", handlers.ProfileHandler)
 	http.HandleFunc("/logout", handlers.LogoutHandler)
+	http.HandleFunc("/logout/all", handlers.RequireAuth(handlers.LogoutAllHandler))
+	http.HandleFunc("/account/password", handlers.RequireSession(handlers.ChangePasswordHandler))
+	http.HandleFunc("/account/tokens", handlers.RequireSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListTokensHandler(w, r)
+		case http.MethodPost:
+			handlers.CreateTokenHandler(w, r)
+		case http.MethodDelete:
+			handlers.RevokeTokenHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/account/identities", handlers.RequireSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListIdentitiesHandler(w, r)
+		case http.MethodDelete:
+			handlers.UnlinkIdentityHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/account/identities/link", handlers.RequireSession(handlers.LinkIdentityHandler))
+	http.HandleFunc("/auth/oidc/providers", handlers.OIDCProvidersHandler)
+	http.HandleFunc("/auth/oidc/login", handlers.OIDCLoginHandler)
+	http.HandleFunc("/auth/oidc/callback", handlers.OIDCCallbackHandler)
+	http.HandleFunc("/account/password/reset/request", handlers.RequestPasswordResetHandler)
+	http.HandleFunc("/account/password/reset", handlers.ResetPasswordHandler)
 	http.HandleFunc("/alldatacenters", handlers.AllDataCentersHandler)
 	http.HandleFunc("/api/possible-datacenters", handlers.PossibleDataCenterHandler)
 	http.HandleFunc("/api/property-details", handlers.GetPropertyDetailsHandler)
-	http.HandleFunc("/cart/add", handlers.AddToCartHandler)
-	http.HandleFunc("/cart/item", handlers.DeleteCartItemHandler)
-	http.HandleFunc("/cart", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/cart/add", handlers.RequireAuth(handlers.AddToCartHandler))
+	http.HandleFunc("/cart/item", handlers.RequireAuth(handlers.DeleteCartItemHandler))
+	http.HandleFunc("/cart/transactions", handlers.RequireAuth(handlers.GetTransactionsHandler))
+	http.HandleFunc("/cart/upgrade", handlers.RequireAuth(handlers.OrderUpgradeHandler))
+	http.HandleFunc("/cart", handlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodDelete {
 			handlers.DeleteCartHandler(w, r)
 		} else if r.Method == http.MethodGet {
@@ -33,10 +244,98 @@ func main() {
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
-	http.HandleFunc("/api/simulation", handlers.GetUserClimateSimulationHandler)
-	http.HandleFunc("/cart/carbon-footprint", handlers.GetCarbonFootprintHandler)
+	}))
+	http.HandleFunc("/api/simulation", handlers.RequireAuth(handlers.GetUserClimateSimulationHandler))
+	http.HandleFunc("/cart/carbon-footprint", handlers.RequireAuth(handlers.GetCarbonFootprintHandler))
+	http.HandleFunc("/api/simulation/compare", handlers.RequireAuthReadOnly(handlers.CompareSimulationHandler))
+	http.HandleFunc("/api/simulation/stream", handlers.RequireAuth(handlers.StreamClimateSimulationHandler))
+	http.HandleFunc("/api/recommend", handlers.RecommendPortfolioHandler)
+	http.HandleFunc("/api/scoring-profiles", handlers.ScoringProfilesHandler)
+	http.HandleFunc("/api/pareto", handlers.ParetoFrontierHandler)
+	http.HandleFunc("/api/stress-test", handlers.RequireAuth(handlers.GetStressTestHandler))
+	http.HandleFunc("/api/economy", handlers.GetEconomyHandler)
+	http.HandleFunc("/api/economics", handlers.RequireAuth(handlers.GetEconomicsHandler))
+	http.HandleFunc("/api/demand", handlers.DemandHandler)
+	http.HandleFunc("/api/upgrades", handlers.UpgradesHandler)
+	http.HandleFunc("/game", handlers.RequireAuth(handlers.GetGameHandler))
+	http.HandleFunc("/game/new", handlers.RequireAuth(handlers.NewGameHandler))
+	http.HandleFunc("/game/advance", handlers.RequireAuth(handlers.AdvanceGameHandler))
+	http.HandleFunc("/game/recs", handlers.RequireAuth(handlers.SetRECShareHandler))
+	http.HandleFunc("/game/finish", handlers.RequireAuth(handlers.FinishGameHandler))
+	http.HandleFunc("/game/results", handlers.RequireAuth(handlers.GetResultsHandler))
+	http.HandleFunc("/api/achievements", handlers.AchievementsHandler)
+	http.HandleFunc("/api/leaderboard", handlers.LeaderboardHandler)
+	http.HandleFunc("/api/leaderboard/top", handlers.LeaderboardTopHandler)
+	http.HandleFunc("/api/leaderboard/me", handlers.RequireAuth(handlers.LeaderboardMeHandler))
+	http.HandleFunc("/api/carbon-policies", handlers.CarbonPoliciesHandler)
+	http.HandleFunc("/api/game-scenarios", handlers.GameScenariosHandler)
+	http.HandleFunc("/api/scenarios", handlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListScenariosHandler(w, r)
+		case http.MethodPost, http.MethodOptions:
+			handlers.SaveScenarioHandler(w, r)
+		case http.MethodDelete:
+			handlers.DeleteScenarioHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
 
-	fmt.Println("Starting server on :8080 ...")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	instructor := func(next http.HandlerFunc) http.HandlerFunc {
+		return handlers.RequireRole(user.RoleInstructor, next)
+	}
+	http.HandleFunc("/instructor/classrooms", instructor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListClassroomsHandler(w, r)
+		case http.MethodPost:
+			handlers.CreateClassroomHandler(w, r)
+		case http.MethodDelete:
+			handlers.DeleteClassroomHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/instructor/classrooms/students", instructor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ClassroomRosterHandler(w, r)
+		case http.MethodPost:
+			handlers.UpdateRosterHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/instructor/classrooms/games", instructor(handlers.StartClassroomGamesHandler))
+
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return handlers.RequireRole(user.RoleAdmin, next)
+	}
+	http.HandleFunc("/admin/users", admin(handlers.ListUsersHandler))
+	http.HandleFunc("/admin/users/role", admin(handlers.SetRoleHandler))
+	http.HandleFunc("/admin/users/logout", admin(handlers.LogoutUserHandler))
+	http.HandleFunc("/admin/datasets", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ListDatasetsHandler(w, r)
+		case http.MethodPut:
+			handlers.ReplaceDatasetHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/admin/scoring-profiles", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.ScoringProfilesHandler(w, r)
+		case http.MethodPut:
+			handlers.SaveScoringProfileHandler(w, r)
+		case http.MethodDelete:
+			handlers.DeleteScoringProfileHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	http.HandleFunc("/admin/scoring-profiles/active", admin(handlers.SetActiveProfileHandler))
 }
@@ -0,0 +1,248 @@
+// Package apitoken keeps personal API tokens, which let scripts call the API
+// with an "Authorization: Bearer" header instead of a session cookie. Tokens
+// are stored as SHA-256 hashes; the token itself is only returned once, when
+// it is created.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Scopes. A read-only token may only make requests that don't change data.
+const (
+	ScopeRead      = "read"
+	ScopeReadWrite = "read-write"
+)
+
+// Prefix starts every token, so they are easy to recognise in scripts and
+// secret scanners.
+const Prefix = "dcem_"
+
+// MaxPerUser is how many tokens a user may have at once.
+const MaxPerUser = 20
+
+// MaxNameLength bounds a token's name.
+const MaxNameLength = 64
+
+// MaxLifetime is the longest a token that expires may last.
+const MaxLifetime = 365 * 24 * time.Hour
+
+// usedInterval is how stale LastUsed may get before a request updates it, as
+// for sessions.
+const usedInterval = time.Minute
+
+// Token is an API token as its owner sees it. LastUsed and ExpiresAt are
+// null for tokens never used and tokens that don't expire.
+type Token struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+var (
+	tokens = make(map[string]*store.TokenRecord) // hash -> token
+	mu     sync.Mutex
+)
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func view(rec *store.TokenRecord) Token {
+	t := Token{ID: rec.ID, Name: rec.Name, Scope: rec.Scope, CreatedAt: rec.CreatedAt}
+	if !rec.LastUsed.IsZero() {
+		lastUsed := rec.LastUsed
+		t.LastUsed = &lastUsed
+	}
+	if !rec.ExpiresAt.IsZero() {
+		expiresAt := rec.ExpiresAt
+		t.ExpiresAt = &expiresAt
+	}
+	return t
+}
+
+// Load loads every token from the store. Tokens of users that no longer
+// exist are dropped. Users must be loaded first.
+func Load() error {
+	stored, err := store.Default().LoadTokens()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range stored {
+		rec := stored[i]
+		if _, ok := user.UsernameFor(rec.UserID); !ok {
+			if err := store.Default().DeleteToken(rec.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		tokens[rec.Hash] = &rec
+	}
+	return nil
+}
+
+// Create makes a new token for a user and returns it with its description.
+// The returned token string is the only copy. A zero ttl means the token
+// doesn't expire.
+func Create(username, name, scope string, ttl time.Duration) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxNameLength {
+		return "", Token{}, fmt.Errorf("token name must be 1 to %d characters", MaxNameLength)
+	}
+	if scope != ScopeRead && scope != ScopeReadWrite {
+		return "", Token{}, fmt.Errorf("unknown scope %q; use %q or %q", scope, ScopeRead, ScopeReadWrite)
+	}
+	if ttl < 0 || ttl > MaxLifetime {
+		return "", Token{}, fmt.Errorf("token lifetime must be at most %d days", MaxLifetime/(24*time.Hour))
+	}
+	userID, err := user.StoreKey(username)
+	if err != nil {
+		return "", Token{}, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return "", Token{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", Token{}, err
+	}
+	token := Prefix + secret
+
+	now := time.Now().UTC()
+	rec := &store.TokenRecord{ID: "tok_" + id, UserID: userID, Name: name, Hash: hash(token), Scope: scope, CreatedAt: now}
+	if ttl > 0 {
+		rec.ExpiresAt = now.Add(ttl)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	count := 0
+	for _, t := range tokens {
+		if t.UserID == userID {
+			count++
+		}
+	}
+	if count >= MaxPerUser {
+		return "", Token{}, fmt.Errorf("you already have %d tokens; revoke one first", MaxPerUser)
+	}
+	if err := store.Default().SaveToken(*rec); err != nil {
+		return "", Token{}, err
+	}
+	tokens[rec.Hash] = rec
+	return token, view(rec), nil
+}
+
+// List returns a user's tokens, newest first.
+func List(username string) []Token {
+	userID, _ := user.IDFor(username)
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Token, 0)
+	for _, t := range tokens {
+		if userID != "" && t.UserID == userID {
+			list = append(list, view(t))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Revoke deletes one of a user's tokens.
+func Revoke(username, id string) error {
+	userID, _ := user.IDFor(username)
+	mu.Lock()
+	defer mu.Unlock()
+	for h, t := range tokens {
+		if t.ID != id || t.UserID != userID {
+			continue
+		}
+		if err := store.Default().DeleteToken(id); err != nil {
+			return err
+		}
+		delete(tokens, h)
+		return nil
+	}
+	return fmt.Errorf("token %s not found", id)
+}
+
+// RevokeAll deletes every token of a user and returns how many there were.
+func RevokeAll(username string) (int, error) {
+	userID, ok := user.IDFor(username)
+	if !ok {
+		return 0, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	revoked := 0
+	for h, t := range tokens {
+		if t.UserID != userID {
+			continue
+		}
+		if err := store.Default().DeleteToken(t.ID); err != nil {
+			return revoked, err
+		}
+		delete(tokens, h)
+		revoked++
+	}
+	return revoked, nil
+}
+
+// Authenticate returns the user and scope a token grants. Expired tokens
+// are deleted and not found.
+func Authenticate(token string) (username, scope string, ok bool) {
+	if !strings.HasPrefix(token, Prefix) {
+		return "", "", false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	h := hash(token)
+	t, ok := tokens[h]
+	if !ok {
+		return "", "", false
+	}
+	now := time.Now().UTC()
+	if !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt) {
+		if err := store.Default().DeleteToken(t.ID); err != nil {
+			log.Printf("Error deleting expired token: %v", err)
+		}
+		delete(tokens, h)
+		return "", "", false
+	}
+	username, ok = user.UsernameFor(t.UserID)
+	if !ok {
+		return "", "", false
+	}
+	if now.Sub(t.LastUsed) >= usedInterval {
+		t.LastUsed = now
+		if err := store.Default().SaveToken(*t); err != nil {
+			log.Printf("Error recording token use: %v", err)
+		}
+	}
+	return username, t.Scope, true
+}
@@ -3,151 +3,343 @@ package cart
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
 )
 
 var (
 	// carts holds the in‑memory mapping from username to Cart.
-	carts   = make(map[string]*Cart)
-	cartMu  sync.RWMutex
-	cartDir = "./carts" // directory where cart files are stored
+	carts  = make(map[string]*Cart)
+	cartMu sync.RWMutex
 )
 
-type CartItem = data.DatacenterLocation
+// CartItem is a purchased site. The embedded location keeps the JSON flat, so
+// cart files written before PurchasePrice existed still load.
+type CartItem struct {
+	data.DatacenterLocation
+	PurchasePrice   float64          `json:"purchase_price,omitempty"` // USD debited for the site and its upgrades
+	PendingUpgrades []PendingUpgrade `json:"pending_upgrades,omitempty"`
+}
 
 // Cart represents a user's shopping cart.
 type Cart struct {
-	Username  string     `json:"username"`
-	Items     []CartItem `json:"items"`
-	MoneyLeft float64    `json:"money_left"`
+	Username   string     `json:"username"`
+	Items      []CartItem `json:"items"`
+	MoneyLeft  float64    `json:"money_left"`
+	JournalSeq int        `json:"journal_seq,omitempty"` // last journal entry reflected in this snapshot
 }
 
-// LoadAllCarts loads all cart files from disk when the app starts.
-func LoadAllCarts() error {
-	// Ensure the cart directory exists.
-	if err := os.MkdirAll(cartDir, 0755); err != nil {
-		return err
+// Locations returns the locations of all items in the cart.
+func (c *Cart) Locations() []data.DatacenterLocation {
+	locs := make([]data.DatacenterLocation, 0, len(c.Items))
+	for _, item := range c.Items {
+		locs = append(locs, item.DatacenterLocation)
+	}
+	return locs
+}
+
+// clone returns a copy of the cart that can be changed without touching it.
+func (c *Cart) clone() *Cart {
+	next := *c
+	next.Items = make([]CartItem, len(c.Items))
+	for i, item := range c.Items {
+		item.Upgrades = append([]string(nil), item.Upgrades...)
+		item.PendingUpgrades = append([]PendingUpgrade(nil), item.PendingUpgrades...)
+		next.Items[i] = item
 	}
-	files, err := ioutil.ReadDir(cartDir)
+	return &next
+}
+
+// LoadAllCarts loads all carts from the store when the app starts. Each
+// snapshot is checked against the user's journal: a snapshot that is behind
+// has the missing operations replayed, and one that is unreadable or missing
+// is rebuilt from the journal. Repaired snapshots are saved, and every cart's
+// balance is checked against its ledger. The report says what was done.
+// Users must be loaded first, since carts are stored by user ID.
+func LoadAllCarts() (IntegrityReport, error) {
+	var report IntegrityReport
+	docs, err := store.Default().LoadCarts()
 	if err != nil {
-		return err
+		return report, err
 	}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	journalUsers, err := store.Default().JournalUsers()
+	if err != nil {
+		return report, err
+	}
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	for _, id := range journalUsers {
+		if _, ok := docs[id]; !ok {
+			ids = append(ids, id)
 		}
-		if filepath.Ext(file.Name()) != ".cart" {
+	}
+	sort.Strings(ids)
+
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	for _, id := range ids {
+		username, ok := user.UsernameFor(id)
+		if !ok {
+			report.Issues = append(report.Issues, fmt.Sprintf("%s: cart belongs to no known user", id))
 			continue
 		}
-		username := file.Name()[0 : len(file.Name())-len(".cart")]
-		path := filepath.Join(cartDir, file.Name())
-		content, err := ioutil.ReadFile(path)
+		var snapshot *Cart
+		if content, ok := docs[id]; ok {
+			var c Cart
+			if err := json.Unmarshal(content, &c); err != nil {
+				report.Issues = append(report.Issues, fmt.Sprintf("%s: unreadable cart snapshot: %v", username, err))
+			} else {
+				snapshot = &c
+			}
+		}
+		c, repaired, err := recoverCart(username, snapshot, &report)
 		if err != nil {
-			fmt.Printf("Error reading cart file %s: %v\n", path, err)
-			continue
+			return report, err
 		}
-		var c Cart
-		if err := json.Unmarshal(content, &c); err != nil {
-			fmt.Printf("Error unmarshaling cart file %s: %v\n", path, err)
+		if c == nil {
 			continue
 		}
-		cartMu.Lock()
-		carts[username] = &c
-		cartMu.Unlock()
+		if c.Items == nil {
+			c.Items = []CartItem{}
+		}
+		carts[username] = c
+		report.Carts++
+		if repaired {
+			if err := SaveCartNoLock(username, c); err != nil {
+				report.Issues = append(report.Issues, fmt.Sprintf("%s: saving repaired snapshot: %v", username, err))
+			}
+		}
+		if entries, err := loadLedgerNoLock(username); err != nil {
+			report.Issues = append(report.Issues, err.Error())
+		} else if len(entries) > 0 {
+			if err := checkBalanceNoLock(username, c); err != nil {
+				report.Issues = append(report.Issues, err.Error())
+			}
+		}
 	}
-	return nil
+	return report, nil
 }
 
 // SaveCartNoLock saves the given cart assuming the lock is already held.
 func SaveCartNoLock(username string, c *Cart) error {
-	// Ensure the cart directory exists
-	if err := os.MkdirAll(cartDir, 0755); err != nil {
+	key, err := user.StoreKey(username)
+	if err != nil {
 		return err
 	}
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
 	}
-	path := filepath.Join(cartDir, username+".cart")
-	return ioutil.WriteFile(path, data, 0644)
+	return store.Default().SaveCart(key, data)
+}
+
+// FlushAll writes every cart snapshot to the store, for a clean shutdown.
+// Every change is already journaled, so this only saves replaying it later.
+func FlushAll() error {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	for username, c := range carts {
+		if err := SaveCartNoLock(username, c); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// GetCart returns the cart for a given user.
+// GetCart returns a copy of the cart for a given user, taken under the lock
+// since operations (including the game scheduler) replace the live cart.
 func GetCart(username string) (*Cart, bool) {
 	cartMu.RLock()
 	defer cartMu.RUnlock()
 	c, ok := carts[username]
-	return c, ok
+	if !ok {
+		return nil, false
+	}
+	return c.clone(), true
+}
+
+// getOrCreateCartNoLock returns the user's cart, creating it with the
+// configured starting funds (and a matching ledger grant) if needed.
+func getOrCreateCartNoLock(username string) (*Cart, error) {
+	if c, exists := carts[username]; exists {
+		return c, ensureOpeningNoLock(username, c)
+	}
+	c := &Cart{
+		Username:  username,
+		Items:     []CartItem{},
+		MoneyLeft: economy.StartingFunds(),
+	}
+	if err := appendLedgerNoLock(username, c, LedgerEntry{
+		Type:        EntryGrant,
+		Amount:      c.MoneyLeft,
+		Description: "Starting funds",
+	}); err != nil {
+		return nil, err
+	}
+	carts[username] = c
+	return c, nil
+}
+
+// GetItems returns a copy of the items in the user's cart.
+func GetItems(username string) []CartItem {
+	cartMu.RLock()
+	defer cartMu.RUnlock()
+	c, ok := carts[username]
+	if !ok {
+		return nil
+	}
+	return c.clone().Items
 }
 
-// AddToCart adds a datacenter item to the user's cart and deducts the cost.
-func AddToCart(username string, item data.DatacenterLocation, cost float64) error {
+// NetWorth returns the user's cash plus what their sites would fetch if sold.
+func NetWorth(username string) float64 {
+	cartMu.RLock()
+	defer cartMu.RUnlock()
+	c, ok := carts[username]
+	if !ok {
+		return economy.StartingFunds()
+	}
+	worth := c.MoneyLeft
+	for _, item := range c.Items {
+		worth += item.PurchasePrice * economy.GetConfig().ResaleRate
+	}
+	return worth
+}
+
+// Settle books an operating result (positive or negative) against the user's
+// balance and returns the new balance.
+func Settle(username string, amount float64, description string) (float64, error) {
 	cartMu.Lock()
 	defer cartMu.Unlock()
-	c, exists := carts[username]
-	if !exists {
-		// If no cart exists, create a new one with a default money value.
-		c = &Cart{
-			Username:  username,
-			Items:     []data.DatacenterLocation{},
-			MoneyLeft: 1000000, // starting funds (e.g., $1,000,000)
-		}
-		carts[username] = c
+	c, err := getOrCreateCartNoLock(username)
+	if err != nil {
+		return 0, err
+	}
+	next := c.clone()
+	next.MoneyLeft += amount
+	if err := applyNoLock(username, c, next, &LedgerEntry{
+		Type:        EntryOperations,
+		Amount:      amount,
+		Description: description,
+	}, JournalEntry{Op: OpSettle}); err != nil {
+		return 0, err
+	}
+	return c.MoneyLeft, nil
+}
+
+// AddToCart adds a datacenter item to the user's cart and deducts the price.
+// The price must come from the server-side pricing in the economy package.
+func AddToCart(username string, item data.DatacenterLocation, price float64) error {
+	if price <= 0 {
+		return fmt.Errorf("invalid price %f", price)
+	}
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	c, err := getOrCreateCartNoLock(username)
+	if err != nil {
+		return err
 	}
-	if c.MoneyLeft < cost {
-		return fmt.Errorf("insufficient funds: available %f, cost %f", c.MoneyLeft, cost)
+	if c.MoneyLeft < price {
+		return fmt.Errorf("insufficient funds: available %f, cost %f", c.MoneyLeft, price)
 	}
-	c.Items = append(c.Items, item)
-	c.MoneyLeft -= cost
-	// Use the no-lock version since the write lock is held.
-	return SaveCartNoLock(username, c)
+	next := c.clone()
+	next.Items = append(next.Items, CartItem{DatacenterLocation: item, PurchasePrice: price})
+	next.MoneyLeft -= price
+	return applyNoLock(username, c, next, &LedgerEntry{
+		Type:        EntryPurchase,
+		Amount:      -price,
+		SiteID:      item.ID,
+		Tier:        item.Tier,
+		Description: fmt.Sprintf("Bought %s", item.Name),
+	}, JournalEntry{Op: OpAdd, Item: &next.Items[len(next.Items)-1]})
 }
 
-// RemoveItemFromCart removes an item at the given index from the user's cart.
-func RemoveItemFromCart(username string, index int) error {
+// SellItem removes the item at the given index and refunds the configured
+// resale share of its purchase price. It returns the amount refunded.
+func SellItem(username string, index int) (float64, error) {
+	return removeItem(username, index, EntrySale, economy.GetConfig().ResaleRate)
+}
+
+// DemolishItem removes the item at the given index and refunds the configured
+// salvage share of its purchase price. It returns the amount refunded.
+func DemolishItem(username string, index int) (float64, error) {
+	return removeItem(username, index, EntryDemolition, economy.GetConfig().SalvageRate)
+}
+
+// removeItem takes an item out of the cart and credits rate × purchase price.
+func removeItem(username string, index int, entryType string, rate float64) (float64, error) {
 	cartMu.Lock()
 	defer cartMu.Unlock()
 
 	c, exists := carts[username]
 	if !exists {
-		return fmt.Errorf("cart not found for user %s", username)
+		return 0, fmt.Errorf("cart not found for user %s", username)
 	}
-
 	if index < 0 || index >= len(c.Items) {
-		return fmt.Errorf("invalid index %d", index)
+		return 0, fmt.Errorf("invalid index %d", index)
+	}
+	if err := ensureOpeningNoLock(username, c); err != nil {
+		return 0, err
 	}
 
-	// Remove the item by slicing it out
-	c.Items = append(c.Items[:index], c.Items[index+1:]...)
-	return SaveCartNoLock(username, c)
+	item := c.Items[index]
+	refund := item.PurchasePrice * rate
+	next := c.clone()
+	next.Items = append(next.Items[:index], next.Items[index+1:]...)
+	next.MoneyLeft += refund
+	verb := "Sold"
+	if entryType == EntryDemolition {
+		verb = "Demolished"
+	}
+	if err := applyNoLock(username, c, next, &LedgerEntry{
+		Type:        entryType,
+		Amount:      refund,
+		SiteID:      item.ID,
+		Tier:        item.Tier,
+		Description: fmt.Sprintf("%s %s", verb, item.Name),
+	}, JournalEntry{Op: OpRemove, Index: index}); err != nil {
+		return 0, err
+	}
+	return refund, nil
 }
 
-// DeleteCart deletes the entire cart for a user.
-func DeleteCart(username string) error {
+// ResetCart empties the user's cart and restores the starting funds. The
+// difference is booked as a reset entry, so the history is kept.
+func ResetCart(username string) error {
 	cartMu.Lock()
 	defer cartMu.Unlock()
 
-	// Remove from in-memory map
-	delete(carts, username)
-
-	// Remove the file on disk.
-	path := filepath.Join(cartDir, username+".cart")
-	if err := os.Remove(path); err != nil {
-		return fmt.Errorf("failed to delete cart file: %v", err)
+	c, exists := carts[username]
+	if !exists {
+		return fmt.Errorf("cart not found for user %s", username)
 	}
-	return nil
+	if err := ensureOpeningNoLock(username, c); err != nil {
+		return err
+	}
+
+	funds := economy.StartingFunds()
+	next := c.clone()
+	next.Items = []CartItem{}
+	next.MoneyLeft = funds
+	return applyNoLock(username, c, next, &LedgerEntry{
+		Type:        EntryReset,
+		Amount:      funds - c.MoneyLeft,
+		Description: "Cart reset to starting funds",
+	}, JournalEntry{Op: OpBase})
 }
 
 func CalculateCarbonFootprint(username string) (float64, error) {
 	cartMu.RLock()
+	defer cartMu.RUnlock()
 	c, exists := carts[username]
-	cartMu.RUnlock()
 	if !exists {
 		// if no cart, zero footprint
 		return 0, nil
@@ -155,7 +347,7 @@ func CalculateCarbonFootprint(username string) (float64, error) {
 
 	var totalCarbon float64
 	for _, item := range c.Items {
-		totalCarbon += computeCarbonForItem(item)
+		totalCarbon += computeCarbonForItem(item.DatacenterLocation)
 	}
 	return totalCarbon, nil
 }
@@ -169,8 +361,11 @@ func computeCarbonForItem(item data.DatacenterLocation) float64 {
 	base := 1.0
 	nameLower := strings.ToLower(item.Name)
 	notesLower := strings.ToLower(item.Notes)
+	tier, hasTier := data.GetTier(item.Tier)
 
 	switch {
+	case hasTier:
+		base = tier.CarbonFactor
 	case strings.Contains(nameLower, "eco") || strings.Contains(notesLower, "eco"):
 		base = 0.4
 	case strings.Contains(nameLower, "next-gen") || strings.Contains(notesLower, "next-gen"):
@@ -0,0 +1,347 @@
+package cart
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// useTempStore points the store at a fresh directory and forgets every cart,
+// ledger and journal position cached from an earlier test.
+func useTempStore(t *testing.T) *store.FileStore {
+	t.Helper()
+	st := store.NewFileStore(t.TempDir())
+	store.SetDefault(st)
+	forgetCarts()
+	return st
+}
+
+// forgetCarts empties the in-memory cart state, as a restart would.
+func forgetCarts() {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	carts = make(map[string]*Cart)
+	ledgers = make(map[string][]LedgerEntry)
+	journalSeqs = make(map[string]int)
+}
+
+// addUser registers a user in the current store. Users outlive a test, so
+// one left by an earlier run (go test -count) is reused.
+func addUser(t *testing.T, username string) {
+	t.Helper()
+	if user.Exists(username) {
+		return
+	}
+	if err := user.AddUser(username, ""); err != nil {
+		t.Fatalf("adding %s: %v", username, err)
+	}
+}
+
+func site(id int, name string) data.DatacenterLocation {
+	return data.DatacenterLocation{ID: id, Name: name, Tier: data.TierStandard}
+}
+
+// checkLedger fails the test unless the user's ledger sums to their balance
+// and the balance is want.
+func checkLedger(t *testing.T, username string, want float64) {
+	t.Helper()
+	c, ok := GetCart(username)
+	if !ok {
+		t.Fatalf("no cart for %s", username)
+	}
+	entries, err := GetLedger(username)
+	if err != nil {
+		t.Fatalf("reading ledger: %v", err)
+	}
+	var sum float64
+	for _, e := range entries {
+		sum += e.Amount
+	}
+	if math.Abs(sum-c.MoneyLeft) > balanceTolerance {
+		t.Errorf("ledger sums to %f but balance is %f", sum, c.MoneyLeft)
+	}
+	if math.Abs(c.MoneyLeft-want) > balanceTolerance {
+		t.Errorf("balance is %f, want %f", c.MoneyLeft, want)
+	}
+	if err := VerifyBalance(username); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLedgerMatchesBalance(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "ledger_player")
+	funds := economy.StartingFunds()
+	cfg := economy.GetConfig()
+
+	if err := AddToCart("ledger_player", site(1, "Ashburn"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(t, "ledger_player", funds-1000)
+	if err := AddToCart("ledger_player", site(2, "Phoenix"), 3000); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(t, "ledger_player", funds-4000)
+
+	refund, err := SellItem("ledger_player", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1000 * cfg.ResaleRate; math.Abs(refund-want) > balanceTolerance {
+		t.Errorf("sale refunded %f, want %f", refund, want)
+	}
+	checkLedger(t, "ledger_player", funds-4000+refund)
+
+	salvage, err := DemolishItem("ledger_player", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 3000 * cfg.SalvageRate; math.Abs(salvage-want) > balanceTolerance {
+		t.Errorf("demolition refunded %f, want %f", salvage, want)
+	}
+	checkLedger(t, "ledger_player", funds-4000+refund+salvage)
+
+	if _, err := Settle("ledger_player", -250, "Quarter 1"); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(t, "ledger_player", funds-4250+refund+salvage)
+
+	if err := AddToCart("ledger_player", site(3, "Seattle"), 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetCart("ledger_player"); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(t, "ledger_player", funds)
+	if items := GetItems("ledger_player"); len(items) != 0 {
+		t.Errorf("reset left %d items", len(items))
+	}
+}
+
+func TestRefusedChangeLeavesCart(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "refused_player")
+	funds := economy.StartingFunds()
+
+	if err := AddToCart("refused_player", site(1, "Ashburn"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToCart("refused_player", site(2, "Phoenix"), funds); err == nil {
+		t.Error("bought a site the player can't afford")
+	}
+	if _, err := SellItem("refused_player", 5); err == nil {
+		t.Error("sold an item that doesn't exist")
+	}
+	if items := GetItems("refused_player"); len(items) != 1 {
+		t.Errorf("cart has %d items, want 1", len(items))
+	}
+	checkLedger(t, "refused_player", funds-1000)
+}
+
+// failingJournal is a store whose journal writes fail while fail is set.
+type failingJournal struct {
+	store.Store
+	fail bool
+}
+
+func (f *failingJournal) AppendJournal(userID string, entry []byte) error {
+	if f.fail {
+		return errors.New("disk full")
+	}
+	return f.Store.AppendJournal(userID, entry)
+}
+
+func TestFailedCommitLeavesCart(t *testing.T) {
+	st := &failingJournal{Store: useTempStore(t)}
+	store.SetDefault(st)
+	addUser(t, "failing_player")
+	funds := economy.StartingFunds()
+
+	if err := AddToCart("failing_player", site(1, "Ashburn"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	st.fail = true
+	if err := AddToCart("failing_player", site(2, "Phoenix"), 2000); err == nil {
+		t.Fatal("purchase succeeded without a journal")
+	}
+	if _, err := SellItem("failing_player", 0); err == nil {
+		t.Fatal("sale succeeded without a journal")
+	}
+	if err := ResetCart("failing_player"); err == nil {
+		t.Fatal("reset succeeded without a journal")
+	}
+	if _, err := Settle("failing_player", 100, "Quarter 1"); err == nil {
+		t.Fatal("settlement succeeded without a journal")
+	}
+	if _, _, err := OrderUpgrade("failing_player", 0, data.UpgradeTier, data.TierEco, 0); err == nil {
+		t.Fatal("upgrade succeeded without a journal")
+	}
+
+	items := GetItems("failing_player")
+	if len(items) != 1 || items[0].Name != "Ashburn" || items[0].PurchasePrice != 1000 || items[0].Tier != data.TierStandard {
+		t.Errorf("cart changed after failed commits: %+v", items)
+	}
+	checkLedger(t, "failing_player", funds-1000)
+	entries, _ := GetLedger("failing_player")
+	reversals := 0
+	for _, e := range entries {
+		if e.Type == EntryReversal {
+			reversals++
+		}
+	}
+	if reversals != 5 {
+		t.Errorf("ledger has %d reversals, want 5", reversals)
+	}
+
+	// Once the journal works again the cart carries on where it was.
+	st.fail = false
+	if _, err := SellItem("failing_player", 0); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(t, "failing_player", funds-1000+1000*economy.GetConfig().ResaleRate)
+}
+
+// restart forgets the in-memory carts and loads them back from the store,
+// as the server does when it starts.
+func restart(t *testing.T) IntegrityReport {
+	t.Helper()
+	forgetCarts()
+	if err := user.LoadUsers(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := LoadAllCarts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) > 0 || len(report.Lost) > 0 {
+		t.Errorf("loading carts: %s: %v", report, report.Issues)
+	}
+	return report
+}
+
+func TestCartsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	store.SetDefault(store.NewFileStore(dir))
+	forgetCarts()
+	addUser(t, "restart_player")
+	if err := AddToCart("restart_player", site(1, "Ashburn"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToCart("restart_player", site(2, "Phoenix"), 3000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SellItem("restart_player", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Settle("restart_player", -250, "Quarter 1"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := GetCart("restart_player")
+	wantItems := GetItems("restart_player")
+	wantMoney := before.MoneyLeft
+	wantLedger, err := GetLedger("restart_player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(when string) {
+		t.Helper()
+		c, ok := GetCart("restart_player")
+		if !ok {
+			t.Fatalf("%s: cart is gone", when)
+		}
+		if !reflect.DeepEqual(c.Items, wantItems) {
+			t.Errorf("%s: items %+v, want %+v", when, c.Items, wantItems)
+		}
+		if c.MoneyLeft != wantMoney {
+			t.Errorf("%s: balance %f, want %f", when, c.MoneyLeft, wantMoney)
+		}
+		ledger, err := GetLedger("restart_player")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ledger, wantLedger) {
+			t.Errorf("%s: ledger %+v, want %+v", when, ledger, wantLedger)
+		}
+		checkLedger(t, "restart_player", wantMoney)
+	}
+
+	if err := FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+	if report := restart(t); report.OK != 1 {
+		t.Errorf("after a clean shutdown: %s", report)
+	}
+	check("after a clean shutdown")
+
+	// Without its snapshot the cart is rebuilt from the journal.
+	key, _ := user.StoreKey("restart_player")
+	if err := os.Remove(filepath.Join(dir, "carts", key+".cart")); err != nil {
+		t.Fatal(err)
+	}
+	if report := restart(t); report.Rebuilt != 1 {
+		t.Errorf("after losing the snapshot: %s", report)
+	}
+	check("after losing the snapshot")
+}
+
+// TestReadersDontShareTheCart settles quarters, as the game scheduler does,
+// while handlers read the cart. Run with -race.
+func TestReadersDontShareTheCart(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "busy_player")
+	for i := 1; i <= 3; i++ {
+		if err := AddToCart("busy_player", site(i, "Site"), 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if _, err := Settle("busy_player", 10, "Quarter"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		c, ok := GetCart("busy_player")
+		if !ok || len(c.Locations()) != 3 {
+			t.Fatalf("cart went missing or changed size: %v", c)
+		}
+		if _, err := json.Marshal(c); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CalculateCarbonFootprint("busy_player"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A copy taken earlier doesn't follow later changes.
+	before, _ := GetCart("busy_player")
+	if _, err := Settle("busy_player", 10, "Quarter"); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := GetCart("busy_player"); after.MoneyLeft == before.MoneyLeft {
+		t.Error("the balance didn't change")
+	}
+	before.Items[0].Name = "Renamed"
+	if c, _ := GetCart("busy_player"); c.Items[0].Name == "Renamed" {
+		t.Error("changing a copy changed the cart")
+	}
+}
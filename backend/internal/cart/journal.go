@@ -0,0 +1,277 @@
+package cart
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Journal operations. A base entry carries the whole cart; the others carry
+// just enough to redo one change on top of the cart before it.
+const (
+	OpBase   = "base"   // the whole cart, written first and on reset
+	OpAdd    = "add"    // Item appended
+	OpRemove = "remove" // item at Index removed
+	OpUpdate = "update" // item at Index replaced by Item
+	OpSettle = "settle" // balance changed, items untouched
+)
+
+// JournalEntry is one cart operation. Every entry records the balance after
+// it, so replaying never has to recompute prices.
+type JournalEntry struct {
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	Index     int       `json:"index,omitempty"`
+	Item      *CartItem `json:"item,omitempty"`
+	Cart      *Cart     `json:"cart,omitempty"`
+	MoneyLeft float64   `json:"money_left"`
+}
+
+// journalSeqs caches the last journal sequence number written per user.
+var journalSeqs = make(map[string]int)
+
+// lastJournalSeqNoLock returns the user's last journal sequence number,
+// reading the journal if it isn't cached yet.
+func lastJournalSeqNoLock(username string) (int, error) {
+	if seq, ok := journalSeqs[username]; ok {
+		return seq, nil
+	}
+	entries, _, err := loadJournal(username)
+	if err != nil {
+		return 0, err
+	}
+	seq := 0
+	if len(entries) > 0 {
+		seq = entries[len(entries)-1].Seq
+	}
+	journalSeqs[username] = seq
+	return seq, nil
+}
+
+// commitNoLock persists a change to the user's cart: the operation is
+// appended to the journal first and the snapshot is rewritten after, so a
+// crash in between is repaired by replaying the journal at startup. Once the
+// journal entry is written the change is committed; a snapshot that can't be
+// saved is only logged, since startup repairs it the same way. A user's first
+// journal entry is always a base entry with the whole cart, since the journal
+// must be able to rebuild the cart on its own. The lock must be held.
+func commitNoLock(username string, c *Cart, op JournalEntry) error {
+	seq, err := lastJournalSeqNoLock(username)
+	if err != nil {
+		return err
+	}
+	key, err := user.StoreKey(username)
+	if err != nil {
+		return err
+	}
+	if seq == 0 {
+		op = JournalEntry{Op: OpBase}
+	}
+	op.Seq = seq + 1
+	op.Time = time.Now().UTC()
+	op.MoneyLeft = c.MoneyLeft
+	if op.Op == OpBase {
+		snapshot := *c
+		snapshot.Items = append([]CartItem(nil), c.Items...)
+		snapshot.JournalSeq = 0
+		op.Cart = &snapshot
+	}
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	if err := store.Default().AppendJournal(key, line); err != nil {
+		return err
+	}
+	journalSeqs[username] = op.Seq
+	c.JournalSeq = op.Seq
+	if err := SaveCartNoLock(username, c); err != nil {
+		fmt.Printf("Error saving cart snapshot for %s (the journal has the change): %v\n", username, err)
+	}
+	return nil
+}
+
+// applyNoLock makes a change to the user's cart c that was worked out on
+// next, a clone of it. The ledger entry, if the change moves money, is
+// appended and checked first and the journal operation written after; c
+// only takes on the change once both are stored. If the balance check or
+// the journal write fails, the ledger entry is reversed, so the ledger still
+// sums to the unchanged balance. The lock must be held.
+func applyNoLock(username string, c, next *Cart, entry *LedgerEntry, op JournalEntry) error {
+	if entry != nil {
+		if err := appendLedgerNoLock(username, next, *entry); err != nil {
+			return err
+		}
+		if err := checkBalanceNoLock(username, next); err != nil {
+			reverseLedgerNoLock(username, c, *entry)
+			return err
+		}
+	}
+	if err := commitNoLock(username, next, op); err != nil {
+		if entry != nil {
+			reverseLedgerNoLock(username, c, *entry)
+		}
+		return err
+	}
+	*c = *next
+	return nil
+}
+
+// loadJournal reads and decodes a user's journal. Lines that don't decode
+// (a torn final append, or its remains once later entries were written) are
+// skipped and described in the returned issues.
+func loadJournal(username string) ([]JournalEntry, []string, error) {
+	key, err := user.StoreKey(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines, err := store.Default().LoadJournal(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []JournalEntry
+	var issues []string
+	for i, line := range lines {
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var e JournalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			issues = append(issues, fmt.Sprintf("%s: skipped unreadable journal line %d: %v", username, i+1, err))
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, issues, nil
+}
+
+// replay applies journal entries to a cart in order.
+func replay(c *Cart, entries []JournalEntry) error {
+	for _, e := range entries {
+		switch e.Op {
+		case OpBase:
+			if e.Cart == nil {
+				return fmt.Errorf("journal entry %d: base entry without a cart", e.Seq)
+			}
+			*c = *e.Cart
+			c.Items = append([]CartItem(nil), e.Cart.Items...)
+		case OpAdd:
+			if e.Item == nil {
+				return fmt.Errorf("journal entry %d: add without an item", e.Seq)
+			}
+			c.Items = append(c.Items, *e.Item)
+		case OpRemove:
+			if e.Index < 0 || e.Index >= len(c.Items) {
+				return fmt.Errorf("journal entry %d: no item at index %d", e.Seq, e.Index)
+			}
+			c.Items = append(c.Items[:e.Index], c.Items[e.Index+1:]...)
+		case OpUpdate:
+			if e.Item == nil || e.Index < 0 || e.Index >= len(c.Items) {
+				return fmt.Errorf("journal entry %d: bad update at index %d", e.Seq, e.Index)
+			}
+			c.Items[e.Index] = *e.Item
+		case OpSettle:
+		default:
+			return fmt.Errorf("journal entry %d: unknown op %q", e.Seq, e.Op)
+		}
+		c.MoneyLeft = e.MoneyLeft
+		c.JournalSeq = e.Seq
+	}
+	return nil
+}
+
+// IntegrityReport describes what LoadAllCarts found when it checked every
+// cart snapshot against its journal and ledger.
+type IntegrityReport struct {
+	Carts    int      `json:"carts"`    // carts loaded
+	OK       int      `json:"ok"`       // snapshot matched the journal
+	Replayed int      `json:"replayed"` // snapshot was behind; journal replayed on top
+	Rebuilt  int      `json:"rebuilt"`  // snapshot unreadable or missing; rebuilt from the journal
+	Lost     []string `json:"lost"`     // users whose cart couldn't be recovered
+	Issues   []string `json:"issues"`
+}
+
+// String summarises the report in one line.
+func (r IntegrityReport) String() string {
+	return fmt.Sprintf("%d carts loaded: %d ok, %d replayed, %d rebuilt, %d lost, %d issues",
+		r.Carts, r.OK, r.Replayed, r.Rebuilt, len(r.Lost), len(r.Issues))
+}
+
+// recoverCart builds a user's cart from its snapshot (nil if missing or
+// unreadable) and journal, recording what it did in the report. It returns
+// nil if nothing could be recovered, and whether the snapshot needs saving.
+func recoverCart(username string, snapshot *Cart, report *IntegrityReport) (*Cart, bool, error) {
+	entries, issues, err := loadJournal(username)
+	if err != nil {
+		return nil, false, err
+	}
+	report.Issues = append(report.Issues, issues...)
+	if len(entries) > 0 {
+		journalSeqs[username] = entries[len(entries)-1].Seq
+	} else {
+		journalSeqs[username] = 0
+	}
+
+	if snapshot != nil {
+		var pending []JournalEntry
+		for _, e := range entries {
+			if e.Seq > snapshot.JournalSeq {
+				pending = append(pending, e)
+			}
+		}
+		if len(pending) == 0 {
+			if len(entries) > 0 && snapshot.JournalSeq > journalSeqs[username] {
+				report.Issues = append(report.Issues, fmt.Sprintf("%s: snapshot is at journal entry %d but the journal ends at %d",
+					username, snapshot.JournalSeq, journalSeqs[username]))
+			}
+			report.OK++
+			return snapshot, false, nil
+		}
+		c := *snapshot
+		c.Items = append([]CartItem(nil), snapshot.Items...)
+		err := replay(&c, pending)
+		if err == nil {
+			report.Replayed++
+			return &c, true, nil
+		}
+		report.Issues = append(report.Issues, fmt.Sprintf("%s: replay onto snapshot failed: %v", username, err))
+	}
+
+	// Rebuild from the latest base entry.
+	start := -1
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Op == OpBase {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		if snapshot != nil {
+			// The snapshot is still the best we have.
+			report.OK++
+			return snapshot, false, nil
+		}
+		report.Lost = append(report.Lost, username)
+		return nil, false, nil
+	}
+	var c Cart
+	if err := replay(&c, entries[start:]); err != nil {
+		report.Issues = append(report.Issues, fmt.Sprintf("%s: rebuild from journal failed: %v", username, err))
+		if snapshot != nil {
+			report.OK++
+			return snapshot, false, nil
+		}
+		report.Lost = append(report.Lost, username)
+		return nil, false, nil
+	}
+	if c.Username == "" {
+		c.Username = username
+	}
+	report.Rebuilt++
+	return &c, true, nil
+}
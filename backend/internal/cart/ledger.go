@@ -0,0 +1,180 @@
+package cart
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Ledger entry types.
+const (
+	EntryGrant      = "grant"      // starting funds for a new cart
+	EntryOpening    = "opening"    // balance of a cart created before the ledger existed
+	EntryPurchase   = "purchase"   // a site bought through AddToCart
+	EntrySale       = "sale"       // a site sold back at the resale rate
+	EntryDemolition = "demolition" // a site torn down for its salvage value
+	EntryReset      = "reset"      // balance restored to the starting funds
+	EntryOperations = "operations" // revenue minus opex settled by a game tick
+	EntryUpgrade    = "upgrade"    // an upgrade ordered for an owned site
+	EntryReversal   = "reversal"   // undoes an entry whose cart change couldn't be stored
+)
+
+// balanceTolerance absorbs floating-point drift when summing the ledger.
+const balanceTolerance = 0.01
+
+// LedgerEntry records a single debit or credit against a user's balance.
+type LedgerEntry struct {
+	Seq         int       `json:"seq"`
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	Amount      float64   `json:"amount"`  // positive = credit, negative = debit
+	Balance     float64   `json:"balance"` // MoneyLeft after this entry
+	SiteID      int       `json:"site_id,omitempty"`
+	Tier        string    `json:"tier,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// ledgers caches each user's ledger; the store keeps them append-only.
+var ledgers = make(map[string][]LedgerEntry)
+
+// loadLedgerNoLock reads a user's ledger into memory if it isn't cached yet.
+// Unreadable lines are appends torn by a crash and are skipped; if one held
+// a real entry, the balance check reports the ledger as out of step.
+func loadLedgerNoLock(username string) ([]LedgerEntry, error) {
+	if entries, ok := ledgers[username]; ok {
+		return entries, nil
+	}
+	key, err := user.StoreKey(username)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := store.Default().LoadLedger(key)
+	if err != nil {
+		return nil, err
+	}
+	var entries []LedgerEntry
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var e LedgerEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			fmt.Printf("Skipping unreadable ledger line %d for %s: %v\n", i+1, username, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	ledgers[username] = entries
+	return entries, nil
+}
+
+// appendLedgerNoLock appends an entry to the user's ledger, filling in the
+// sequence number, timestamp and resulting balance. The lock must be held.
+func appendLedgerNoLock(username string, c *Cart, e LedgerEntry) error {
+	entries, err := loadLedgerNoLock(username)
+	if err != nil {
+		return err
+	}
+	e.Seq = len(entries) + 1
+	e.Time = time.Now().UTC()
+	e.Balance = c.MoneyLeft
+
+	key, err := user.StoreKey(username)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := store.Default().AppendLedger(key, line); err != nil {
+		return err
+	}
+
+	ledgers[username] = append(entries, e)
+	return nil
+}
+
+// reverseLedgerNoLock books the opposite of an entry whose change to the
+// cart c was abandoned, bringing the ledger back to c's balance. A failure
+// is only logged: the caller is already reporting one, and the balance check
+// at startup reports the ledger as out of step.
+func reverseLedgerNoLock(username string, c *Cart, e LedgerEntry) {
+	if err := appendLedgerNoLock(username, c, LedgerEntry{
+		Type:        EntryReversal,
+		Amount:      -e.Amount,
+		SiteID:      e.SiteID,
+		Tier:        e.Tier,
+		Description: "Reversed: " + e.Description,
+	}); err != nil {
+		fmt.Printf("Error reversing ledger entry for %s: %v\n", username, err)
+	}
+}
+
+// ensureOpeningNoLock gives carts that predate the ledger an opening entry for
+// their current balance, so the ledger always sums to MoneyLeft.
+func ensureOpeningNoLock(username string, c *Cart) error {
+	entries, err := loadLedgerNoLock(username)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return nil
+	}
+	return appendLedgerNoLock(username, c, LedgerEntry{
+		Type:        EntryOpening,
+		Amount:      c.MoneyLeft,
+		Description: "Opening balance",
+	})
+}
+
+// GetLedger returns a copy of the user's ledger, oldest entry first.
+func GetLedger(username string) ([]LedgerEntry, error) {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	entries, err := loadLedgerNoLock(username)
+	if err != nil {
+		return nil, err
+	}
+	return append([]LedgerEntry(nil), entries...), nil
+}
+
+// checkBalanceNoLock verifies the cart's balance invariants: the ledger sums
+// to MoneyLeft and its last entry records that same balance. Only operating
+// losses may take the balance below zero; purchases are refused first.
+// The lock must be held.
+func checkBalanceNoLock(username string, c *Cart) error {
+	entries, err := loadLedgerNoLock(username)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no ledger for %s", username)
+	}
+	var sum float64
+	for _, e := range entries {
+		sum += e.Amount
+	}
+	if math.Abs(sum-c.MoneyLeft) > balanceTolerance {
+		return fmt.Errorf("ledger for %s sums to %f but balance is %f", username, sum, c.MoneyLeft)
+	}
+	if last := entries[len(entries)-1]; math.Abs(last.Balance-c.MoneyLeft) > balanceTolerance {
+		return fmt.Errorf("last ledger entry for %s records %f but balance is %f", username, last.Balance, c.MoneyLeft)
+	}
+	return nil
+}
+
+// VerifyBalance checks a user's balance invariants.
+func VerifyBalance(username string) error {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	c, exists := carts[username]
+	if !exists {
+		return fmt.Errorf("cart not found for user %s", username)
+	}
+	return checkBalanceNoLock(username, c)
+}
@@ -0,0 +1,159 @@
+package cart
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// PendingUpgrade is an upgrade that has been paid for but isn't installed yet.
+type PendingUpgrade struct {
+	Upgrade   string    `json:"upgrade"`
+	Tier      string    `json:"tier,omitempty"` // target tier of a tier upgrade
+	Cost      float64   `json:"cost"`
+	OrderedAt time.Time `json:"ordered_at"`
+	ReadyTick int       `json:"ready_tick"` // game tick from which it takes effect
+}
+
+// OrderUpgrade pays for an upgrade on the item at the given index. nextTick is
+// the next tick of the player's game; the upgrade takes effect after its
+// installation delay from then on. Without a game (nextTick 0) it is
+// installed at once. The cost is added to the item's purchase price.
+func OrderUpgrade(username string, index int, upgradeID, tierID string, nextTick int) (CartItem, economy.UpgradeQuote, error) {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+
+	c, exists := carts[username]
+	if !exists {
+		return CartItem{}, economy.UpgradeQuote{}, fmt.Errorf("cart not found for user %s", username)
+	}
+	if index < 0 || index >= len(c.Items) {
+		return CartItem{}, economy.UpgradeQuote{}, fmt.Errorf("invalid index %d", index)
+	}
+	next := c.clone()
+	item := &next.Items[index]
+	for _, p := range item.PendingUpgrades {
+		if p.Upgrade == upgradeID {
+			return CartItem{}, economy.UpgradeQuote{}, fmt.Errorf("%s is already on order", upgradeID)
+		}
+	}
+	quote, err := economy.QuoteUpgrade(&item.DatacenterLocation, upgradeID, tierID)
+	if err != nil {
+		return CartItem{}, economy.UpgradeQuote{}, err
+	}
+	if next.MoneyLeft < quote.Cost {
+		return CartItem{}, economy.UpgradeQuote{}, fmt.Errorf("insufficient funds: available %f, cost %f", c.MoneyLeft, quote.Cost)
+	}
+	if err := ensureOpeningNoLock(username, c); err != nil {
+		return CartItem{}, economy.UpgradeQuote{}, err
+	}
+
+	next.MoneyLeft -= quote.Cost
+	item.PurchasePrice += quote.Cost
+	pending := PendingUpgrade{
+		Upgrade:   quote.Upgrade,
+		Tier:      quote.Tier,
+		Cost:      quote.Cost,
+		OrderedAt: time.Now().UTC(),
+	}
+	if nextTick > 0 {
+		pending.ReadyTick = nextTick + quote.DelayQuarters
+		item.PendingUpgrades = append(item.PendingUpgrades, pending)
+	} else {
+		installUpgrade(item, pending)
+	}
+
+	if err := applyNoLock(username, c, next, &LedgerEntry{
+		Type:        EntryUpgrade,
+		Amount:      -quote.Cost,
+		SiteID:      item.ID,
+		Tier:        quote.Tier,
+		Description: fmt.Sprintf("Ordered %s for %s", quote.Upgrade, item.Name),
+	}, JournalEntry{Op: OpUpdate, Index: index, Item: item}); err != nil {
+		return CartItem{}, economy.UpgradeQuote{}, err
+	}
+	return *item, quote, nil
+}
+
+// ItemsAfterUpgrades returns a copy of the user's items as they will be once
+// the pending upgrades due by tick are installed, and "<upgrade> at <site>"
+// for each of those. Nothing is installed until SettleQuarter.
+func ItemsAfterUpgrades(username string, tick int) ([]CartItem, []string) {
+	cartMu.RLock()
+	defer cartMu.RUnlock()
+	c, exists := carts[username]
+	if !exists {
+		return nil, nil
+	}
+	next := c.clone()
+	installed, _ := installDueUpgrades(next, tick)
+	return next.Items, installed
+}
+
+// SettleQuarter installs the upgrades due by tick and books the quarter's
+// operating result in one change, so neither happens without the other. It
+// returns the new balance.
+func SettleQuarter(username string, tick int, amount float64, description string) (float64, error) {
+	cartMu.Lock()
+	defer cartMu.Unlock()
+	c, err := getOrCreateCartNoLock(username)
+	if err != nil {
+		return 0, err
+	}
+	next := c.clone()
+	op := JournalEntry{Op: OpSettle}
+	if installed, upgradeOp := installDueUpgrades(next, tick); len(installed) > 0 {
+		op = upgradeOp
+	}
+	next.MoneyLeft += amount
+	if err := applyNoLock(username, c, next, &LedgerEntry{
+		Type:        EntryOperations,
+		Amount:      amount,
+		Description: description,
+	}, op); err != nil {
+		return 0, err
+	}
+	return c.MoneyLeft, nil
+}
+
+// installDueUpgrades installs every pending upgrade of c whose ready tick has
+// been reached. It returns "<upgrade> at <site>" for each one and the journal
+// entry for the change: several items changing are journaled as one base
+// entry, so the change is written whole or not at all.
+func installDueUpgrades(c *Cart, tick int) ([]string, JournalEntry) {
+	var installed []string
+	var ops []JournalEntry
+	for i := range c.Items {
+		item := &c.Items[i]
+		waiting := item.PendingUpgrades[:0]
+		for _, p := range item.PendingUpgrades {
+			if p.ReadyTick > tick {
+				waiting = append(waiting, p)
+				continue
+			}
+			installUpgrade(item, p)
+			installed = append(installed, fmt.Sprintf("%s at %s", p.Upgrade, item.Name))
+		}
+		if len(waiting) != len(item.PendingUpgrades) {
+			ops = append(ops, JournalEntry{Op: OpUpdate, Index: i, Item: item})
+		}
+		item.PendingUpgrades = waiting
+	}
+	if len(ops) == 1 {
+		return installed, ops[0]
+	}
+	return installed, JournalEntry{Op: OpBase}
+}
+
+// installUpgrade applies an upgrade to an item and recomputes its metrics.
+func installUpgrade(item *CartItem, p PendingUpgrade) {
+	if p.Upgrade == data.UpgradeTier {
+		item.Tier = p.Tier
+	} else if !data.HasUpgrade(&item.DatacenterLocation, p.Upgrade) {
+		item.Upgrades = append(item.Upgrades, p.Upgrade)
+	}
+	// The metrics only depend on the site itself, not on the other data centers.
+	data.CalculateResearchBasedMetrics(&item.DatacenterLocation, nil)
+}
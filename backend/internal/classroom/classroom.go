@@ -0,0 +1,229 @@
+// Package classroom keeps the classes instructors run. A classroom has one
+// instructor and a roster of students; games the instructor starts for the
+// class are tagged with its name, so the class shares a leaderboard.
+package classroom
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Classroom is a class as it is stored. Its instructor and students are
+// kept by user ID.
+type Classroom struct {
+	Name         string    `json:"name"`
+	InstructorID string    `json:"instructor_id"`
+	StudentIDs   []string  `json:"student_ids"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// View is a classroom with its users resolved to usernames, as the API
+// returns it.
+type View struct {
+	Name       string    `json:"name"`
+	Instructor string    `json:"instructor"`
+	Students   []string  `json:"students"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+var (
+	classrooms  = make(map[string]*Classroom) // name -> classroom
+	mu          sync.RWMutex
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// ValidateName checks a classroom name: 3-40 letters, digits, '_', '.' or
+// '-', starting with a letter or digit. Names are used as store keys.
+func ValidateName(name string) error {
+	if len(name) < 3 || len(name) > 40 {
+		return fmt.Errorf("classroom name must be 3-40 characters")
+	}
+	if !namePattern.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("classroom name may only contain letters, digits, '_', '.' and '-', and must start with a letter or digit")
+	}
+	return nil
+}
+
+// Load reads every classroom from the store. Users must be loaded first.
+func Load() error {
+	docs, err := store.Default().LoadClassrooms()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for name, doc := range docs {
+		var c Classroom
+		if err := json.Unmarshal(doc, &c); err != nil {
+			fmt.Printf("Skipping classroom %s: %v\n", name, err)
+			continue
+		}
+		classrooms[c.Name] = &c
+	}
+	return nil
+}
+
+func saveNoLock(c *Classroom) error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Default().SaveClassroom(c.Name, content)
+}
+
+// Create starts an empty classroom run by the given instructor. Names are
+// unique, ignoring case.
+func Create(name, instructor string) (View, error) {
+	if err := ValidateName(name); err != nil {
+		return View{}, err
+	}
+	instructorID, err := user.StoreKey(instructor)
+	if err != nil {
+		return View{}, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for existing := range classrooms {
+		if strings.EqualFold(existing, name) {
+			return View{}, fmt.Errorf("classroom %s already exists", existing)
+		}
+	}
+	c := &Classroom{Name: name, InstructorID: instructorID, StudentIDs: []string{}, CreatedAt: time.Now().UTC()}
+	if err := saveNoLock(c); err != nil {
+		return View{}, err
+	}
+	classrooms[name] = c
+	return c.view(), nil
+}
+
+// Get returns a classroom.
+func Get(name string) (View, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := classrooms[name]
+	if !ok {
+		return View{}, false
+	}
+	return c.view(), true
+}
+
+// List returns the classrooms run by an instructor, or every classroom if
+// instructor is empty, sorted by name.
+func List(instructor string) []View {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]View, 0)
+	for _, c := range classrooms {
+		v := c.view()
+		if instructor == "" || v.Instructor == instructor {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// AddStudents adds registered users to a classroom's roster. Students
+// already on it are left alone.
+func AddStudents(name string, usernames []string) (View, error) {
+	ids := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		id, err := user.StoreKey(username)
+		if err != nil {
+			return View{}, err
+		}
+		ids = append(ids, id)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := classrooms[name]
+	if !ok {
+		return View{}, fmt.Errorf("classroom %s not found", name)
+	}
+	updated := *c
+	updated.StudentIDs = append([]string(nil), c.StudentIDs...)
+	for _, id := range ids {
+		if !containsID(updated.StudentIDs, id) {
+			updated.StudentIDs = append(updated.StudentIDs, id)
+		}
+	}
+	if err := saveNoLock(&updated); err != nil {
+		return View{}, err
+	}
+	classrooms[name] = &updated
+	return updated.view(), nil
+}
+
+// RemoveStudents takes users off a classroom's roster. Their games keep the
+// classroom's name until they start a new one.
+func RemoveStudents(name string, usernames []string) (View, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := classrooms[name]
+	if !ok {
+		return View{}, fmt.Errorf("classroom %s not found", name)
+	}
+	remove := make(map[string]bool)
+	for _, username := range usernames {
+		if id, ok := user.IDFor(username); ok {
+			remove[id] = true
+		}
+	}
+	updated := *c
+	updated.StudentIDs = make([]string, 0, len(c.StudentIDs))
+	for _, id := range c.StudentIDs {
+		if !remove[id] {
+			updated.StudentIDs = append(updated.StudentIDs, id)
+		}
+	}
+	if err := saveNoLock(&updated); err != nil {
+		return View{}, err
+	}
+	classrooms[name] = &updated
+	return updated.view(), nil
+}
+
+// Delete removes a classroom. Its students' games and results are kept.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := classrooms[name]; !ok {
+		return fmt.Errorf("classroom %s not found", name)
+	}
+	if err := store.Default().DeleteClassroom(name); err != nil {
+		return err
+	}
+	delete(classrooms, name)
+	return nil
+}
+
+// view resolves a classroom's user IDs. Users that no longer resolve are
+// left out.
+func (c *Classroom) view() View {
+	v := View{Name: c.Name, Students: make([]string, 0, len(c.StudentIDs)), CreatedAt: c.CreatedAt}
+	v.Instructor, _ = user.UsernameFor(c.InstructorID)
+	for _, id := range c.StudentIDs {
+		if username, ok := user.UsernameFor(id); ok {
+			v.Students = append(v.Students, username)
+		}
+	}
+	sort.Strings(v.Students)
+	return v
+}
+
+func containsID(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -127,6 +127,7 @@ func ReadDatacenterLocations(filename string) ([]DatacenterLocation, error) {
 		}
 
 		locations = append(locations, DatacenterLocation{
+			ID:          len(locations) + 1,
 			Latitude:    lat,
 			Longitude:   lng,
 			Name:        strings.TrimSpace(record[2]),
@@ -182,6 +183,16 @@ func ReadExistingDatacenters(filename string) ([]DatacenterLocation, error) {
 	return existingDCs, scanner.Err()
 }
 
+// FindLocationByID returns the catalog entry with the given site ID.
+func FindLocationByID(locations []DatacenterLocation, id int) (DatacenterLocation, bool) {
+	for _, loc := range locations {
+		if loc.ID == id {
+			return loc, true
+		}
+	}
+	return DatacenterLocation{}, false
+}
+
 // parseNotes (simple pass-through, or do advanced logic)
 func parseNotes(notesStr string) string {
 	return notesStr
@@ -13,12 +13,15 @@ type DataCenter struct {
 }
 
 type DatacenterLocation struct {
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	Name        string  `json:"name,omitempty"`
-	LandPrice   string  `json:"land_price,omitempty"`
-	Electricity string  `json:"electricity,omitempty"`
-	Notes       string  `json:"notes,omitempty"`
+	ID          int      `json:"id,omitempty"` // row number in us_possible_locations.csv
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	Name        string   `json:"name,omitempty"`
+	LandPrice   string   `json:"land_price,omitempty"`
+	Electricity string   `json:"electricity,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	Tier        string   `json:"tier,omitempty"`     // see tiers.go; empty means untiered
+	Upgrades    []string `json:"upgrades,omitempty"` // installed upgrade IDs, see upgrades.go
 
 	EcoScore               int     `json:"eco_score,omitempty"`
 	CarbonImpact           float64 `json:"carbon_impact,omitempty"`
@@ -62,6 +65,13 @@ func GetEnvironmentalData(loc *DatacenterLocation) EnvironmentalData {
 
 // Below are the private “helper” functions that you had in your original code.
 // They are basically unchanged except for being package-private (lowercase first letter).
+// The lookup tables are the same ones main.go uses.
+
+// StateCode returns the two-letter state of a location, taken from its name
+// ("Mobile, AL"), or "Unknown".
+func StateCode(loc *DatacenterLocation) string {
+	return extractStateCode(loc.Name)
+}
 
 func extractStateCode(name string) string {
 	parts := splitCommaSpace(name)
@@ -78,59 +88,382 @@ func splitCommaSpace(s string) []string {
 	return strings.Split(s, ", ")
 }
 
-// getGridEmissionsIntensity, getRenewablePenetration, etc.
+// Get grid emissions intensity (kg CO2e/kWh) based on eGRID data
 func getGridEmissionsIntensity(stateCode string) float64 {
+	// Grid emissions intensity by state (kg CO2e/kWh)
+	// From EPA eGRID 2021 data: https://www.epa.gov/egrid
 	gridData := map[string]float64{
-		// [snip] same map as before
+		"WA": 0.0932, "OR": 0.1521, "CA": 0.2096, "ID": 0.0905, "NV": 0.3135,
+		"MT": 0.3929, "WY": 0.7891, "UT": 0.6321, "CO": 0.5309, "AZ": 0.3742,
+		"NM": 0.4916, "ND": 0.5874, "SD": 0.3326, "NE": 0.4911, "KS": 0.4547,
+		"OK": 0.4139, "TX": 0.4089, "MN": 0.3632, "IA": 0.3817, "MO": 0.6733,
+		"AR": 0.4422, "LA": 0.3924, "WI": 0.5142, "IL": 0.3873, "MS": 0.4341,
+		"MI": 0.4486, "IN": 0.6899, "KY": 0.7662, "TN": 0.3711, "AL": 0.3707,
+		"OH": 0.5354, "WV": 0.8463, "VA": 0.3124, "NC": 0.3299, "SC": 0.2994,
+		"GA": 0.3749, "FL": 0.3830, "PA": 0.3790, "NY": 0.2139, "ME": 0.1743,
+		"NH": 0.1240, "VT": 0.0055, "MA": 0.3075, "RI": 0.3726, "CT": 0.2369,
+		"NJ": 0.2644, "DE": 0.4644, "MD": 0.3187, "DC": 0.2783, "AK": 0.4566,
+		"HI": 0.6246, "PR": 0.5893, "VI": 0.6021, "GU": 0.6432, "MP": 0.6521,
 	}
-	if val, ok := gridData[stateCode]; ok {
-		return val
+
+	// Return value from map or default if not found
+	if intensity, exists := gridData[stateCode]; exists {
+		return intensity
 	}
-	return 0.45 // default
+	return 0.4500 // U.S. average if no data available
 }
 
+// Get renewable energy penetration percentage
 func getRenewablePenetration(stateCode string) float64 {
-	// [snip] same map as before
-	return 20.1
+	// Renewable penetration by state (%)
+	// From EIA 2023 data: https://www.eia.gov/electricity/data/state/
+	renewableData := map[string]float64{
+		"WA": 75.3, "OR": 69.8, "CA": 54.2, "ID": 78.1, "NV": 34.6,
+		"MT": 58.2, "WY": 16.3, "UT": 24.7, "CO": 32.4, "AZ": 16.1,
+		"NM": 36.8, "ND": 43.2, "SD": 77.9, "NE": 30.1, "KS": 47.3,
+		"OK": 44.8, "TX": 32.1, "MN": 33.6, "IA": 60.2, "MO": 11.3,
+		"AR": 13.7, "LA": 4.8, "WI": 14.1, "IL": 14.3, "MS": 3.2,
+		"MI": 12.6, "IN": 10.3, "KY": 7.1, "TN": 14.4, "AL": 9.1,
+		"OH": 5.7, "WV": 6.1, "VA": 12.3, "NC": 14.2, "SC": 7.3,
+		"GA": 12.6, "FL": 6.4, "PA": 6.9, "NY": 31.2, "ME": 82.1,
+		"NH": 23.1, "VT": 99.8, "MA": 15.9, "RI": 12.8, "CT": 6.5,
+		"NJ": 7.9, "DE": 6.1, "MD": 12.4, "DC": 5.3, "AK": 30.1,
+		"HI": 18.2, "PR": 7.1, "VI": 3.2, "GU": 5.1, "MP": 2.1,
+	}
+
+	if pct, exists := renewableData[stateCode]; exists {
+		return pct
+	}
+	return 20.1 // U.S. average if no data available
 }
 
+// Get water scarcity index (0-5 scale, higher is more scarce)
+// Based on WRI Aqueduct Water Risk Atlas
 func getWaterScarcityIndex(lat, lng float64) float64 {
-	// [snip] your logic
-	return 2.0
+	// Simplified regional water stress index
+	// The full implementation would use a GIS lookup or API
+
+	// High water stress regions
+	highStressRegions := []struct {
+		lat, lng float64
+		radius   float64
+		stress   float64
+	}{
+		{33.45, -112.07, 200, 4.2}, // Phoenix
+		{36.17, -115.14, 150, 4.5}, // Las Vegas
+		{32.72, -97.12, 150, 3.8},  // Dallas-Fort Worth
+		{37.77, -122.42, 100, 3.5}, // San Francisco
+		{34.05, -118.24, 120, 3.9}, // Los Angeles
+		{39.74, -104.99, 100, 3.7}, // Denver
+		{40.76, -111.89, 100, 4.0}, // Salt Lake City
+		{35.08, -106.65, 100, 4.1}, // Albuquerque
+	}
+
+	// Check if location is in high stress region
+	for _, region := range highStressRegions {
+		dist := Distance(lat, lng, region.lat, region.lng)
+		if dist <= region.radius {
+			return region.stress
+		}
+	}
+
+	// Default stress levels by region
+	if lng < -115 {
+		return 3.2 // Western states
+	} else if lng < -100 {
+		return 2.5 // Central states
+	} else if lng < -90 {
+		return 1.8 // Midwest
+	} else if lat < 35 && lng > -90 {
+		return 2.2 // Southeast
+	} else if lat >= 40 && lng > -90 {
+		return 1.5 // Northeast
+	}
+
+	return 2.0 // Default
 }
 
+// Get average temperature (°C)
 func getAverageTemperature(lat, lng float64) float64 {
-	// [snip]
-	return 25.0
+	// Simplified model based on latitude and longitude
+	// The full implementation would use climate data APIs
+
+	// Base temperature decreases with latitude
+	baseTemp := 30.0 - 0.5*math.Abs(lat-20)
+
+	// Adjust for elevation (rough approximation)
+	if lng < -105 && lat > 35 {
+		baseTemp -= 5.0 // Rocky Mountains
+	} else if lng < -115 {
+		baseTemp -= 2.0 // West Coast correction
+	} else if lng > -80 && lat > 40 {
+		baseTemp -= 3.0 // Northeast correction
+	} else if lng > -90 && lat < 30 {
+		baseTemp += 2.0 // Gulf Coast correction
+	}
+
+	return baseTemp
 }
 
+// Count nearby datacenters
 func countNearbyCenters(loc *DatacenterLocation) int {
-	// [snip]
-	return 0
+	// This would query a database of known datacenter locations
+	// For now, let's use a simplified map of known datacenter clusters
+
+	clusters := []struct {
+		lat, lng float64
+		radius   float64
+		count    int
+	}{
+		{39.05, -77.46, 50, 60},  // Ashburn, VA (Data Center Alley)
+		{32.78, -96.80, 50, 35},  // Dallas-Fort Worth
+		{37.37, -121.97, 40, 40}, // Silicon Valley
+		{41.88, -87.63, 40, 25},  // Chicago
+		{33.45, -112.07, 50, 15}, // Phoenix
+		{40.73, -74.00, 40, 30},  // New York/New Jersey
+		{47.60, -122.33, 50, 20}, // Seattle
+		{39.74, -104.99, 40, 15}, // Denver
+		{25.78, -80.19, 40, 12},  // Miami
+		{36.17, -115.14, 40, 10}, // Las Vegas
+		{33.75, -84.39, 40, 18},  // Atlanta
+	}
+
+	// Check if location is in a known cluster
+	for _, cluster := range clusters {
+		dist := Distance(loc.Latitude, loc.Longitude, cluster.lat, cluster.lng)
+		if dist <= cluster.radius {
+			return cluster.count
+		}
+	}
+
+	// Default count by population density
+	if inUrbanArea(loc.Latitude, loc.Longitude) {
+		return 3 // Typical urban area has a few datacenters
+	}
+
+	return 0 // Rural areas typically have no datacenters
+}
+
+// Check if location is in an urban area
+func inUrbanArea(lat, lng float64) bool {
+	// Very simplified check - would use GIS data in production
+
+	urbanCenters := []struct {
+		lat, lng float64
+		radius   float64
+	}{
+		{40.71, -74.01, 50},  // NYC
+		{34.05, -118.24, 60}, // LA
+		{41.88, -87.63, 40},  // Chicago
+		{29.76, -95.37, 40},  // Houston
+		{33.45, -112.07, 40}, // Phoenix
+		{39.95, -75.17, 30},  // Philadelphia
+		{29.42, -98.49, 30},  // San Antonio
+		{32.78, -96.80, 40},  // Dallas
+		{30.27, -97.74, 30},  // Austin
+		{37.77, -122.42, 30}, // San Francisco
+	}
+
+	for _, city := range urbanCenters {
+		dist := Distance(lat, lng, city.lat, city.lng)
+		if dist <= city.radius {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hazard types used by the disaster risk tables.
+const (
+	HazardEarthquake = "earthquake"
+	HazardHurricane  = "hurricane"
+	HazardTornado    = "tornado"
+	HazardWildfire   = "wildfire"
+)
+
+// Hazards lists every hazard type.
+var Hazards = []string{HazardEarthquake, HazardHurricane, HazardTornado, HazardWildfire}
+
+// DisasterZone is a high-risk area for a single hazard.
+type DisasterZone struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+	Risk      float64 // 0-1
+	Type      string
 }
 
+// DisasterZones are the high risk zones.
+// Would use FEMA, USGS and other risk maps in production
+var DisasterZones = []DisasterZone{
+	{37.77, -122.42, 100, 0.85, HazardEarthquake}, // Bay Area
+	{34.05, -118.24, 100, 0.80, HazardEarthquake}, // Southern California
+	{25.76, -80.19, 200, 0.90, HazardHurricane},   // South Florida
+	{29.95, -90.07, 150, 0.85, HazardHurricane},   // New Orleans
+	{35.65, -97.48, 150, 0.75, HazardTornado},     // Oklahoma
+	{39.74, -104.99, 100, 0.60, HazardWildfire},   // Colorado Front Range
+}
+
+// regionalDisasterRisk returns the baseline risk outside the high risk zones
+// and the hazards that drive it (none for the default).
+func regionalDisasterRisk(lat, lng float64) (float64, []string) {
+	if lng < -115 {
+		return 0.5, []string{HazardEarthquake, HazardWildfire} // West Coast (earthquake, fire)
+	} else if lng > -90 && lat < 35 {
+		return 0.6, []string{HazardHurricane} // Southeast (hurricane)
+	} else if lng > -98 && lng < -88 && lat > 35 && lat < 42 {
+		return 0.5, []string{HazardTornado} // Midwest (tornado)
+	} else if lng < -100 && lat > 35 {
+		return 0.4, []string{HazardWildfire} // Mountain West (wildfire)
+	}
+
+	return 0.3, nil // Default moderate risk
+}
+
+// Get natural disaster risk (0-1 scale)
 func getNaturalDisasterRisk(lat, lng float64) float64 {
-	// [snip]
-	return 0.3
+	// Simplified disaster risk model
+
+	// Check if in high risk zone
+	maxRisk := 0.0
+	for _, zone := range DisasterZones {
+		dist := Distance(lat, lng, zone.Latitude, zone.Longitude)
+		if dist <= zone.RadiusKm && zone.Risk > maxRisk {
+			maxRisk = zone.Risk
+		}
+	}
+
+	if maxRisk > 0 {
+		return maxRisk
+	}
+
+	// Regional baseline risks
+	risk, _ := regionalDisasterRisk(lat, lng)
+	return risk
+}
+
+// DisasterExposure breaks the disaster risk at a location down by hazard (0-1 each).
+// Zones count at full risk; a regional baseline is spread over its hazards, and
+// the default baseline gives every hazard a small background risk.
+func DisasterExposure(lat, lng float64) map[string]float64 {
+	exposure := make(map[string]float64, len(Hazards))
+	for _, zone := range DisasterZones {
+		if Distance(lat, lng, zone.Latitude, zone.Longitude) <= zone.RadiusKm {
+			exposure[zone.Type] = math.Max(exposure[zone.Type], zone.Risk)
+		}
+	}
+
+	risk, hazards := regionalDisasterRisk(lat, lng)
+	if len(hazards) == 0 {
+		hazards = Hazards
+		risk /= float64(len(Hazards))
+	}
+	for _, h := range hazards {
+		exposure[h] = math.Max(exposure[h], risk)
+	}
+	return exposure
 }
 
+// Get biodiversity sensitivity (0-1 scale)
 func getBiodiversitySensitivity(lat, lng float64) float64 {
-	// [snip]
-	return 0.5
+	// Simplified biodiversity sensitivity model
+	// Would use conservation databases in production
+
+	// High biodiversity zones
+	bioZones := []struct {
+		lat, lng    float64
+		radius      float64
+		sensitivity float64
+	}{
+		{27.5, -81.0, 150, 0.85},   // Florida Everglades
+		{37.86, -119.54, 100, 0.8}, // Yosemite/Sierra Nevada
+		{35.6, -83.52, 100, 0.75},  // Great Smoky Mountains
+		{44.6, -110.5, 150, 0.8},   // Yellowstone
+		{48.7, -113.8, 120, 0.75},  // Glacier National Park
+		{29.3, -103.25, 100, 0.65}, // Big Bend
+		{36.1, -112.1, 120, 0.7},   // Grand Canyon
+	}
+
+	// Check if in sensitive zone
+	for _, zone := range bioZones {
+		dist := Distance(lat, lng, zone.lat, zone.lng)
+		if dist <= zone.radius {
+			return zone.sensitivity
+		}
+	}
+
+	// Default sensitivity based on urban proximity
+	if inUrbanArea(lat, lng) {
+		return 0.3 // Urban areas have lower biodiversity
+	}
+
+	return 0.5 // Default moderate sensitivity
 }
 
+// Get land use change impact (0-1 scale)
 func getLandUseChangeImpact(lat, lng float64) float64 {
-	// [snip]
-	return 0.5
+	// Simplified land use impact model
+	// Would use land cover and ecosystem maps in production
+
+	// If in urban area, impact is lower (already developed)
+	if inUrbanArea(lat, lng) {
+		return 0.3
+	}
+
+	// Regional impacts
+	if lng < -115 && lat < 36 {
+		return 0.8 // Desert ecosystems (fragile)
+	} else if lng > -90 && lat < 30 {
+		return 0.7 // Gulf Coast wetlands
+	} else if lng > -98 && lng < -88 && lat > 40 && lat < 50 {
+		return 0.6 // Northern forests
+	} else if lng < -105 && lat > 40 {
+		return 0.7 // Mountain ecosystems
+	}
+
+	return 0.5 // Default moderate impact
 }
 
+// Get socioeconomic impact (0-1 scale)
 func getSocioeconomicImpact(lat, lng float64) float64 {
-	// [snip]
-	return 0.3
+	// Simplified socioeconomic impact model
+	// Would use census data and environmental justice indices in production
+
+	// Environmental justice focus areas
+	ejAreas := []struct {
+		lat, lng float64
+		radius   float64
+		impact   float64
+	}{
+		{37.5, -122.0, 30, 0.7},  // East Palo Alto
+		{37.7, -122.2, 20, 0.8},  // Oakland
+		{33.9, -118.2, 30, 0.85}, // South LA
+		{29.7, -95.3, 25, 0.75},  // East Houston
+		{38.9, -77.0, 15, 0.7},   // DC SE
+		{40.8, -74.0, 20, 0.8},   // Newark
+		{41.8, -87.7, 25, 0.75},  // Chicago South/West
+		{32.7, -96.8, 20, 0.7},   // South Dallas
+	}
+
+	// Check if in environmental justice area
+	for _, area := range ejAreas {
+		dist := Distance(lat, lng, area.lat, area.lng)
+		if dist <= area.radius {
+			return area.impact
+		}
+	}
+
+	// Default impact based on urban proximity
+	if inUrbanArea(lat, lng) {
+		return 0.5 // Urban areas have moderate justice concerns
+	}
+
+	return 0.3 // Rural areas typically have lower justice issues
 }
 
-// Basic Haversine
-func distance(lat1, lon1, lat2, lon2 float64) float64 {
+// Distance returns the great-circle (haversine) distance in km between two points.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371.0
 	dLat := (lat2 - lat1) * math.Pi / 180.0
 	dLon := (lon2 - lon1) * math.Pi / 180.0
@@ -0,0 +1,105 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The CSV files the catalog is read from, relative to the working directory.
+const (
+	PossibleLocationsCSV = "us_possible_locations.csv" // sites players can build on
+	DatacentersCSV       = "us_datacenters.csv"        // existing datacenters
+)
+
+// Dataset describes one of the CSV files, as admins see it.
+type Dataset struct {
+	Name       string    `json:"name"`
+	File       string    `json:"file"`
+	Rows       int       `json:"rows"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// datasets maps dataset names to their file and a check that parses it the
+// way the handlers will, returning the number of usable rows.
+var datasets = map[string]struct {
+	file  string
+	check func(path string) (int, error)
+}{
+	"possible-locations": {PossibleLocationsCSV, func(path string) (int, error) {
+		locations, err := ReadDatacenterLocations(path)
+		return len(locations), err
+	}},
+	"datacenters": {DatacentersCSV, func(path string) (int, error) {
+		dataCenters, err := ReadAllDataCenters(path)
+		return len(dataCenters), err
+	}},
+}
+
+// ListDatasets describes every dataset, sorted by name. A file that can't be
+// read is listed with no rows.
+func ListDatasets() []Dataset {
+	list := make([]Dataset, 0, len(datasets))
+	for name, ds := range datasets {
+		d := Dataset{Name: name, File: ds.file}
+		if info, err := os.Stat(ds.file); err == nil {
+			d.Size, d.ModifiedAt = info.Size(), info.ModTime().UTC()
+			d.Rows, _ = ds.check(ds.file)
+		}
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ReplaceDataset replaces a dataset's CSV file with content. The new file is
+// parsed first and must have at least one usable row; it then replaces the
+// old one atomically, so requests read either the old or the new catalog.
+// Site IDs are row numbers, so replacing the possible locations can change
+// what players' carts point at.
+func ReplaceDataset(name string, content []byte) (Dataset, error) {
+	ds, ok := datasets[name]
+	if !ok {
+		return Dataset{}, fmt.Errorf("unknown dataset %q", name)
+	}
+	dir := filepath.Dir(ds.file)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(ds.file)+".upload-*")
+	if err != nil {
+		return Dataset{}, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return Dataset{}, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return Dataset{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Dataset{}, err
+	}
+	rows, err := ds.check(tmpPath)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("invalid %s dataset: %v", name, err)
+	}
+	if rows == 0 {
+		return Dataset{}, fmt.Errorf("invalid %s dataset: no usable rows", name)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return Dataset{}, err
+	}
+	if err := os.Rename(tmpPath, ds.file); err != nil {
+		return Dataset{}, err
+	}
+	info, err := os.Stat(ds.file)
+	if err != nil {
+		return Dataset{}, err
+	}
+	return Dataset{Name: name, File: ds.file, Rows: rows, Size: info.Size(), ModifiedAt: info.ModTime().UTC()}, nil
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"math"
+	"sort"
+)
+
+// MetroArea is a market whose compute demand players' facilities serve.
+type MetroArea struct {
+	Name       string  `json:"name"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	DemandMW   float64 `json:"demand_mw"`   // IT load demanded in the first game year
+	GrowthRate float64 `json:"growth_rate"` // annual growth of demand
+}
+
+// MetroAreas is each player's market: the IT load they can sell per metro.
+// Would use market research data in production.
+var MetroAreas = []MetroArea{
+	{"New York", 40.71, -74.01, 8, 0.06},
+	{"Washington DC", 38.90, -77.04, 8, 0.10},
+	{"Los Angeles", 34.05, -118.24, 6, 0.07},
+	{"San Francisco Bay Area", 37.77, -122.42, 6, 0.09},
+	{"Chicago", 41.88, -87.63, 5, 0.05},
+	{"Dallas", 32.78, -96.80, 5, 0.12},
+	{"Houston", 29.76, -95.37, 4, 0.08},
+	{"Atlanta", 33.75, -84.39, 4, 0.11},
+	{"Seattle", 47.60, -122.33, 4, 0.08},
+	{"Phoenix", 33.45, -112.07, 3, 0.15},
+	{"Denver", 39.74, -104.99, 3, 0.10},
+	{"Miami", 25.78, -80.19, 3, 0.07},
+}
+
+// Latency model: round trips cost about 1 ms per 100 km of fiber. Users pay
+// full price up to FreeLatencyMs, a LatencyDiscountPerMs share less for each
+// millisecond beyond it, and can't be served past MaxLatencyMs.
+const (
+	FreeLatencyMs        = 5.0
+	MaxLatencyMs         = 35.0
+	LatencyDiscountPerMs = 0.03
+)
+
+// DemandAfter returns a metro's demand in MW after the given number of years.
+func (m MetroArea) DemandAfter(years float64) float64 {
+	return m.DemandMW * math.Pow(1+m.GrowthRate, years)
+}
+
+// LatencyMs estimates the round-trip latency over a distance in km.
+func LatencyMs(km float64) float64 {
+	return km / 100
+}
+
+// ServiceFactor is the share of the full price users pay for compute served
+// from a distance in km; zero when the site is too far away to serve them.
+func ServiceFactor(km float64) float64 {
+	latency := LatencyMs(km)
+	if latency > MaxLatencyMs {
+		return 0
+	}
+	return math.Min(1, 1-(latency-FreeLatencyMs)*LatencyDiscountPerMs)
+}
+
+// NearestMetro returns the closest metro area to a point and its distance in km.
+func NearestMetro(lat, lng float64) (MetroArea, float64) {
+	var nearest MetroArea
+	best := math.Inf(1)
+	for _, m := range MetroAreas {
+		if d := Distance(lat, lng, m.Latitude, m.Longitude); d < best {
+			nearest, best = m, d
+		}
+	}
+	return nearest, best
+}
+
+// Supply is the IT load a facility can sell.
+type Supply struct {
+	Latitude  float64
+	Longitude float64
+	MW        float64
+}
+
+// MetroService is how much of a metro's demand was served.
+type MetroService struct {
+	Name     string  `json:"name"`
+	DemandMW float64 `json:"demand_mw"`
+	ServedMW float64 `json:"served_mw"`
+}
+
+// DemandAllocation is the result of matching facilities to metro demand.
+type DemandAllocation struct {
+	SoldMW      []float64      `json:"sold_mw"`      // per facility
+	EffectiveMW []float64      `json:"effective_mw"` // per facility, after latency discounts
+	Metros      []MetroService `json:"metros"`
+	DemandMW    float64        `json:"demand_mw"`
+	ServedMW    float64        `json:"served_mw"`
+	UnmetMW     float64        `json:"unmet_mw"`
+}
+
+// AllocateDemand sells the facilities' capacity to the metro areas' demand
+// after the given number of years. Pairs with the smallest latency discount
+// are matched first, so each metro is served from the closest capacity.
+func AllocateDemand(supply []Supply, years float64) DemandAllocation {
+	alloc := DemandAllocation{
+		SoldMW:      make([]float64, len(supply)),
+		EffectiveMW: make([]float64, len(supply)),
+		Metros:      make([]MetroService, len(MetroAreas)),
+	}
+	for j, m := range MetroAreas {
+		alloc.Metros[j] = MetroService{Name: m.Name, DemandMW: m.DemandAfter(years)}
+		alloc.DemandMW += alloc.Metros[j].DemandMW
+	}
+
+	type pair struct {
+		facility, metro int
+		km, factor      float64
+	}
+	var pairs []pair
+	for i, s := range supply {
+		for j, m := range MetroAreas {
+			km := Distance(s.Latitude, s.Longitude, m.Latitude, m.Longitude)
+			if f := ServiceFactor(km); f > 0 {
+				pairs = append(pairs, pair{i, j, km, f})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].km < pairs[b].km })
+
+	for _, p := range pairs {
+		left := supply[p.facility].MW - alloc.SoldMW[p.facility]
+		wanted := alloc.Metros[p.metro].DemandMW - alloc.Metros[p.metro].ServedMW
+		mw := math.Min(left, wanted)
+		if mw <= 0 {
+			continue
+		}
+		alloc.SoldMW[p.facility] += mw
+		alloc.EffectiveMW[p.facility] += mw * p.factor
+		alloc.Metros[p.metro].ServedMW += mw
+		alloc.ServedMW += mw
+	}
+	alloc.UnmetMW = alloc.DemandMW - alloc.ServedMW
+	return alloc
+}
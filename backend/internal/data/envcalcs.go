@@ -0,0 +1,330 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ScoringProfile holds the weights used to collapse the impact factors into an eco score.
+type ScoringProfile struct {
+	Name         string  `json:"name"`
+	WeightCarbon float64 `json:"weight_carbon"`
+	WeightWater  float64 `json:"weight_water"`
+	WeightTemp   float64 `json:"weight_temp"`
+	WeightLand   float64 `json:"weight_land"`
+	WeightSocial float64 `json:"weight_social"`
+}
+
+// DefaultScoringProfileName is the profile with the original weights.
+const DefaultScoringProfileName = "default"
+
+var (
+	scoringProfiles = map[string]ScoringProfile{
+		DefaultScoringProfileName: {Name: DefaultScoringProfileName, WeightCarbon: 0.40, WeightWater: 0.25, WeightTemp: 0.20, WeightLand: 0.10, WeightSocial: 0.05},
+		"carbon-first":            {Name: "carbon-first", WeightCarbon: 0.70, WeightWater: 0.10, WeightTemp: 0.10, WeightLand: 0.05, WeightSocial: 0.05},
+		"water-stressed":          {Name: "water-stressed", WeightCarbon: 0.25, WeightWater: 0.50, WeightTemp: 0.10, WeightLand: 0.10, WeightSocial: 0.05},
+		"community":               {Name: "community", WeightCarbon: 0.30, WeightWater: 0.20, WeightTemp: 0.15, WeightLand: 0.15, WeightSocial: 0.20},
+	}
+	activeScoringProfile = DefaultScoringProfileName
+	scoringProfilesFile  string // where admin changes are saved; empty keeps them in memory
+	scoringMu            sync.RWMutex
+)
+
+// scoringProfilesDoc is the layout of the scoring profiles file.
+type scoringProfilesDoc struct {
+	Active   string           `json:"active"`
+	Profiles []ScoringProfile `json:"profiles"`
+}
+
+// ListScoringProfiles returns all scoring profiles sorted by name.
+func ListScoringProfiles() []ScoringProfile {
+	scoringMu.RLock()
+	defer scoringMu.RUnlock()
+	list := make([]ScoringProfile, 0, len(scoringProfiles))
+	for _, p := range scoringProfiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// GetScoringProfile looks up a profile by name. An empty name returns the active profile.
+func GetScoringProfile(name string) (ScoringProfile, bool) {
+	scoringMu.RLock()
+	defer scoringMu.RUnlock()
+	if name == "" {
+		name = activeScoringProfile
+	}
+	p, ok := scoringProfiles[name]
+	return p, ok
+}
+
+// ActiveScoringProfile returns the profile used when a request doesn't name one.
+func ActiveScoringProfile() ScoringProfile {
+	p, _ := GetScoringProfile("")
+	return p
+}
+
+// SetActiveScoringProfile switches the profile used by default.
+func SetActiveScoringProfile(name string) error {
+	scoringMu.Lock()
+	defer scoringMu.Unlock()
+	if _, ok := scoringProfiles[name]; !ok {
+		return fmt.Errorf("unknown scoring profile %q", name)
+	}
+	previous := activeScoringProfile
+	activeScoringProfile = name
+	if err := saveScoringProfilesNoLock(); err != nil {
+		activeScoringProfile = previous
+		return err
+	}
+	return nil
+}
+
+// LoadScoringProfiles adds the profiles in filename to the built-in ones and
+// remembers the file, so admin changes are saved back to it. A missing file
+// keeps the built-in profiles.
+func LoadScoringProfiles(filename string) error {
+	scoringMu.Lock()
+	defer scoringMu.Unlock()
+	scoringProfilesFile = filename
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var doc scoringProfilesDoc
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	for _, p := range doc.Profiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		scoringProfiles[p.Name] = p
+	}
+	if doc.Active != "" {
+		if _, ok := scoringProfiles[doc.Active]; !ok {
+			return fmt.Errorf("%s: unknown active profile %q", filename, doc.Active)
+		}
+		activeScoringProfile = doc.Active
+	}
+	return nil
+}
+
+// Validate checks that a profile has a name and non-negative weights that
+// add up to 1, so its eco scores are comparable with the other profiles'.
+func (p ScoringProfile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("scoring profile name is required")
+	}
+	weights := []float64{p.WeightCarbon, p.WeightWater, p.WeightTemp, p.WeightLand, p.WeightSocial}
+	sum := 0.0
+	for _, w := range weights {
+		if w < 0 || math.IsNaN(w) {
+			return fmt.Errorf("scoring profile %q: weights must be non-negative", p.Name)
+		}
+		sum += w
+	}
+	if math.Abs(sum-1) > 0.001 {
+		return fmt.Errorf("scoring profile %q: weights must add up to 1, not %.3f", p.Name, sum)
+	}
+	return nil
+}
+
+// SaveScoringProfile adds a profile or replaces the one with the same name.
+func SaveScoringProfile(p ScoringProfile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	scoringMu.Lock()
+	defer scoringMu.Unlock()
+	previous, existed := scoringProfiles[p.Name]
+	scoringProfiles[p.Name] = p
+	if err := saveScoringProfilesNoLock(); err != nil {
+		if existed {
+			scoringProfiles[p.Name] = previous
+		} else {
+			delete(scoringProfiles, p.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteScoringProfile removes a profile. The default profile and the
+// active one can't be deleted.
+func DeleteScoringProfile(name string) error {
+	scoringMu.Lock()
+	defer scoringMu.Unlock()
+	p, ok := scoringProfiles[name]
+	if !ok {
+		return fmt.Errorf("unknown scoring profile %q", name)
+	}
+	if name == DefaultScoringProfileName || name == activeScoringProfile {
+		return fmt.Errorf("scoring profile %q is the default or active profile", name)
+	}
+	delete(scoringProfiles, name)
+	if err := saveScoringProfilesNoLock(); err != nil {
+		scoringProfiles[name] = p
+		return err
+	}
+	return nil
+}
+
+// saveScoringProfilesNoLock writes every profile and the active one to the
+// scoring profiles file, if there is one, replacing it atomically. The lock
+// must be held.
+func saveScoringProfilesNoLock() error {
+	if scoringProfilesFile == "" {
+		return nil
+	}
+	doc := scoringProfilesDoc{Active: activeScoringProfile}
+	for _, p := range scoringProfiles {
+		doc.Profiles = append(doc.Profiles, p)
+	}
+	sort.Slice(doc.Profiles, func(i, j int) bool { return doc.Profiles[i].Name < doc.Profiles[j].Name })
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(scoringProfilesFile), filepath.Base(scoringProfilesFile)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), scoringProfilesFile)
+}
+
+// CalculateResearchBasedMetrics applies your research-based env. calculations
+// using the active scoring profile.
+func CalculateResearchBasedMetrics(loc *DatacenterLocation, allDatacenters []DatacenterLocation) {
+	CalculateResearchBasedMetricsWithProfile(loc, allDatacenters, ActiveScoringProfile())
+}
+
+// CalculateResearchBasedMetricsWithProfile is CalculateResearchBasedMetrics with
+// an explicit scoring profile.
+func CalculateResearchBasedMetricsWithProfile(loc *DatacenterLocation, allDatacenters []DatacenterLocation, profile ScoringProfile) {
+	envData := GetEnvironmentalData(loc)
+
+	// 1. Calculate PUE (Power Usage Effectiveness) based on climate and tier
+	itLoadMW, pue, onsiteRenewable := FacilityLoad(loc, envData)
+
+	// 2. Some constants
+	const landUseHectares = 12.0
+
+	// 3. Calculate total energy usage (MWh/year)
+	totalEnergyMWh := itLoadMW * pue * HoursPerYear
+
+	// 4. Calculate carbon emissions using regional grid intensity (kg CO2e/year)
+	carbonEmissions := totalEnergyMWh * (1 - onsiteRenewable) * GridCarbonShare(loc) * 1000 * envData.GridEmissionsIntensity
+
+	// 5. Calculate water consumption
+	waterConsumption := totalEnergyMWh * 1000 * WaterUseLPerKWh
+	waterImpact := waterConsumption * envData.WaterScarcityIndex
+
+	// 6. Temperature impact
+	heatRejection := itLoadMW * (1.0 - (1.0 / pue)) * 3.412
+	tempImpact := calculateTemperatureImpact(heatRejection, envData.DatacenterDensity, envData.AmbientTemperature)
+
+	// 7. Land use impact
+	landImpact := landUseHectares * envData.LandUseChangeImpact * envData.BiodiversitySensitivity
+
+	// 8. Overall Eco Score
+	ecoScore := calcEcoScore(carbonEmissions, waterImpact, tempImpact, landImpact, envData.SocioeconomicImpact, profile)
+	if ecoScore < 1 {
+		ecoScore = 1
+	} else if ecoScore > 100 {
+		ecoScore = 100
+	}
+
+	// Assign values
+	loc.EcoScore = int(ecoScore)
+	loc.CarbonImpact = carbonEmissions / 1000 // metric tons
+	loc.TempIncrease = tempImpact
+	loc.WaterUsage = waterConsumption / 3.785 // gallons
+	loc.DatacenterDensity = envData.DatacenterDensity
+	loc.RenewableAccess = int(envData.RenewablePenetration)
+
+	// Calculate compound effects
+	if envData.DatacenterDensity > 0 {
+		densityFactor := math.Log1p(float64(envData.DatacenterDensity)) / math.Log1p(10.0)
+		loc.CompoundedTempIncrease = tempImpact * (1.0 + densityFactor)
+		loc.WaterCompetition = 1.0 + densityFactor
+		loc.WaterUsage *= loc.WaterCompetition
+	} else {
+		loc.CompoundedTempIncrease = tempImpact
+		loc.WaterCompetition = 1.0
+	}
+
+	// Density impact score
+	if envData.DatacenterDensity == 0 {
+		loc.DensityImpactScore = 0
+	} else {
+		loc.DensityImpactScore = int(math.Min(100, 20*math.Log1p(float64(envData.DatacenterDensity))))
+	}
+}
+
+func calculateTemperatureImpact(heatRejection float64, density int, ambientTemp float64) float64 {
+	baseIncrease := 0.02 * heatRejection
+
+	densityMultiplier := 1.0
+	if density > 0 {
+		densityMultiplier = 1.0 + (math.Pow(float64(density), 0.7) / 10.0)
+	}
+
+	climateFactor := 1.0
+	if ambientTemp > 25 {
+		climateFactor = 1.0 + (ambientTemp-25)*0.02
+	} else if ambientTemp < 10 {
+		climateFactor = 0.8
+	}
+
+	return baseIncrease * densityMultiplier * climateFactor
+}
+
+func calcEcoScore(carbonEmissions, waterImpact, tempImpact, landImpact, socioImpact float64, profile ScoringProfile) float64 {
+	// The references are about one untiered facility's annual impact (15 MW
+	// at a PUE near 1.4 emits some 8e7 kg CO2e and uses 3e8 L of water).
+	// References of 5e6 kg and 5e7 L put every site far below zero, so all
+	// of them were clamped to a score of 1.
+	const (
+		carbonNorm = 200000000.0
+		waterNorm  = 2000000000.0
+		tempNorm   = 2.0
+		landNorm   = 10.0
+	)
+
+	normCarbon := carbonEmissions / carbonNorm
+	normWater := waterImpact / waterNorm
+	normTemp := tempImpact / tempNorm
+	normLand := landImpact / landNorm
+
+	envImpact := (normCarbon * profile.WeightCarbon) +
+		(normWater * profile.WeightWater) +
+		(normTemp * profile.WeightTemp) +
+		(normLand * profile.WeightLand) +
+		(socioImpact * profile.WeightSocial)
+
+	return 100 - (envImpact * 100)
+}
@@ -0,0 +1,83 @@
+package data
+
+import "math"
+
+// HoursPerYear converts a constant load in MW into MWh per year.
+const HoursPerYear = 8760.0
+
+// WaterUseLPerKWh is the cooling water consumed per kWh of facility energy.
+const WaterUseLPerKWh = 1.8
+
+// Footprint is the annual resource use of a facility at full load.
+type Footprint struct {
+	ITLoadMW        float64 `json:"it_load_mw"`
+	PUE             float64 `json:"pue"`
+	OnsiteRenewable float64 `json:"onsite_renewable"` // share of energy generated on site
+	EnergyMWh       float64 `json:"energy_mwh"`       // IT load x PUE
+	GridEnergyMWh   float64 `json:"grid_energy_mwh"`  // energy bought from the grid
+	CarbonTons      float64 `json:"carbon_tons"`      // metric tons CO2e from grid energy
+	WaterLiters     float64 `json:"water_liters"`
+}
+
+// FacilityLoad returns the IT load (MW), PUE and on-site renewable share of a
+// facility. Built tiers and installed upgrades change the IT load, cooling
+// overhead and on-site generation.
+func FacilityLoad(loc *DatacenterLocation, envData EnvironmentalData) (itLoadMW, pue, onsiteRenewable float64) {
+	itLoadMW = 15.0
+	pue = LocationPUE(envData.AmbientTemperature, envData.DatacenterDensity)
+	if tier, ok := GetTier(loc.Tier); ok {
+		itLoadMW = tier.CapacityMW
+		pue = 1 + (pue-1)*tier.CoolingFactor
+		onsiteRenewable = tier.OnsiteRenewable
+	}
+	pue, onsiteRenewable = applyUpgrades(loc, pue, onsiteRenewable)
+	return itLoadMW, pue, onsiteRenewable
+}
+
+// LocationPUE estimates the PUE of a conventional facility from the ambient
+// temperature and the number of nearby data centers.
+func LocationPUE(averageTemp float64, density int) float64 {
+	var basePUE float64
+
+	if averageTemp < 10 {
+		basePUE = 1.15 + (averageTemp+10)*0.005
+	} else if averageTemp < 18 {
+		basePUE = 1.2 + (averageTemp-10)*0.01
+	} else if averageTemp < 24 {
+		basePUE = 1.3 + (averageTemp-18)*0.025
+	} else {
+		basePUE = 1.45 + (averageTemp-24)*0.04
+	}
+
+	if density > 0 {
+		densityEffect := 0.01 * math.Min(0.5, math.Log10(float64(density))/2)
+		basePUE += densityEffect
+	}
+	return basePUE
+}
+
+// AnnualFootprint returns a facility's yearly energy, carbon and water use.
+func AnnualFootprint(loc *DatacenterLocation) Footprint {
+	return FootprintWith(loc, GetEnvironmentalData(loc))
+}
+
+// FootprintWith is AnnualFootprint under the given environmental conditions.
+func FootprintWith(loc *DatacenterLocation, envData EnvironmentalData) Footprint {
+	itLoadMW, pue, onsiteRenewable := FacilityLoad(loc, envData)
+	energy := itLoadMW * pue * HoursPerYear
+	grid := energy * (1 - onsiteRenewable)
+	return Footprint{
+		ITLoadMW:        itLoadMW,
+		PUE:             pue,
+		OnsiteRenewable: onsiteRenewable,
+		EnergyMWh:       energy,
+		GridEnergyMWh:   grid,
+		CarbonTons:      grid * GridCarbonShare(loc) * envData.GridEmissionsIntensity, // kg/kWh == t/MWh
+		WaterLiters:     energy * 1000 * WaterUseLPerKWh,
+	}
+}
+
+// AnnualEnergyMWh returns the total facility energy use (IT load x PUE) per year.
+func AnnualEnergyMWh(loc *DatacenterLocation) float64 {
+	return AnnualFootprint(loc).EnergyMWh
+}
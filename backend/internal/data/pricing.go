@@ -0,0 +1,82 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SiteAcres is the parcel size assumed when pricing land for a new facility.
+const SiteAcres = 5.0
+
+// DefaultElectricityPrice is used when a site's electricity price can't be parsed ($/kWh).
+const DefaultElectricityPrice = 0.07
+
+var priceNumberRe = regexp.MustCompile(`[0-9][0-9,]*(\.[0-9]+)?\s*[mMkK]?`)
+
+// parsePriceRange extracts the numbers from strings such as "$75,000-150,000/acre",
+// "1.5-2.5M per acre" or "$0.0972/kWh" and returns their midpoint.
+func parsePriceRange(s string) (float64, error) {
+	matches := priceNumberRe.FindAllString(s, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("no price found in %q", s)
+	}
+
+	// A trailing unit suffix ("1.5-2.5M") applies to the whole range.
+	suffix := ""
+	last := strings.TrimSpace(matches[len(matches)-1])
+	if n := len(last); n > 0 && strings.ContainsAny(last[n-1:], "mMkK") {
+		suffix = strings.ToLower(last[n-1:])
+	}
+
+	var total float64
+	for _, m := range matches {
+		m = strings.TrimSpace(m)
+		unit := suffix
+		if n := len(m); strings.ContainsAny(m[n-1:], "mMkK") {
+			unit = strings.ToLower(m[n-1:])
+			m = strings.TrimSpace(m[:n-1])
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid price %q: %v", s, err)
+		}
+		switch unit {
+		case "m":
+			v *= 1000000
+		case "k":
+			v *= 1000
+		}
+		total += v
+	}
+	return total / float64(len(matches)), nil
+}
+
+// ParseLandPrice returns the midpoint land price in USD per acre.
+func ParseLandPrice(landPrice string) (float64, error) {
+	return parsePriceRange(landPrice)
+}
+
+// ParseElectricityPrice returns the midpoint electricity price in USD per kWh.
+func ParseElectricityPrice(electricity string) (float64, error) {
+	return parsePriceRange(electricity)
+}
+
+// ElectricityPrice is ParseElectricityPrice with DefaultElectricityPrice as a fallback.
+func ElectricityPrice(loc *DatacenterLocation) float64 {
+	price, err := ParseElectricityPrice(loc.Electricity)
+	if err != nil || price <= 0 {
+		return DefaultElectricityPrice
+	}
+	return price
+}
+
+// LandCost returns the cost of the parcel for a site.
+func LandCost(loc *DatacenterLocation) (float64, error) {
+	perAcre, err := ParseLandPrice(loc.LandPrice)
+	if err != nil {
+		return 0, err
+	}
+	return perAcre * SiteAcres, nil
+}
@@ -0,0 +1,42 @@
+package data
+
+import "strings"
+
+// Tier describes one of the construction options a player can build on a site.
+// The values mirror the building options shown in the frontend.
+type Tier struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Capex            float64 `json:"capex"`             // construction cost in USD, excluding land
+	EnergyEfficiency int     `json:"energy_efficiency"` // 0-100 scale
+	CapacityMW       float64 `json:"capacity_mw"`       // IT load in MW
+	CarbonFactor     float64 `json:"carbon_factor"`     // relative footprint used by the cart
+	EmissionFactor   float64 `json:"emission_factor"`   // multiplier on the climate simulation contribution
+	CoolingFactor    float64 `json:"cooling_factor"`    // multiplier on the cooling overhead (PUE - 1)
+	OnsiteRenewable  float64 `json:"onsite_renewable"`  // share of energy from on-site renewables (0-1)
+}
+
+// Tier IDs accepted by the API.
+const (
+	TierStandard = "standard"
+	TierEco      = "eco"
+	TierNextGen  = "next-gen"
+)
+
+// Tiers lists the available tiers from cheapest to most sustainable.
+var Tiers = []Tier{
+	{ID: TierStandard, Name: "Standard Data Center", Capex: 2000000, EnergyEfficiency: 60, CapacityMW: 15, CarbonFactor: 0.8, EmissionFactor: 1.0, CoolingFactor: 1.0, OnsiteRenewable: 0},
+	{ID: TierEco, Name: "Eco Optimized Center", Capex: 3500000, EnergyEfficiency: 85, CapacityMW: 14, CarbonFactor: 0.4, EmissionFactor: 0.5, CoolingFactor: 0.7, OnsiteRenewable: 0.3},
+	{ID: TierNextGen, Name: "Next-Gen Sustainable Facility", Capex: 5000000, EnergyEfficiency: 95, CapacityMW: 16, CarbonFactor: 0.1, EmissionFactor: 0.125, CoolingFactor: 0.4, OnsiteRenewable: 0.7},
+}
+
+// GetTier looks up a tier by ID (case-insensitive).
+func GetTier(id string) (Tier, bool) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, t := range Tiers {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Tier{}, false
+}
@@ -0,0 +1,140 @@
+package data
+
+import "math"
+
+// Upgrade IDs.
+const (
+	UpgradeTier            = "tier-upgrade"
+	UpgradeCoolingRetrofit = "cooling-retrofit"
+	UpgradeOnsiteSolar     = "onsite-solar"
+	UpgradeBattery         = "battery-storage"
+	UpgradeRenewablePPA    = "renewable-ppa"
+)
+
+// maxOnsiteRenewable caps the on-site share a facility can reach with upgrades.
+const maxOnsiteRenewable = 0.9
+
+// Upgrade is a retrofit that can be installed on an owned facility. Factors
+// of zero leave the corresponding input unchanged.
+type Upgrade struct {
+	ID                     string  `json:"id"`
+	Name                   string  `json:"name"`
+	Description            string  `json:"description"`
+	CostPerMW              float64 `json:"cost_per_mw"`              // USD per MW of IT capacity
+	DelayQuarters          int     `json:"delay_quarters"`           // game quarters before it takes effect
+	CoolingFactor          float64 `json:"cooling_factor"`           // multiplier on cooling overhead (PUE - 1)
+	OnsiteRenewable        float64 `json:"onsite_renewable"`         // added share of energy generated on site
+	PPAShare               float64 `json:"ppa_share"`                // share of grid energy bought carbon-free
+	ElectricityPriceFactor float64 `json:"electricity_price_factor"` // multiplier on the site's power price
+}
+
+// Upgrades lists the retrofits available to owned facilities. A tier upgrade
+// is priced from the tier capex difference rather than per MW.
+var Upgrades = []Upgrade{
+	{
+		ID:            UpgradeTier,
+		Name:          "Tier Upgrade",
+		Description:   "Rebuild the facility as a higher tier. Costs the capex difference plus a retrofit premium.",
+		DelayQuarters: 3,
+	},
+	{
+		ID:            UpgradeCoolingRetrofit,
+		Name:          "Cooling Retrofit",
+		Description:   "Replace chillers with free-air and liquid cooling, cutting cooling overhead by 30%.",
+		CostPerMW:     100000,
+		DelayQuarters: 2,
+		CoolingFactor: 0.7,
+	},
+	{
+		ID:              UpgradeOnsiteSolar,
+		Name:            "On-site Solar",
+		Description:     "Rooftop and parking-lot solar covering a fifth of the facility's energy.",
+		CostPerMW:       150000,
+		DelayQuarters:   3,
+		OnsiteRenewable: 0.2,
+	},
+	{
+		ID:                     UpgradeBattery,
+		Name:                   "Battery Storage",
+		Description:            "Batteries shift load away from peak hours, lowering the average power price.",
+		CostPerMW:              60000,
+		DelayQuarters:          1,
+		ElectricityPriceFactor: 0.92,
+	},
+	{
+		ID:                     UpgradeRenewablePPA,
+		Name:                   "Renewable PPA",
+		Description:            "A power purchase agreement with a wind farm for half of the grid energy, at a small premium.",
+		CostPerMW:              20000,
+		DelayQuarters:          1,
+		PPAShare:               0.5,
+		ElectricityPriceFactor: 1.04,
+	},
+}
+
+// TierUpgradePremium is the share added to the capex difference of a tier upgrade.
+const TierUpgradePremium = 0.1
+
+// GetUpgrade looks up an upgrade by ID.
+func GetUpgrade(id string) (Upgrade, bool) {
+	for _, u := range Upgrades {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return Upgrade{}, false
+}
+
+// HasUpgrade reports whether an upgrade is installed on a facility.
+func HasUpgrade(loc *DatacenterLocation, id string) bool {
+	for _, installed := range loc.Upgrades {
+		if installed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// installedUpgrades returns the specs of a facility's installed upgrades.
+func installedUpgrades(loc *DatacenterLocation) []Upgrade {
+	var list []Upgrade
+	for _, id := range loc.Upgrades {
+		if u, ok := GetUpgrade(id); ok {
+			list = append(list, u)
+		}
+	}
+	return list
+}
+
+// applyUpgrades adjusts a facility's PUE and on-site renewable share for its
+// installed upgrades.
+func applyUpgrades(loc *DatacenterLocation, pue, onsiteRenewable float64) (float64, float64) {
+	for _, u := range installedUpgrades(loc) {
+		if u.CoolingFactor > 0 {
+			pue = 1 + (pue-1)*u.CoolingFactor
+		}
+		onsiteRenewable = math.Min(maxOnsiteRenewable, onsiteRenewable+u.OnsiteRenewable)
+	}
+	return pue, onsiteRenewable
+}
+
+// GridCarbonShare is the share of a facility's grid energy that carries the
+// grid's emissions, after renewable PPAs.
+func GridCarbonShare(loc *DatacenterLocation) float64 {
+	share := 1.0
+	for _, u := range installedUpgrades(loc) {
+		share -= u.PPAShare
+	}
+	return math.Max(0, share)
+}
+
+// UpgradePriceFactor is the combined effect of a facility's upgrades on its power price.
+func UpgradePriceFactor(loc *DatacenterLocation) float64 {
+	factor := 1.0
+	for _, u := range installedUpgrades(loc) {
+		if u.ElectricityPriceFactor > 0 {
+			factor *= u.ElectricityPriceFactor
+		}
+	}
+	return factor
+}
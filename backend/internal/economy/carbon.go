@@ -0,0 +1,119 @@
+package economy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CarbonPolicy is the carbon regime a game is played under. A policy can
+// combine a tax with a cap: emissions are taxed, and emissions above the cap
+// need allowances bought at AllowancePrice, while unused cap is sold at the
+// same price. The cap is allocated per MW of installed capacity, so a player
+// without facilities has nothing to sell. RECs cover a share of grid energy
+// and the emissions that come with it.
+type CarbonPolicy struct {
+	Name              string  `json:"name"`
+	TaxPerTonne       float64 `json:"tax_per_tonne"`        // USD per tonne CO2e
+	CapPerMW          float64 `json:"cap_per_mw"`           // annual tonnes per MW in the first year, 0 for no cap
+	CapDeclinePerYear float64 `json:"cap_decline_per_year"` // share the cap shrinks by each year
+	AllowancePrice    float64 `json:"allowance_price"`      // USD per tonne above (or below) the cap
+	RECPrice          float64 `json:"rec_price"`            // USD per MWh of renewable energy certificates
+}
+
+// DefaultCarbonPolicy is used by games that don't choose one.
+const DefaultCarbonPolicy = "carbon-tax"
+
+// carbonPolicies are the built-in policies.
+var carbonPolicies = map[string]CarbonPolicy{
+	"none": {
+		Name:     "none",
+		RECPrice: 5,
+	},
+	"carbon-tax": {
+		Name:        "carbon-tax",
+		TaxPerTonne: 50,
+		RECPrice:    5,
+	},
+	"cap-and-trade": {
+		Name:              "cap-and-trade",
+		CapPerMW:          2000,
+		CapDeclinePerYear: 0.05,
+		AllowancePrice:    60,
+		RECPrice:          5,
+	},
+	"strict": {
+		Name:              "strict",
+		TaxPerTonne:       25,
+		CapPerMW:          1000,
+		CapDeclinePerYear: 0.1,
+		AllowancePrice:    100,
+		RECPrice:          8,
+	},
+}
+
+// ListCarbonPolicies returns the built-in policies sorted by name.
+func ListCarbonPolicies() []CarbonPolicy {
+	list := make([]CarbonPolicy, 0, len(carbonPolicies))
+	for _, p := range carbonPolicies {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// GetCarbonPolicy looks up a built-in policy. An empty name means the default.
+func GetCarbonPolicy(name string) (CarbonPolicy, bool) {
+	if name == "" {
+		name = DefaultCarbonPolicy
+	}
+	p, ok := carbonPolicies[name]
+	return p, ok
+}
+
+// Validate checks that a (possibly custom) policy makes sense.
+func (p CarbonPolicy) Validate() error {
+	if p.TaxPerTonne < 0 || p.CapPerMW < 0 || p.AllowancePrice < 0 || p.RECPrice < 0 {
+		return fmt.Errorf("carbon policy %q: prices and cap must not be negative", p.Name)
+	}
+	if p.CapDeclinePerYear < 0 || p.CapDeclinePerYear >= 1 {
+		return fmt.Errorf("carbon policy %q: cap_decline_per_year must be in [0, 1)", p.Name)
+	}
+	return nil
+}
+
+// CapFor returns the annual cap for capacityMW of installed capacity,
+// yearIndex years into the game.
+func (p CarbonPolicy) CapFor(capacityMW float64, yearIndex int) float64 {
+	return p.CapPerMW * capacityMW * math.Pow(1-p.CapDeclinePerYear, float64(yearIndex))
+}
+
+// CarbonCharges is what a player pays for their emissions over a period.
+type CarbonCharges struct {
+	GrossTonnes   float64 `json:"gross_tonnes"` // emissions before RECs
+	NetTonnes     float64 `json:"net_tonnes"`   // emissions after RECs
+	CapTonnes     float64 `json:"cap_tonnes"`
+	Tax           float64 `json:"tax"`
+	AllowanceCost float64 `json:"allowance_cost"` // negative when surplus allowances were sold
+	RECCost       float64 `json:"rec_cost"`
+	Total         float64 `json:"total"`
+}
+
+// Charge prices a period's emissions. gridMWh and tonnes are the period's grid
+// energy and emissions, recShare the share of grid energy matched with RECs,
+// and capTonnes the cap for the period (ignored if the policy has no cap).
+func (p CarbonPolicy) Charge(gridMWh, tonnes, recShare, capTonnes float64) CarbonCharges {
+	recShare = math.Max(0, math.Min(1, recShare))
+	c := CarbonCharges{
+		GrossTonnes: tonnes,
+		NetTonnes:   tonnes * (1 - recShare),
+		RECCost:     gridMWh * recShare * p.RECPrice,
+	}
+	c.Tax = c.NetTonnes * p.TaxPerTonne
+	if p.CapPerMW > 0 {
+		c.CapTonnes = capTonnes
+		c.AllowanceCost = (c.NetTonnes - capTonnes) * p.AllowancePrice
+	}
+	c.Total = c.Tax + c.AllowanceCost + c.RECCost
+	return c
+}
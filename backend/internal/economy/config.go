@@ -0,0 +1,94 @@
+// Package economy holds the game's economic rules: configuration such as the
+// starting funds and refund rates, server-side pricing of sites and tiers, and
+// the operating economics of owned facilities.
+package economy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Config holds the tunable economic parameters of a game.
+type Config struct {
+	StartingFunds float64 `json:"starting_funds"` // USD granted when a player's cart is created
+	ResaleRate    float64 `json:"resale_rate"`    // share of the purchase price refunded when a site is sold
+	SalvageRate   float64 `json:"salvage_rate"`   // share of the purchase price refunded when a site is demolished
+
+	// Operating economics.
+	Utilization     float64 `json:"utilization"`        // share of IT capacity that is sold
+	RevenuePerMWh   float64 `json:"revenue_per_mwh"`    // USD per MWh of IT load delivered to customers
+	WaterPricePerKL float64 `json:"water_price_per_kl"` // USD per 1,000 liters
+	StaffingPerMW   float64 `json:"staffing_per_mw"`    // USD per MW of capacity per year
+	CarbonPrice     float64 `json:"carbon_price"`       // USD per metric ton CO2e
+	DiscountRate    float64 `json:"discount_rate"`      // annual rate used for NPV
+	HorizonYears    int     `json:"horizon_years"`      // years of operation projected
+}
+
+// DefaultConfig matches the budget the frontend has always advertised.
+var DefaultConfig = Config{
+	StartingFunds: 10000000,
+	ResaleRate:    0.7,
+	SalvageRate:   0.1,
+
+	Utilization:     0.7,
+	RevenuePerMWh:   180,
+	WaterPricePerKL: 2.5,
+	StaffingPerMW:   100000,
+	CarbonPrice:     50,
+	DiscountRate:    0.08,
+	HorizonYears:    20,
+}
+
+var (
+	current  = DefaultConfig
+	configMu sync.RWMutex
+)
+
+// LoadConfig reads the game configuration from a JSON file. Fields missing from
+// the file keep their defaults, and a missing file leaves the defaults in place.
+func LoadConfig(filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cfg := DefaultConfig
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	if cfg.StartingFunds <= 0 {
+		return fmt.Errorf("%s: starting_funds must be positive", filename)
+	}
+	if cfg.ResaleRate < 0 || cfg.ResaleRate > 1 || cfg.SalvageRate < 0 || cfg.SalvageRate > 1 {
+		return fmt.Errorf("%s: resale_rate and salvage_rate must be between 0 and 1", filename)
+	}
+	if cfg.Utilization <= 0 || cfg.Utilization > 1 {
+		return fmt.Errorf("%s: utilization must be in (0, 1]", filename)
+	}
+	if cfg.DiscountRate < 0 || cfg.HorizonYears <= 0 {
+		return fmt.Errorf("%s: discount_rate must be non-negative and horizon_years positive", filename)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	current = cfg
+	return nil
+}
+
+// GetConfig returns the active configuration.
+func GetConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current
+}
+
+// StartingFunds returns the money a new player starts with.
+func StartingFunds() float64 {
+	return GetConfig().StartingFunds
+}
@@ -0,0 +1,164 @@
+package economy
+
+import (
+	"math"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+)
+
+// AnnualOperations is one year of running a facility at the configured utilisation.
+type AnnualOperations struct {
+	Revenue         float64 `json:"revenue"`
+	ElectricityCost float64 `json:"electricity_cost"`
+	WaterCost       float64 `json:"water_cost"`
+	StaffingCost    float64 `json:"staffing_cost"`
+	CarbonCost      float64 `json:"carbon_cost"`
+	Opex            float64 `json:"opex"`
+	NetCashFlow     float64 `json:"net_cash_flow"` // revenue minus opex
+}
+
+// YearCashFlow is the cash position of a facility or portfolio in one year.
+// Year 0 is the investment.
+type YearCashFlow struct {
+	Year       int     `json:"year"`
+	Net        float64 `json:"net"`
+	Cumulative float64 `json:"cumulative"`
+}
+
+// Projection is the cash flow, NPV and payback of an investment.
+type Projection struct {
+	Investment   float64        `json:"investment"`
+	CashFlow     []YearCashFlow `json:"cash_flow"`
+	NPV          float64        `json:"npv"`
+	PaybackYears float64        `json:"payback_years"` // -1 if it never pays back within the horizon
+}
+
+// FacilityEconomics is the operating economics of one owned facility.
+type FacilityEconomics struct {
+	SiteID      int              `json:"site_id"`
+	Name        string           `json:"name"`
+	Tier        string           `json:"tier"`
+	CapacityMW  float64          `json:"capacity_mw"`
+	Utilization float64          `json:"utilization"`
+	Footprint   data.Footprint   `json:"footprint"`
+	Annual      AnnualOperations `json:"annual"`
+	Projection
+}
+
+// PortfolioEconomics sums the economics of several facilities.
+type PortfolioEconomics struct {
+	Facilities []FacilityEconomics `json:"facilities"`
+	Annual     AnnualOperations    `json:"annual"`
+	Projection
+}
+
+// Conditions are the inputs a facility operates under. Game events change
+// them for a while; DefaultConditions are a site's normal conditions.
+type Conditions struct {
+	Env              data.EnvironmentalData
+	ElectricityPrice float64 // USD per kWh
+	WaterPriceFactor float64 // multiplier on the configured water price
+	RevenueFactor    float64 // multiplier on revenue
+}
+
+// DefaultConditions returns a site's normal environmental data and prices,
+// including the effect of its installed upgrades.
+func DefaultConditions(loc *data.DatacenterLocation) Conditions {
+	return Conditions{
+		Env:              data.GetEnvironmentalData(loc),
+		ElectricityPrice: data.ElectricityPrice(loc) * data.UpgradePriceFactor(loc),
+		WaterPriceFactor: 1,
+		RevenueFactor:    1,
+	}
+}
+
+// FacilityOperations computes the yearly revenue and opex of a facility.
+// Revenue comes from the sold share of IT capacity; electricity, water and
+// carbon scale with the energy actually drawn at that utilisation, while
+// staffing scales with installed capacity.
+func FacilityOperations(loc *data.DatacenterLocation) (data.Footprint, AnnualOperations) {
+	return OperateUnder(loc, DefaultConditions(loc))
+}
+
+// OperateUnder is FacilityOperations under the given conditions.
+func OperateUnder(loc *data.DatacenterLocation, cond Conditions) (data.Footprint, AnnualOperations) {
+	cfg := GetConfig()
+	fp := data.FootprintWith(loc, cond.Env)
+	u := cfg.Utilization
+
+	ops := AnnualOperations{
+		Revenue:         fp.ITLoadMW * data.HoursPerYear * u * cfg.RevenuePerMWh * cond.RevenueFactor,
+		ElectricityCost: fp.GridEnergyMWh * u * 1000 * cond.ElectricityPrice,
+		WaterCost:       fp.WaterLiters * u / 1000 * cfg.WaterPricePerKL * cond.WaterPriceFactor,
+		StaffingCost:    fp.ITLoadMW * cfg.StaffingPerMW,
+		CarbonCost:      fp.CarbonTons * u * cfg.CarbonPrice,
+	}
+	ops.Opex = ops.ElectricityCost + ops.WaterCost + ops.StaffingCost + ops.CarbonCost
+	ops.NetCashFlow = ops.Revenue - ops.Opex
+	return fp, ops
+}
+
+// EvaluateFacility projects a facility bought for investment over the
+// configured horizon.
+func EvaluateFacility(loc *data.DatacenterLocation, investment float64) FacilityEconomics {
+	cfg := GetConfig()
+	fp, ops := FacilityOperations(loc)
+	return FacilityEconomics{
+		SiteID:      loc.ID,
+		Name:        loc.Name,
+		Tier:        loc.Tier,
+		CapacityMW:  fp.ITLoadMW,
+		Utilization: cfg.Utilization,
+		Footprint:   fp,
+		Annual:      ops,
+		Projection:  Project(investment, ops.NetCashFlow, cfg.HorizonYears, cfg.DiscountRate),
+	}
+}
+
+// EvaluatePortfolio sums facility economics into a portfolio projection.
+func EvaluatePortfolio(facilities []FacilityEconomics) PortfolioEconomics {
+	cfg := GetConfig()
+	p := PortfolioEconomics{Facilities: facilities}
+	if p.Facilities == nil {
+		p.Facilities = []FacilityEconomics{}
+	}
+	var investment float64
+	for _, f := range facilities {
+		investment += f.Investment
+		p.Annual.Revenue += f.Annual.Revenue
+		p.Annual.ElectricityCost += f.Annual.ElectricityCost
+		p.Annual.WaterCost += f.Annual.WaterCost
+		p.Annual.StaffingCost += f.Annual.StaffingCost
+		p.Annual.CarbonCost += f.Annual.CarbonCost
+		p.Annual.Opex += f.Annual.Opex
+		p.Annual.NetCashFlow += f.Annual.NetCashFlow
+	}
+	p.Projection = Project(investment, p.Annual.NetCashFlow, cfg.HorizonYears, cfg.DiscountRate)
+	return p
+}
+
+// Project builds the cash flow of an investment returning the same net cash
+// flow every year, and derives its NPV and (interpolated) payback period.
+func Project(investment, annualNet float64, years int, rate float64) Projection {
+	p := Projection{
+		Investment:   investment,
+		CashFlow:     make([]YearCashFlow, 0, years+1),
+		NPV:          -investment,
+		PaybackYears: -1,
+	}
+	cumulative := -investment
+	p.CashFlow = append(p.CashFlow, YearCashFlow{Year: 0, Net: -investment, Cumulative: cumulative})
+	if investment <= 0 {
+		p.PaybackYears = 0
+	}
+	for y := 1; y <= years; y++ {
+		prev := cumulative
+		cumulative += annualNet
+		p.NPV += annualNet / math.Pow(1+rate, float64(y))
+		p.CashFlow = append(p.CashFlow, YearCashFlow{Year: y, Net: annualNet, Cumulative: cumulative})
+		if p.PaybackYears < 0 && prev < 0 && cumulative >= 0 {
+			p.PaybackYears = float64(y-1) + -prev/annualNet
+		}
+	}
+	return p
+}
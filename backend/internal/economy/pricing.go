@@ -0,0 +1,91 @@
+package economy
+
+import (
+	"fmt"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+)
+
+// Quote is the server-side price of building a tier on a catalog site.
+type Quote struct {
+	SiteID   int     `json:"site_id"`
+	Tier     string  `json:"tier"`
+	LandCost float64 `json:"land_cost"`
+	Capex    float64 `json:"capex"`
+	Total    float64 `json:"total"`
+}
+
+// QuoteSite prices a tier on a site from its parsed land price and the tier capex.
+func QuoteSite(loc *data.DatacenterLocation, tierID string) (Quote, error) {
+	tier, ok := data.GetTier(tierID)
+	if !ok {
+		return Quote{}, fmt.Errorf("unknown tier %q", tierID)
+	}
+	land, err := data.LandCost(loc)
+	if err != nil {
+		return Quote{}, fmt.Errorf("cannot price site %d: %v", loc.ID, err)
+	}
+	return Quote{
+		SiteID:   loc.ID,
+		Tier:     tier.ID,
+		LandCost: land,
+		Capex:    tier.Capex,
+		Total:    land + tier.Capex,
+	}, nil
+}
+
+// QuoteAllTiers prices every tier on a site.
+func QuoteAllTiers(loc *data.DatacenterLocation) ([]Quote, error) {
+	quotes := make([]Quote, 0, len(data.Tiers))
+	for _, t := range data.Tiers {
+		q, err := QuoteSite(loc, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, nil
+}
+
+// UpgradeQuote is the server-side price of an upgrade on an owned facility.
+type UpgradeQuote struct {
+	Upgrade       string  `json:"upgrade"`
+	Tier          string  `json:"tier,omitempty"` // target tier of a tier upgrade
+	Cost          float64 `json:"cost"`
+	DelayQuarters int     `json:"delay_quarters"`
+}
+
+// QuoteUpgrade prices an upgrade on a facility. Tier upgrades must move to a
+// tier with a higher capex and cost the difference plus a retrofit premium;
+// other upgrades are priced per MW of the facility's IT capacity and can only
+// be installed once.
+func QuoteUpgrade(loc *data.DatacenterLocation, upgradeID, tierID string) (UpgradeQuote, error) {
+	u, ok := data.GetUpgrade(upgradeID)
+	if !ok {
+		return UpgradeQuote{}, fmt.Errorf("unknown upgrade %q", upgradeID)
+	}
+	current, ok := data.GetTier(loc.Tier)
+	if !ok {
+		current, _ = data.GetTier(data.TierStandard)
+	}
+
+	q := UpgradeQuote{Upgrade: u.ID, DelayQuarters: u.DelayQuarters}
+	if u.ID == data.UpgradeTier {
+		target, ok := data.GetTier(tierID)
+		if !ok {
+			return UpgradeQuote{}, fmt.Errorf("unknown tier %q", tierID)
+		}
+		if target.Capex <= current.Capex {
+			return UpgradeQuote{}, fmt.Errorf("cannot upgrade from %s to %s", current.ID, target.ID)
+		}
+		q.Tier = target.ID
+		q.Cost = (target.Capex - current.Capex) * (1 + data.TierUpgradePremium)
+		return q, nil
+	}
+
+	if data.HasUpgrade(loc, u.ID) {
+		return UpgradeQuote{}, fmt.Errorf("%s is already installed", u.Name)
+	}
+	q.Cost = u.CostPerMW * current.CapacityMW
+	return q, nil
+}
@@ -0,0 +1,313 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// DefaultScenario is the scenario games are played under unless they pick one.
+const DefaultScenario = "default"
+
+// Event types. They only label events; what an event does is set by its effects.
+const (
+	EventHeatwave    = "heatwave"
+	EventDrought     = "drought"
+	EventGridOutage  = "grid-outage"
+	EventRegulation  = "regulation"
+	EventPriceShock  = "price-shock"
+	EventSubsidy     = "subsidy"
+	EventDemandSurge = "demand-surge"
+)
+
+// Scenario is a set of events a game can be played under. Instructors script
+// lessons by writing scenario files with fixed-tick events.
+type Scenario struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Events      []EventDef `json:"events"`
+}
+
+// EventDef defines an event: when it fires, where, for how long and what it does.
+type EventDef struct {
+	ID          string  `json:"id"`
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Trigger     Trigger `json:"trigger"`
+	Region      Region  `json:"region"`
+	Duration    int     `json:"duration"` // quarters, at least 1
+	Effects     Effects `json:"effects"`
+}
+
+// Trigger holds the conditions under which an event starts. All set
+// conditions must hold; an event never runs twice at the same time.
+type Trigger struct {
+	AtTick        int     `json:"at_tick,omitempty"`         // fires on exactly this tick (1-based)
+	Probability   float64 `json:"probability,omitempty"`     // chance per quarter otherwise
+	FromTick      int     `json:"from_tick,omitempty"`       // earliest tick
+	UntilTick     int     `json:"until_tick,omitempty"`      // latest tick, 0 for no limit
+	MinFacilities int     `json:"min_facilities,omitempty"`  // player must own at least this many sites
+	MinCarbonTons float64 `json:"min_carbon_tons,omitempty"` // cumulative emissions so far
+	Once          bool    `json:"once,omitempty"`            // fire at most once per game
+}
+
+// Region limits an event to some states; an empty region is nationwide.
+type Region struct {
+	States []string `json:"states,omitempty"`
+}
+
+// Effects change the inputs and prices of facilities in the region while the
+// event runs. Zero factors mean "unchanged".
+type Effects struct {
+	AmbientTemperatureDelta float64 `json:"ambient_temperature_delta,omitempty"` // °C, raises PUE
+	WaterScarcityFactor     float64 `json:"water_scarcity_factor,omitempty"`
+	GridEmissionsFactor     float64 `json:"grid_emissions_factor,omitempty"`
+	ElectricityPriceFactor  float64 `json:"electricity_price_factor,omitempty"`
+	WaterPriceFactor        float64 `json:"water_price_factor,omitempty"`
+	RevenueFactor           float64 `json:"revenue_factor,omitempty"`
+	CarbonTaxDelta          float64 `json:"carbon_tax_delta,omitempty"` // USD per tonne, nationwide
+	SubsidyPerMW            float64 `json:"subsidy_per_mw,omitempty"`   // USD per MW per quarter
+}
+
+// ActiveEvent is an event running in a game.
+type ActiveEvent struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	StartTick   int    `json:"start_tick"`
+	EndTick     int    `json:"end_tick"` // last tick the event applies to
+}
+
+// EventLogEntry records an event starting or ending in a game.
+type EventLogEntry struct {
+	Tick    int    `json:"tick"`
+	Year    int    `json:"year"`
+	Quarter int    `json:"quarter"`
+	EventID string `json:"event_id"`
+	Action  string `json:"action"` // "started" or "ended"
+}
+
+var (
+	scenarios  = map[string]Scenario{DefaultScenario: defaultScenario}
+	scenarioMu sync.RWMutex
+)
+
+// defaultScenario is the built-in set of random events.
+var defaultScenario = Scenario{
+	Name:        DefaultScenario,
+	Description: "Random weather, grid and market events",
+	Events: []EventDef{
+		{ID: "heatwave", Type: EventHeatwave, Description: "Heatwave drives up cooling load and power prices",
+			Trigger: Trigger{Probability: 0.10}, Duration: 1,
+			Effects: Effects{AmbientTemperatureDelta: 6, ElectricityPriceFactor: 1.25}},
+		{ID: "drought", Type: EventDrought, Description: "Drought raises water scarcity and prices",
+			Trigger: Trigger{Probability: 0.05}, Duration: 2,
+			Effects: Effects{WaterScarcityFactor: 1.5, WaterPriceFactor: 2}},
+		{ID: "demand-surge", Type: EventDemandSurge, Description: "AI demand surge lifts utilisation",
+			Trigger: Trigger{Probability: 0.08}, Duration: 1,
+			Effects: Effects{RevenueFactor: 1.15, ElectricityPriceFactor: 1.05}},
+		{ID: "grid-outage", Type: EventGridOutage, Description: "Grid outage forces customers onto backup sites",
+			Trigger: Trigger{Probability: 0.05}, Duration: 1,
+			Effects: Effects{RevenueFactor: 0.9, GridEmissionsFactor: 1.3}},
+		{ID: "cheap-power", Type: EventPriceShock, Description: "Mild weather and cheap gas lower power prices",
+			Trigger: Trigger{Probability: 0.08}, Duration: 1,
+			Effects: Effects{ElectricityPriceFactor: 0.85}},
+	},
+}
+
+// LoadScenarios reads every *.json scenario file from dir. A missing
+// directory is fine; a file named default.json replaces the built-in default
+// scenario.
+func LoadScenarios(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	loaded := map[string]Scenario{DefaultScenario: defaultScenario}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var s Scenario
+		if err := json.Unmarshal(content, &s); err != nil {
+			return fmt.Errorf("failed to parse scenario file %s: %v", path, err)
+		}
+		if s.Name == "" {
+			s.Name = strings.TrimSuffix(file.Name(), ".json")
+		}
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		loaded[s.Name] = s
+	}
+
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	scenarios = loaded
+	return nil
+}
+
+func (s Scenario) validate() error {
+	seen := make(map[string]bool)
+	for _, e := range s.Events {
+		if e.ID == "" {
+			return fmt.Errorf("scenario %q: every event needs an id", s.Name)
+		}
+		if seen[e.ID] {
+			return fmt.Errorf("scenario %q: duplicate event id %q", s.Name, e.ID)
+		}
+		seen[e.ID] = true
+		if e.Duration < 1 {
+			return fmt.Errorf("scenario %q: event %q needs a duration of at least 1 quarter", s.Name, e.ID)
+		}
+		if e.Trigger.AtTick == 0 && e.Trigger.Probability <= 0 {
+			return fmt.Errorf("scenario %q: event %q needs at_tick or a probability", s.Name, e.ID)
+		}
+	}
+	return nil
+}
+
+// GetScenario looks up a scenario by name. An empty name means the default.
+func GetScenario(name string) (Scenario, bool) {
+	if name == "" {
+		name = DefaultScenario
+	}
+	scenarioMu.RLock()
+	defer scenarioMu.RUnlock()
+	s, ok := scenarios[name]
+	return s, ok
+}
+
+// ListScenarios returns all scenarios sorted by name.
+func ListScenarios() []Scenario {
+	scenarioMu.RLock()
+	defer scenarioMu.RUnlock()
+	list := make([]Scenario, 0, len(scenarios))
+	for _, s := range scenarios {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// rollEvents starts the scenario events whose triggers fire on this tick. It
+// always makes one draw per event definition so the sequence doesn't depend
+// on what happened before.
+func rollEvents(rng *rand.Rand, g *Game, s Scenario, tick, facilities int) []ActiveEvent {
+	var started []ActiveEvent
+	for _, def := range s.Events {
+		roll := rng.Float64()
+		if !def.Trigger.fires(g, def.ID, tick, facilities, roll) {
+			continue
+		}
+		started = append(started, ActiveEvent{
+			ID:          def.ID,
+			Type:        def.Type,
+			Description: def.Description,
+			StartTick:   tick,
+			EndTick:     tick + def.Duration - 1,
+		})
+	}
+	return started
+}
+
+func (t Trigger) fires(g *Game, id string, tick, facilities int, roll float64) bool {
+	if g.eventActive(id) {
+		return false
+	}
+	if t.Once && g.eventStarted(id) {
+		return false
+	}
+	if t.FromTick > 0 && tick < t.FromTick {
+		return false
+	}
+	if t.UntilTick > 0 && tick > t.UntilTick {
+		return false
+	}
+	if facilities < t.MinFacilities || g.CarbonTons < t.MinCarbonTons {
+		return false
+	}
+	if t.AtTick > 0 {
+		return tick == t.AtTick
+	}
+	return roll < t.Probability
+}
+
+func (g *Game) eventActive(id string) bool {
+	for _, e := range g.ActiveEvents {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) eventStarted(id string) bool {
+	for _, entry := range g.EventLog {
+		if entry.EventID == id && entry.Action == "started" {
+			return true
+		}
+	}
+	return false
+}
+
+// inRegion reports whether a location is affected by an event's region.
+func (r Region) inRegion(loc *data.DatacenterLocation) bool {
+	if len(r.States) == 0 {
+		return true
+	}
+	state := data.StateCode(loc)
+	for _, st := range r.States {
+		if strings.EqualFold(st, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply changes a facility's conditions by the event's effects.
+func (e Effects) apply(cond *economy.Conditions) {
+	cond.Env.AmbientTemperature += e.AmbientTemperatureDelta
+	if e.WaterScarcityFactor > 0 {
+		cond.Env.WaterScarcityIndex *= e.WaterScarcityFactor
+	}
+	if e.GridEmissionsFactor > 0 {
+		cond.Env.GridEmissionsIntensity *= e.GridEmissionsFactor
+	}
+	if e.ElectricityPriceFactor > 0 {
+		cond.ElectricityPrice *= e.ElectricityPriceFactor
+	}
+	if e.WaterPriceFactor > 0 {
+		cond.WaterPriceFactor *= e.WaterPriceFactor
+	}
+	if e.RevenueFactor > 0 {
+		cond.RevenueFactor *= e.RevenueFactor
+	}
+}
+
+// eventDef finds the definition of an active event in the game's scenario.
+func eventDef(s Scenario, id string) (EventDef, bool) {
+	for _, def := range s.Events {
+		if def.ID == id {
+			return def, true
+		}
+	}
+	return EventDef{}, false
+}
@@ -0,0 +1,215 @@
+// Package game runs the turn-based game clock. Each player has one game that
+// advances a quarter at a time, settling operating results into their cart
+// balance. Ticks are deterministic under the game's seed so games can be replayed.
+package game
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// StartYear is the calendar year of a game's first quarter.
+const StartYear = 2025
+
+var (
+	// games holds the in-memory mapping from username to their current game.
+	games  = make(map[string]*Game)
+	gameMu sync.Mutex
+)
+
+// Game is a player's running game.
+type Game struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Seed        uint64 `json:"seed"`
+	Quarter     int    `json:"quarter"` // ticks played so far
+	AutoAdvance bool   `json:"auto_advance"`
+	Bankrupt    bool   `json:"bankrupt"`
+	Finished    bool   `json:"finished"` // scored with Finish; no longer advances
+
+	CarbonPolicy economy.CarbonPolicy `json:"carbon_policy"`
+	RECShare     float64              `json:"rec_share"` // share of grid energy matched with RECs each quarter
+	Scenario     string               `json:"scenario"`
+	Classroom    string               `json:"classroom,omitempty"` // class the player competes in
+
+	ActiveEvents []ActiveEvent   `json:"active_events"`
+	EventLog     []EventLogEntry `json:"event_log"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Running totals over all ticks.
+	Revenue        float64 `json:"revenue"`
+	Opex           float64 `json:"opex"`
+	CarbonTons     float64 `json:"carbon_tons"`
+	EnergyMWh      float64 `json:"energy_mwh"`
+	WaterLiters    float64 `json:"water_liters"`
+	DemandMWh      float64 `json:"demand_mwh"`
+	UnmetDemandMWh float64 `json:"unmet_demand_mwh"`
+	Score          float64 `json:"score"`
+
+	History []TickReport `json:"history"`
+}
+
+// Options configure a new game.
+type Options struct {
+	Seed         uint64 // 0 picks a random seed
+	AutoAdvance  bool
+	CarbonPolicy economy.CarbonPolicy
+	Scenario     string // empty for DefaultScenario
+	Classroom    string
+}
+
+// Date returns the calendar year and quarter (1-4) of the next tick.
+func (g *Game) Date() (year, quarter int) {
+	return StartYear + g.Quarter/4, g.Quarter%4 + 1
+}
+
+// LoadAllGames loads all games from the store when the app starts.
+func LoadAllGames() error {
+	docs, err := store.Default().LoadGames()
+	if err != nil {
+		return err
+	}
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	for username, content := range docs {
+		var g Game
+		if err := json.Unmarshal(content, &g); err != nil {
+			fmt.Printf("Error unmarshaling game for %s: %v\n", username, err)
+			continue
+		}
+		if g.Scenario == "" {
+			g.Scenario = DefaultScenario
+		}
+		if g.CarbonPolicy.Name == "" {
+			// Games saved before carbon policies existed play under the default.
+			g.CarbonPolicy, _ = economy.GetCarbonPolicy("")
+		}
+		games[g.Username] = &g
+	}
+	return nil
+}
+
+// FlushAll writes every game to the store, for a clean shutdown.
+func FlushAll() error {
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	for _, g := range games {
+		if err := saveNoLock(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveNoLock writes a game to the store assuming the lock is held.
+func saveNoLock(g *Game) error {
+	key, err := user.StoreKey(g.Username)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Default().SaveGame(key, content)
+}
+
+// New starts a new game for the user, replacing any previous one.
+func New(username string, opts Options) (*Game, error) {
+	if err := opts.CarbonPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Scenario == "" {
+		opts.Scenario = DefaultScenario
+	}
+	if _, ok := GetScenario(opts.Scenario); !ok {
+		return nil, fmt.Errorf("unknown scenario %q", opts.Scenario)
+	}
+	id, err := newGameID()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Seed == 0 {
+		opts.Seed = uint64(time.Now().UnixNano())
+	}
+	now := time.Now().UTC()
+	g := &Game{
+		ID:           id,
+		Username:     username,
+		Seed:         opts.Seed,
+		AutoAdvance:  opts.AutoAdvance,
+		CarbonPolicy: opts.CarbonPolicy,
+		Scenario:     opts.Scenario,
+		Classroom:    opts.Classroom,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ActiveEvents: []ActiveEvent{},
+		EventLog:     []EventLogEntry{},
+		History:      []TickReport{},
+	}
+
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	if err := saveNoLock(g); err != nil {
+		return nil, err
+	}
+	games[username] = g
+	return copyGame(g), nil
+}
+
+// SetRECShare sets the share of grid energy the player covers with renewable
+// energy certificates from the next quarter on.
+func SetRECShare(username string, share float64) (*Game, error) {
+	if share < 0 || share > 1 {
+		return nil, fmt.Errorf("REC share must be between 0 and 1")
+	}
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	g, ok := games[username]
+	if !ok {
+		return nil, fmt.Errorf("no game found for user %s", username)
+	}
+	g.RECShare = share
+	g.UpdatedAt = time.Now().UTC()
+	if err := saveNoLock(g); err != nil {
+		return nil, err
+	}
+	return copyGame(g), nil
+}
+
+// Get returns a copy of the user's current game.
+func Get(username string) (*Game, bool) {
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	g, ok := games[username]
+	if !ok {
+		return nil, false
+	}
+	return copyGame(g), true
+}
+
+func copyGame(g *Game) *Game {
+	c := *g
+	c.ActiveEvents = append([]ActiveEvent(nil), g.ActiveEvents...)
+	c.EventLog = append([]EventLogEntry(nil), g.EventLog...)
+	c.History = append([]TickReport(nil), g.History...)
+	return &c
+}
+
+func newGameID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+var (
+	// records holds the in-memory mapping from username to their results.
+	records  = make(map[string]*Record)
+	resultMu sync.Mutex
+)
+
+// UnlockedAchievement records when and in which game a player unlocked an achievement.
+type UnlockedAchievement struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	GameID     string    `json:"game_id"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+// Record holds a player's finished games and achievements.
+type Record struct {
+	Username     string                `json:"username"`
+	Results      []Result              `json:"results"`
+	Achievements []UnlockedAchievement `json:"achievements"`
+	BestScore    float64               `json:"best_score"`
+}
+
+// LoadAllResults loads all result records from the store when the app starts.
+func LoadAllResults() error {
+	docs, err := store.Default().LoadResults()
+	if err != nil {
+		return err
+	}
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	for username, content := range docs {
+		var rec Record
+		if err := json.Unmarshal(content, &rec); err != nil {
+			fmt.Printf("Error unmarshaling results for %s: %v\n", username, err)
+			continue
+		}
+		records[rec.Username] = &rec
+	}
+	return nil
+}
+
+// saveRecordNoLock writes a record to the store assuming the lock is held.
+func saveRecordNoLock(rec *Record) error {
+	key, err := user.StoreKey(rec.Username)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Default().SaveResults(key, content)
+}
+
+// recordResult stores a finished game's result in the player's record and
+// unlocks the achievements it earns for the first time.
+func recordResult(r *Result) error {
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	rec, ok := records[r.Username]
+	if !ok {
+		rec = &Record{
+			Username:     r.Username,
+			Results:      []Result{},
+			Achievements: []UnlockedAchievement{},
+			BestScore:    r.Score,
+		}
+	}
+	for _, a := range GetAchievements().Achievements {
+		if rec.hasAchievement(a.ID) || !r.Outcome.meets(a.Conditions) {
+			continue
+		}
+		rec.Achievements = append(rec.Achievements, UnlockedAchievement{
+			ID:         a.ID,
+			Name:       a.Name,
+			GameID:     r.GameID,
+			UnlockedAt: r.FinishedAt,
+		})
+		r.Unlocked = append(r.Unlocked, a.ID)
+	}
+	rec.Results = append(rec.Results, *r)
+	if r.Score > rec.BestScore {
+		rec.BestScore = r.Score
+	}
+	if err := saveRecordNoLock(rec); err != nil {
+		return err
+	}
+	records[r.Username] = rec
+	return nil
+}
+
+func (rec *Record) hasAchievement(id string) bool {
+	for _, a := range rec.Achievements {
+		if a.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRecord returns a copy of the user's record.
+func GetRecord(username string) (*Record, bool) {
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	rec, ok := records[username]
+	if !ok {
+		return nil, false
+	}
+	c := *rec
+	c.Results = append([]Result(nil), rec.Results...)
+	c.Achievements = append([]UnlockedAchievement(nil), rec.Achievements...)
+	return &c, true
+}
+
+// AllResults returns a copy of every player's finished game results.
+func AllResults() []Result {
+	resultMu.Lock()
+	defer resultMu.Unlock()
+	var all []Result
+	for _, rec := range records {
+		all = append(all, rec.Results...)
+	}
+	return all
+}
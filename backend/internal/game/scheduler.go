@@ -0,0 +1,48 @@
+package game
+
+import (
+	"log"
+	"time"
+)
+
+// StartScheduler advances every game with AutoAdvance set by one quarter per
+// interval, until the returned stop function is called. Stop waits for a tick
+// in progress to finish.
+func StartScheduler(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				for _, username := range autoAdvanceUsers() {
+					if _, err := Advance(username, 1); err != nil {
+						log.Printf("Scheduled tick for %s failed: %v", username, err)
+					}
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// autoAdvanceUsers lists the players whose games run on the schedule.
+func autoAdvanceUsers() []string {
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	var users []string
+	for username, g := range games {
+		if g.AutoAdvance && !g.Bankrupt && !g.Finished {
+			users = append(users, username)
+		}
+	}
+	return users
+}
@@ -0,0 +1,373 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// Final score weights. Profit is net worth gained over the starting funds;
+// the environmental part charges cumulative carbon and water, rewards the
+// average eco score of the portfolio and charges every year the portfolio
+// brings the climate threshold forward in the simulation. Unmet demand is
+// charged at scorePerUnmetMWh.
+const (
+	scorePerLiter    = 1.0 / 1e8
+	scorePerEcoPoint = 0.1
+	scorePerYearLost = 1.0
+)
+
+// Rank is a title awarded for a final score of at least MinScore.
+type Rank struct {
+	Name     string  `json:"name"`
+	MinScore float64 `json:"min_score"`
+}
+
+// Ranks are ordered from best to worst; the last one catches every score.
+var Ranks = []Rank{
+	{Name: "Climate Champion", MinScore: 50},
+	{Name: "Sustainable Operator", MinScore: 20},
+	{Name: "Responsible Builder", MinScore: 0},
+	{Name: "Short-term Thinker", MinScore: -20},
+	{Name: "Polluter", MinScore: -1e18},
+}
+
+// Condition compares an outcome metric (see Outcome.Metric) with a value.
+type Condition struct {
+	Metric string  `json:"metric"`
+	Op     string  `json:"op"` // ">=", ">", "<=" or "<"
+	Value  float64 `json:"value"`
+}
+
+// Objective is a goal that adds points to the final score when all of its
+// conditions hold, e.g. "serve 100 MW with carbon below 0.2 t/MWh".
+type Objective struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Conditions  []Condition `json:"conditions"`
+	Points      float64     `json:"points"`
+}
+
+// Achievement is unlocked for good the first time a finished game meets all
+// of its conditions.
+type Achievement struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Conditions  []Condition `json:"conditions"`
+}
+
+// AchievementSet is the content of the achievements data file.
+type AchievementSet struct {
+	Objectives   []Objective   `json:"objectives"`
+	Achievements []Achievement `json:"achievements"`
+}
+
+var (
+	achievements   AchievementSet
+	achievementsMu sync.RWMutex
+)
+
+// LoadAchievements reads the objectives and achievements from a JSON file. A
+// missing file leaves games without objectives or achievements.
+func LoadAchievements(filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var set AchievementSet
+	if err := json.Unmarshal(content, &set); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	if err := set.validate(); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	achievementsMu.Lock()
+	defer achievementsMu.Unlock()
+	achievements = set
+	return nil
+}
+
+// GetAchievements returns the loaded objectives and achievements.
+func GetAchievements() AchievementSet {
+	achievementsMu.RLock()
+	defer achievementsMu.RUnlock()
+	return achievements
+}
+
+func (s AchievementSet) validate() error {
+	seen := make(map[string]bool)
+	check := func(id string, conds []Condition) error {
+		if id == "" {
+			return fmt.Errorf("every objective and achievement needs an id")
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate id %q", id)
+		}
+		seen[id] = true
+		if len(conds) == 0 {
+			return fmt.Errorf("%q has no conditions", id)
+		}
+		for _, c := range conds {
+			if _, ok := (Outcome{}).Metric(c.Metric); !ok {
+				return fmt.Errorf("%q: unknown metric %q", id, c.Metric)
+			}
+			switch c.Op {
+			case ">=", ">", "<=", "<":
+			default:
+				return fmt.Errorf("%q: unknown operator %q", id, c.Op)
+			}
+		}
+		return nil
+	}
+	for _, o := range s.Objectives {
+		if err := check(o.ID, o.Conditions); err != nil {
+			return err
+		}
+	}
+	for _, a := range s.Achievements {
+		if err := check(a.ID, a.Conditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outcome holds the results a finished game is scored on.
+type Outcome struct {
+	NetWorth               float64 `json:"net_worth"`         // cash plus resale value of owned sites
+	Profit                 float64 `json:"profit"`            // net worth minus the starting funds
+	BudgetEfficiency       float64 `json:"budget_efficiency"` // profit per dollar of starting funds
+	CarbonTons             float64 `json:"carbon_tons"`
+	WaterLiters            float64 `json:"water_liters"`
+	EcoScore               float64 `json:"eco_score"` // average over owned sites
+	CapacityMW             float64 `json:"capacity_mw"`
+	CarbonIntensity        float64 `json:"carbon_intensity"`         // t CO2e per MWh used over the game
+	TimeDatacentersRemoved int     `json:"time_datacenters_removed"` // years lost in the climate simulation
+	DemandServed           float64 `json:"demand_served"`            // share of metro demand served over the game
+	UnmetDemandMWh         float64 `json:"unmet_demand_mwh"`
+	Facilities             int     `json:"facilities"`
+	Quarters               int     `json:"quarters"`
+	Bankrupt               bool    `json:"bankrupt"`
+}
+
+// Metric returns an outcome value by its JSON name, for use in conditions.
+func (o Outcome) Metric(name string) (float64, bool) {
+	switch name {
+	case "net_worth":
+		return o.NetWorth, true
+	case "profit":
+		return o.Profit, true
+	case "budget_efficiency":
+		return o.BudgetEfficiency, true
+	case "carbon_tons":
+		return o.CarbonTons, true
+	case "water_liters":
+		return o.WaterLiters, true
+	case "eco_score":
+		return o.EcoScore, true
+	case "capacity_mw":
+		return o.CapacityMW, true
+	case "carbon_intensity":
+		return o.CarbonIntensity, true
+	case "demand_served":
+		return o.DemandServed, true
+	case "unmet_demand_mwh":
+		return o.UnmetDemandMWh, true
+	case "time_datacenters_removed":
+		return float64(o.TimeDatacentersRemoved), true
+	case "facilities":
+		return float64(o.Facilities), true
+	case "quarters":
+		return float64(o.Quarters), true
+	case "bankrupt":
+		if o.Bankrupt {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// meets reports whether the outcome satisfies every condition.
+func (o Outcome) meets(conds []Condition) bool {
+	for _, c := range conds {
+		v, ok := o.Metric(c.Metric)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case ">=":
+			ok = v >= c.Value
+		case ">":
+			ok = v > c.Value
+		case "<=":
+			ok = v <= c.Value
+		case "<":
+			ok = v < c.Value
+		default:
+			ok = false
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ObjectiveResult records whether a finished game met an objective.
+type ObjectiveResult struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Met    bool    `json:"met"`
+	Points float64 `json:"points"` // points awarded, 0 if not met
+}
+
+// ScoreBreakdown splits a final score into its parts.
+type ScoreBreakdown struct {
+	Economic      float64 `json:"economic"`
+	Environmental float64 `json:"environmental"`
+	Service       float64 `json:"service"` // penalty for unmet demand
+	Objectives    float64 `json:"objectives"`
+}
+
+// Result is the final result of a finished game.
+type Result struct {
+	GameID       string            `json:"game_id"`
+	Username     string            `json:"username"`
+	Scenario     string            `json:"scenario"`
+	Classroom    string            `json:"classroom,omitempty"`
+	CarbonPolicy string            `json:"carbon_policy"`
+	Seed         uint64            `json:"seed"`
+	FinishedAt   time.Time         `json:"finished_at"`
+	Outcome      Outcome           `json:"outcome"`
+	Breakdown    ScoreBreakdown    `json:"breakdown"`
+	Score        float64           `json:"score"`
+	Rank         string            `json:"rank"`
+	Objectives   []ObjectiveResult `json:"objectives"`
+	Unlocked     []string          `json:"unlocked"` // achievements unlocked by this game
+}
+
+// Finish ends the user's game and scores it. The climate simulation lives in
+// the handlers, so the caller passes in the years the portfolio takes off
+// the simulated time to the survivability threshold.
+func Finish(username string, timeDatacentersRemoved int) (*Result, error) {
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	g, ok := games[username]
+	if !ok {
+		return nil, fmt.Errorf("no game found for user %s", username)
+	}
+	if g.Finished {
+		return nil, fmt.Errorf("game %s is already finished", g.ID)
+	}
+	if g.Quarter == 0 {
+		return nil, fmt.Errorf("play at least one quarter before finishing")
+	}
+
+	outcome := gameOutcome(g, timeDatacentersRemoved)
+	result := scoreOutcome(outcome, GetAchievements())
+	result.GameID = g.ID
+	result.Username = username
+	result.Scenario = g.Scenario
+	result.Classroom = g.Classroom
+	result.CarbonPolicy = g.CarbonPolicy.Name
+	result.Seed = g.Seed
+	result.FinishedAt = time.Now().UTC()
+
+	if err := recordResult(&result); err != nil {
+		return nil, err
+	}
+	g.Finished = true
+	g.AutoAdvance = false
+	g.UpdatedAt = result.FinishedAt
+	if err := saveNoLock(g); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// gameOutcome collects the results of a game and the portfolio it ends with.
+func gameOutcome(g *Game, timeDatacentersRemoved int) Outcome {
+	o := Outcome{
+		NetWorth:               cart.NetWorth(g.Username),
+		CarbonTons:             g.CarbonTons,
+		WaterLiters:            g.WaterLiters,
+		TimeDatacentersRemoved: timeDatacentersRemoved,
+		Quarters:               g.Quarter,
+		Bankrupt:               g.Bankrupt,
+	}
+	o.Profit = o.NetWorth - economy.StartingFunds()
+	o.BudgetEfficiency = o.Profit / economy.StartingFunds()
+	if g.EnergyMWh > 0 {
+		o.CarbonIntensity = g.CarbonTons / g.EnergyMWh
+	}
+	o.UnmetDemandMWh = g.UnmetDemandMWh
+	if g.DemandMWh > 0 {
+		o.DemandServed = 1 - g.UnmetDemandMWh/g.DemandMWh
+	}
+	items := cart.GetItems(g.Username)
+	for _, item := range items {
+		loc := item.DatacenterLocation
+		// The metrics only depend on the site itself, not on the other data centers.
+		data.CalculateResearchBasedMetrics(&loc, nil)
+		o.EcoScore += float64(loc.EcoScore)
+		o.CapacityMW += data.AnnualFootprint(&loc).ITLoadMW
+	}
+	o.Facilities = len(items)
+	if o.Facilities > 0 {
+		o.EcoScore /= float64(o.Facilities)
+	}
+	return o
+}
+
+// scoreOutcome computes the final score and rank of an outcome and checks it
+// against the objectives. Unlocked is left for recordResult to fill in.
+func scoreOutcome(o Outcome, set AchievementSet) Result {
+	r := Result{
+		Outcome: o,
+		Breakdown: ScoreBreakdown{
+			Economic: o.Profit * scorePerDollar,
+			Environmental: -o.CarbonTons*scorePerTonne -
+				o.WaterLiters*scorePerLiter +
+				o.EcoScore*scorePerEcoPoint -
+				float64(o.TimeDatacentersRemoved)*scorePerYearLost,
+			Service: -o.UnmetDemandMWh * scorePerUnmetMWh,
+		},
+		Objectives: []ObjectiveResult{},
+		Unlocked:   []string{},
+	}
+	for _, obj := range set.Objectives {
+		res := ObjectiveResult{ID: obj.ID, Name: obj.Name, Met: o.meets(obj.Conditions)}
+		if res.Met {
+			res.Points = obj.Points
+			r.Breakdown.Objectives += obj.Points
+		}
+		r.Objectives = append(r.Objectives, res)
+	}
+	r.Score = r.Breakdown.Economic + r.Breakdown.Environmental + r.Breakdown.Service + r.Breakdown.Objectives
+	r.Rank = RankFor(r.Score)
+	return r
+}
+
+// RankFor returns the name of the rank a final score earns.
+func RankFor(score float64) string {
+	for _, rank := range Ranks {
+		if score >= rank.MinScore {
+			return rank.Name
+		}
+	}
+	return Ranks[len(Ranks)-1].Name
+}
@@ -0,0 +1,211 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// MaxTicksPerAdvance bounds how far a single /game/advance call may move the clock.
+const MaxTicksPerAdvance = 40
+
+// TickReport is the outcome of one quarter.
+type TickReport struct {
+	Tick        int                   `json:"tick"` // 1-based quarter number
+	Year        int                   `json:"year"`
+	Quarter     int                   `json:"quarter"` // 1-4
+	Facilities  int                   `json:"facilities"`
+	DemandMW    float64               `json:"demand_mw"` // metro demand this quarter
+	ServedMW    float64               `json:"served_mw"`
+	UnmetMW     float64               `json:"unmet_mw"`
+	Revenue     float64               `json:"revenue"`
+	Subsidies   float64               `json:"subsidies"`
+	Opex        float64               `json:"opex"`
+	Net         float64               `json:"net"`
+	CarbonTons  float64               `json:"carbon_tons"` // net of RECs
+	EnergyMWh   float64               `json:"energy_mwh"`
+	WaterLiters float64               `json:"water_liters"`
+	Carbon      economy.CarbonCharges `json:"carbon"`
+	Events      []ActiveEvent         `json:"events"`              // events running this quarter
+	Installed   []string              `json:"installed,omitempty"` // upgrades that took effect this quarter
+	Balance     float64               `json:"balance"`             // MoneyLeft after settlement
+	Score       float64               `json:"score"`               // game score after this tick
+}
+
+// Score weights: one point per $1M of operating profit, minus one point per
+// 10,000 tonnes of CO2e emitted and per 50,000 MWh of demand left unmet.
+const (
+	scorePerDollar   = 1.0 / 1e6
+	scorePerTonne    = 1.0 / 1e4
+	scorePerUnmetMWh = 1.0 / 5e4
+)
+
+// Advance moves the user's game forward by n quarters and returns the
+// reports of the ticks played. A bankrupt or finished game doesn't advance.
+func Advance(username string, n int) ([]TickReport, error) {
+	if n <= 0 || n > MaxTicksPerAdvance {
+		return nil, fmt.Errorf("ticks must be between 1 and %d", MaxTicksPerAdvance)
+	}
+
+	gameMu.Lock()
+	defer gameMu.Unlock()
+	g, ok := games[username]
+	if !ok {
+		return nil, fmt.Errorf("no game found for user %s", username)
+	}
+	if g.Finished {
+		return nil, fmt.Errorf("game %s is finished", g.ID)
+	}
+
+	var reports []TickReport
+	var tickErr error
+	for i := 0; i < n && !g.Bankrupt; i++ {
+		report, err := tickNoLock(g)
+		if err != nil {
+			tickErr = err
+			break
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) == 0 {
+		return nil, tickErr
+	}
+	// The ticks played before a failed one are settled, so they are kept.
+	g.UpdatedAt = time.Now().UTC()
+	if err := saveNoLock(g); err != nil {
+		return reports, err
+	}
+	return reports, tickErr
+}
+
+// tickNoLock plays one quarter: it ends expired events, rolls new ones from
+// the game's scenario, runs every facility in the cart for three months under
+// the events affecting it and settles the result into the balance. The random
+// source depends only on the seed and the tick number, so replaying the same
+// decisions replays the same game. The quarter is played on a copy of the
+// game with the upgrades due this quarter installed on a copy of the cart;
+// both replace the originals only once the result is settled, so if
+// settling fails the game and the cart are left as they were.
+func tickNoLock(g *Game) (TickReport, error) {
+	scenario, ok := GetScenario(g.Scenario)
+	if !ok {
+		return TickReport{}, fmt.Errorf("unknown scenario %q", g.Scenario)
+	}
+	rng := rand.New(rand.NewPCG(g.Seed, uint64(g.Quarter)))
+	year, quarter := g.Date()
+	tick := g.Quarter + 1
+	items, installed := cart.ItemsAfterUpgrades(g.Username, tick)
+
+	next := copyGame(g)
+	next.expireEvents(tick, year, quarter)
+	for _, e := range rollEvents(rng, next, scenario, tick, len(items)) {
+		next.ActiveEvents = append(next.ActiveEvents, e)
+		next.EventLog = append(next.EventLog, EventLogEntry{Tick: tick, Year: year, Quarter: quarter, EventID: e.ID, Action: "started"})
+	}
+	report := TickReport{
+		Tick:      tick,
+		Year:      year,
+		Quarter:   quarter,
+		Events:    append([]ActiveEvent{}, next.ActiveEvents...),
+		Installed: installed,
+	}
+
+	policy := next.CarbonPolicy
+	var active []EventDef
+	for _, e := range next.ActiveEvents {
+		if def, ok := eventDef(scenario, e.ID); ok {
+			active = append(active, def)
+			policy.TaxPerTonne = math.Max(0, policy.TaxPerTonne+def.Effects.CarbonTaxDelta)
+		}
+	}
+
+	// The game's carbon policy replaces the flat carbon price of the
+	// economics projection. Emissions are each facility's CarbonImpact
+	// (grid energy x grid intensity) at the utilisation actually run.
+	utilization := economy.GetConfig().Utilization
+	footprints := make([]data.Footprint, len(items))
+	operations := make([]economy.AnnualOperations, len(items))
+	subsidies := make([]float64, len(items))
+	supply := make([]data.Supply, len(items))
+	for i, item := range items {
+		loc := item.DatacenterLocation
+		cond := economy.DefaultConditions(&loc)
+		for _, def := range active {
+			if def.Region.inRegion(&loc) {
+				def.Effects.apply(&cond)
+				subsidies[i] += def.Effects.SubsidyPerMW
+			}
+		}
+		footprints[i], operations[i] = economy.OperateUnder(&loc, cond)
+		supply[i] = data.Supply{Latitude: loc.Latitude, Longitude: loc.Longitude, MW: footprints[i].ITLoadMW * utilization}
+	}
+
+	// Facilities only earn revenue for the capacity metro demand takes up,
+	// discounted for the latency to the users they serve. Unsold capacity
+	// still runs and costs opex.
+	demand := data.AllocateDemand(supply, float64(g.Quarter)/4)
+	report.DemandMW = demand.DemandMW
+	report.ServedMW = demand.ServedMW
+	report.UnmetMW = demand.UnmetMW
+
+	var gridMWh, tonnes, capacityMW float64
+	for i := range items {
+		fp, ops, subsidyPerMW := footprints[i], operations[i], subsidies[i]
+		report.Facilities++
+		if supply[i].MW > 0 {
+			report.Revenue += ops.Revenue / 4 * demand.EffectiveMW[i] / supply[i].MW
+		}
+		report.Subsidies += subsidyPerMW * fp.ITLoadMW
+		report.Opex += (ops.ElectricityCost + ops.WaterCost + ops.StaffingCost) / 4
+		gridMWh += fp.GridEnergyMWh * utilization / 4
+		report.EnergyMWh += fp.EnergyMWh * utilization / 4
+		report.WaterLiters += fp.WaterLiters * utilization / 4
+		tonnes += fp.CarbonTons * utilization / 4
+		capacityMW += fp.ITLoadMW
+	}
+	quarterCap := policy.CapFor(capacityMW, year-StartYear) / 4
+	report.Carbon = policy.Charge(gridMWh, tonnes, g.RECShare, quarterCap)
+	report.CarbonTons = report.Carbon.NetTonnes
+	report.Opex += report.Carbon.Total
+	report.Net = report.Revenue + report.Subsidies - report.Opex
+
+	balance, err := cart.SettleQuarter(g.Username, tick, report.Net, fmt.Sprintf("Q%d %d operations", quarter, year))
+	if err != nil {
+		return TickReport{}, err
+	}
+	report.Balance = balance
+
+	next.Quarter++
+	next.Revenue += report.Revenue
+	next.Opex += report.Opex
+	next.CarbonTons += report.CarbonTons
+	next.EnergyMWh += report.EnergyMWh
+	next.WaterLiters += report.WaterLiters
+	next.DemandMWh += report.DemandMW * data.HoursPerYear / 4
+	next.UnmetDemandMWh += report.UnmetMW * data.HoursPerYear / 4
+	next.Score += report.Net*scorePerDollar - report.CarbonTons*scorePerTonne -
+		report.UnmetMW*data.HoursPerYear/4*scorePerUnmetMWh
+	next.Bankrupt = balance < 0
+	report.Score = next.Score
+	next.History = append(next.History, report)
+	*g = *next
+	return report, nil
+}
+
+// expireEvents ends the events whose last tick has passed.
+func (g *Game) expireEvents(tick, year, quarter int) {
+	running := g.ActiveEvents[:0]
+	for _, e := range g.ActiveEvents {
+		if e.EndTick < tick {
+			g.EventLog = append(g.EventLog, EventLogEntry{Tick: tick, Year: year, Quarter: quarter, EventID: e.ID, Action: "ended"})
+			continue
+		}
+		running = append(running, e)
+	}
+	g.ActiveEvents = running
+}
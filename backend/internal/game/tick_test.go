@@ -0,0 +1,125 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// failingLedger is a store whose ledger writes fail while fail is set, so
+// settling a quarter fails.
+type failingLedger struct {
+	store.Store
+	fail bool
+}
+
+func (f *failingLedger) AppendLedger(userID string, entry []byte) error {
+	if f.fail {
+		return errors.New("disk full")
+	}
+	return f.Store.AppendLedger(userID, entry)
+}
+
+// startGame gives username a new game of a scenario with a drought in the
+// first quarter and a cart of one site, on a store whose ledger can be made to fail.
+func startGame(t *testing.T, username string) *failingLedger {
+	t.Helper()
+	st := &failingLedger{Store: store.NewFileStore(t.TempDir())}
+	store.SetDefault(st)
+	if !user.Exists(username) {
+		if err := user.AddUser(username, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scenarioMu.Lock()
+	scenarios["tick_test"] = Scenario{Name: "tick_test", Events: []EventDef{{
+		ID: "drought", Type: EventDrought, Trigger: Trigger{AtTick: 1}, Duration: 2,
+		Effects: Effects{WaterScarcityFactor: 2},
+	}}}
+	scenarioMu.Unlock()
+
+	if _, err := New(username, Options{Seed: 1, Scenario: "tick_test"}); err != nil {
+		t.Fatal(err)
+	}
+	// Carts outlive a test; start from an empty one.
+	if _, ok := cart.GetCart(username); ok {
+		if err := cart.ResetCart(username); err != nil {
+			t.Fatal(err)
+		}
+	}
+	site := data.DatacenterLocation{ID: 1, Name: "Ashburn", Tier: data.TierStandard}
+	if err := cart.AddToCart(username, site, 1000); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestFailedSettlementLeavesGame(t *testing.T) {
+	st := startGame(t, "tick_player")
+
+	st.fail = true
+	if _, err := Advance("tick_player", 1); err == nil {
+		t.Fatal("quarter advanced without settling")
+	}
+	g, _ := Get("tick_player")
+	if g.Quarter != 0 || len(g.ActiveEvents) != 0 || len(g.EventLog) != 0 || len(g.History) != 0 {
+		t.Errorf("failed quarter changed the game: quarter %d, %d active events, %d logged, %d reports",
+			g.Quarter, len(g.ActiveEvents), len(g.EventLog), len(g.History))
+	}
+
+	st.fail = false
+	reports, err := Advance("tick_player", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ = Get("tick_player")
+	if g.Quarter != 1 || len(reports) != 1 || len(reports[0].Events) != 1 {
+		t.Fatalf("quarter %d with %d reports after retrying", g.Quarter, len(reports))
+	}
+	if len(g.ActiveEvents) != 1 || len(g.EventLog) != 1 || g.EventLog[0].Action != "started" {
+		t.Errorf("retried quarter recorded events %+v, log %+v", g.ActiveEvents, g.EventLog)
+	}
+	if err := cart.VerifyBalance("tick_player"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailedSettlementLeavesUpgrades(t *testing.T) {
+	st := startGame(t, "upgrading_player")
+	// Ordered before the first quarter, the battery is ready in the second.
+	if _, _, err := cart.OrderUpgrade("upgrading_player", 0, data.UpgradeBattery, "", 1); err != nil {
+		t.Fatal(err)
+	}
+	if reports, err := Advance("upgrading_player", 1); err != nil || len(reports[0].Installed) != 0 {
+		t.Fatalf("first quarter: %v, %+v", err, reports)
+	}
+
+	st.fail = true
+	if _, err := Advance("upgrading_player", 1); err == nil {
+		t.Fatal("quarter advanced without settling")
+	}
+	item := cart.GetItems("upgrading_player")[0]
+	if len(item.Upgrades) != 0 || len(item.PendingUpgrades) != 1 {
+		t.Errorf("failed quarter installed the battery: upgrades %v, %d pending", item.Upgrades, len(item.PendingUpgrades))
+	}
+
+	st.fail = false
+	reports, err := Advance("upgrading_player", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports[0].Installed) != 1 {
+		t.Errorf("retried quarter installed %v", reports[0].Installed)
+	}
+	item = cart.GetItems("upgrading_player")[0]
+	if len(item.Upgrades) != 1 || item.Upgrades[0] != data.UpgradeBattery || len(item.PendingUpgrades) != 0 {
+		t.Errorf("after the retry: upgrades %v, %d pending", item.Upgrades, len(item.PendingUpgrades))
+	}
+	if err := cart.VerifyBalance("upgrading_player"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/lockout"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/notify"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Failed password checks are counted per account and per client IP. Reset
+// requests are counted per account too, so nobody can flood a user with
+// reset messages.
+var (
+	accountFailures = lockout.New(lockout.AccountPolicy)
+	ipFailures      = lockout.New(lockout.IPPolicy)
+	resetRequests   = lockout.New(lockout.Policy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+)
+
+// ChangePasswordRequest is the expected JSON payload for POST /account/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ResetRequest is the expected JSON payload for
+// POST /account/password/reset/request.
+type ResetRequest struct {
+	Username string `json:"username"`
+}
+
+// ResetPasswordRequest is the expected JSON payload for
+// POST /account/password/reset.
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// clientIP returns the address a request came from. X-Forwarded-For is not
+// trusted; behind a proxy every client shares the proxy's address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// lockoutKey is the key an account's failures are counted under. Unknown
+// usernames are counted too, so a lock says nothing about whether the
+// account exists.
+func lockoutKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// checkLockout writes 429 with a Retry-After header and returns false if
+// the client IP or the account is locked.
+func checkLockout(w http.ResponseWriter, r *http.Request, username string) bool {
+	wait, locked := ipFailures.Locked(clientIP(r))
+	if !locked && username != "" {
+		wait, locked = accountFailures.Locked(lockoutKey(username))
+	}
+	if !locked {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed attempts; try again later", http.StatusTooManyRequests)
+	return false
+}
+
+// recordFailure counts a failed password check against the client IP and
+// the account.
+func recordFailure(r *http.Request, username string) {
+	ip := clientIP(r)
+	if d := ipFailures.Fail(ip); d > 0 {
+		log.Printf("Locked logins from %s for %s", ip, d)
+	}
+	if username != "" {
+		if d := accountFailures.Fail(lockoutKey(username)); d > 0 {
+			log.Printf("Locked logins to %q for %s", username, d)
+		}
+	}
+}
+
+// ChangePasswordHandler handles POST /account/password. The current password
+// is required, unless the user has none yet because they log in through an
+// identity provider. Every other session of the user is ended and their API
+// tokens are revoked.
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Cannot parse request body", http.StatusBadRequest)
+		return
+	}
+	if !checkLockout(w, r, account.Username) {
+		return
+	}
+	if user.HasPassword(account.Username) && !user.CheckPassword(account.Username, req.CurrentPassword) {
+		recordFailure(r, account.Username)
+		http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+	if err := user.SetPassword(account.Username, req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keep := ""
+	if cookie, err := r.Cookie("session_id"); err == nil {
+		keep = cookie.Value
+	}
+	cleared, err := session.ClearOtherSessions(account.Username, keep)
+	if err != nil {
+		fmt.Printf("Error clearing sessions: %v\n", err)
+	}
+	revoked, err := apitoken.RevokeAll(account.Username)
+	if err != nil {
+		fmt.Printf("Error revoking API tokens: %v\n", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"message":  "Password changed",
+		"sessions": cleared,
+		"tokens":   revoked,
+	})
+}
+
+// RequestPasswordResetHandler handles POST /account/password/reset/request.
+// A one-time reset token is sent through the notifier. The response is the
+// same whether or not the user exists, so it can't be used to find accounts.
+func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Cannot parse request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := user.Lookup(req.Username); ok {
+		if _, limited := resetRequests.Locked(req.Username); !limited {
+			resetRequests.Fail(req.Username)
+			sendResetToken(req.Username)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "If the account exists, a reset token has been sent",
+	})
+}
+
+// sendResetToken creates a reset token for a user and sends it to them.
+// Failures are logged, not reported to the client.
+func sendResetToken(username string) {
+	token, err := user.CreateResetToken(username)
+	if err != nil {
+		log.Printf("Password reset for %s: %v", username, err)
+		return
+	}
+	body := fmt.Sprintf("Someone asked to reset the password for %s. If it was you, send this token "+
+		"with your new password to POST /account/password/reset within %s:\n\n%s\n\n"+
+		"If it wasn't you, ignore this message; your password has not changed.",
+		username, user.ResetTokenTTL, token)
+	if err := notify.Send(username, "Password reset", body); err != nil {
+		log.Printf("Error sending password reset to %s: %v", username, err)
+	}
+}
+
+// ResetPasswordHandler handles POST /account/password/reset. A valid token
+// sets the new password, ends every session of the user, revokes their API
+// tokens and clears their lockout; the token can't be used again.
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Cannot parse request body", http.StatusBadRequest)
+		return
+	}
+	if !checkLockout(w, r, "") {
+		return
+	}
+
+	var username string
+	tokenValid := false
+	err := user.RedeemResetToken(req.Token, func(name string) error {
+		username, tokenValid = name, true
+		return user.SetPassword(name, req.NewPassword)
+	})
+	if err != nil {
+		if !tokenValid {
+			recordFailure(r, "")
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := session.ClearUserSessions(username); err != nil {
+		fmt.Printf("Error clearing sessions: %v\n", err)
+	}
+	if _, err := apitoken.RevokeAll(username); err != nil {
+		fmt.Printf("Error revoking API tokens: %v\n", err)
+	}
+	accountFailures.Reset(lockoutKey(username))
+	resetRequests.Reset(username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Password reset; please log in",
+	})
+}
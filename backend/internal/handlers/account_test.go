@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	useTempStore(t)
+	// A user of this run: setting a password writes to the store, which
+	// doesn't have the users of earlier runs.
+	username := user.AvailableUsername("changing_player")
+	current := loggedIn(t, username)
+	other := loggedIn(t, username)
+	if err := user.SetPassword(username, "first-secret-1"); err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	for _, scope := range []string{apitoken.ScopeRead, apitoken.ScopeReadWrite} {
+		token, _, err := apitoken.Create(username, scope, scope, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	change := RequireSession(ChangePasswordHandler)
+
+	w := serveJSON(change, http.MethodPost, "/account/password", current,
+		`{"current_password": "wrong-secret-1", "new_password": "second-secret-2"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("wrong current password: got %d: %s", w.Code, w.Body)
+	}
+	if _, _, ok := apitoken.Authenticate(tokens[0]); !ok {
+		t.Fatal("a refused change revoked the tokens")
+	}
+
+	w = serveJSON(change, http.MethodPost, "/account/password", current,
+		`{"current_password": "first-secret-1", "new_password": "second-secret-2"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("changing the password: got %d: %s", w.Code, w.Body)
+	}
+	for _, token := range tokens {
+		if _, _, ok := apitoken.Authenticate(token); ok {
+			t.Error("an API token still works after the password changed")
+		}
+	}
+	if _, ok := session.GetUserForSession(other.Value); ok {
+		t.Error("another session still works after the password changed")
+	}
+	if _, ok := session.GetUserForSession(current.Value); !ok {
+		t.Error("the session that changed the password was ended")
+	}
+	if !user.CheckPassword(username, "second-secret-2") {
+		t.Error("the new password doesn't work")
+	}
+}
+
+func TestLoginUnknownUser(t *testing.T) {
+	useTempStore(t)
+	w := serveJSON(LoginHandler, http.MethodPost, "/login", nil,
+		`{"username": "nobody_by_this_name", "password": "any-secret-1"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown user: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// maxDatasetSize bounds an uploaded dataset.
+const maxDatasetSize = 32 << 20
+
+// SetRoleRequest is the expected JSON payload for POST /admin/users/role.
+type SetRoleRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"` // player, instructor or admin
+}
+
+// AdminUserRequest is the expected JSON payload for POST /admin/users/logout.
+type AdminUserRequest struct {
+	Username string `json:"username"`
+}
+
+// ActiveProfileRequest is the expected JSON payload for
+// POST /admin/scoring-profiles/active.
+type ActiveProfileRequest struct {
+	Name string `json:"name"`
+}
+
+// ListUsersHandler handles GET /admin/users
+func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"users":  user.List(),
+	})
+}
+
+// SetRoleHandler handles POST /admin/users/role. Admins can't change their
+// own role, so the last admin can't lock everyone out by accident.
+func SetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if account, _ := CurrentUser(r); account.Username == req.Username {
+		http.Error(w, "You can't change your own role", http.StatusForbidden)
+		return
+	}
+	if _, ok := user.Lookup(req.Username); !ok {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.ValidRole(req.Role) {
+		http.Error(w, fmt.Sprintf("Unknown role %q", req.Role), http.StatusBadRequest)
+		return
+	}
+	if err := user.SetRole(req.Username, req.Role); err != nil {
+		http.Error(w, fmt.Sprintf("Error setting role: %v", err), http.StatusInternalServerError)
+		return
+	}
+	account, _ := user.Lookup(req.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"user":   account,
+	})
+}
+
+// LogoutUserHandler handles POST /admin/users/logout, ending every session
+// of the named user and revoking their API tokens.
+func LogoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req AdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if _, ok := user.Lookup(req.Username); !ok {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cleared, err := session.ClearUserSessions(req.Username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error ending sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	revoked, err := apitoken.RevokeAll(req.Username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error revoking API tokens: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"sessions": cleared,
+		"tokens":   revoked,
+	})
+}
+
+// ListDatasetsHandler handles GET /admin/datasets
+func ListDatasetsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"datasets": data.ListDatasets(),
+	})
+}
+
+// ReplaceDatasetHandler handles PUT /admin/datasets?name=possible-locations
+// with the new CSV file as the request body. A file that doesn't parse is
+// rejected and the old one kept.
+func ReplaceDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	content, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxDatasetSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading dataset: %v", err), http.StatusBadRequest)
+		return
+	}
+	ds, err := data.ReplaceDataset(r.URL.Query().Get("name"), content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"dataset": ds,
+	})
+}
+
+// SaveScoringProfileHandler handles PUT /admin/scoring-profiles, adding a
+// profile or replacing the one with the same name.
+func SaveScoringProfileHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var p data.ScoringProfile
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := p.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := data.SaveScoringProfile(p); err != nil {
+		http.Error(w, fmt.Sprintf("Error saving scoring profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	saved, _ := data.GetScoringProfile(p.Name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"profile": saved,
+	})
+}
+
+// DeleteScoringProfileHandler handles DELETE /admin/scoring-profiles?name=community
+func DeleteScoringProfileHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if _, ok := data.GetScoringProfile(name); !ok || name == "" {
+		http.Error(w, "Scoring profile not found", http.StatusNotFound)
+		return
+	}
+	if err := data.DeleteScoringProfile(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Scoring profile %s deleted", name),
+	})
+}
+
+// SetActiveProfileHandler handles POST /admin/scoring-profiles/active and
+// switches the profile used when a request doesn't name one.
+func SetActiveProfileHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ActiveProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := data.SetActiveScoringProfile(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"active": data.ActiveScoringProfile().Name,
+	})
+}
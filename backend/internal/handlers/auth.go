@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+type contextKey int
+
+const accountKey contextKey = iota
+
+// RequireAuth resolves the user from an "Authorization: Bearer" API token or
+// the session_id cookie and puts their account in the request context for
+// CurrentUser. Requests without valid credentials get 401, and read-only
+// tokens get 403 on anything but GET and HEAD. CORS preflight requests carry
+// no credentials, so they are answered here without any.
+func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return requireAuth(next, true, false)
+}
+
+// RequireAuthReadOnly is RequireAuth for routes that never change data
+// whatever their method, such as POST /api/simulation/compare, so read-only
+// tokens may use them too.
+func RequireAuthReadOnly(next http.HandlerFunc) http.HandlerFunc {
+	return requireAuth(next, true, true)
+}
+
+// RequireSession is RequireAuth without API tokens, for managing tokens and
+// passwords: a leaked token can't be used to mint more tokens or to lock its
+// owner out.
+func RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return requireAuth(next, false, false)
+}
+
+func requireAuth(next http.HandlerFunc, allowTokens, readOnly bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			addCORSHeaders(w)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var username string
+		if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := bearerToken(header)
+			if !ok {
+				addCORSHeaders(w)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+				http.Error(w, "Malformed Authorization header; send Bearer <token>", http.StatusUnauthorized)
+				return
+			}
+			if !allowTokens {
+				addCORSHeaders(w)
+				http.Error(w, "API tokens can't be used here; log in instead", http.StatusForbidden)
+				return
+			}
+			name, scope, ok := apitoken.Authenticate(token)
+			if !ok {
+				addCORSHeaders(w)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+				return
+			}
+			if scope == apitoken.ScopeRead && !readOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				addCORSHeaders(w)
+				http.Error(w, "This API token is read-only", http.StatusForbidden)
+				return
+			}
+			username = name
+		} else {
+			cookie, err := r.Cookie("session_id")
+			if err != nil {
+				addCORSHeaders(w)
+				http.Error(w, "Not logged in", http.StatusUnauthorized)
+				return
+			}
+			name, ok := session.GetUserForSession(cookie.Value)
+			if !ok {
+				addCORSHeaders(w)
+				http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+				return
+			}
+			username = name
+		}
+		account, ok := user.Lookup(username)
+		if !ok {
+			addCORSHeaders(w)
+			http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), accountKey, account)))
+	}
+}
+
+// bearerToken returns the token of a "Bearer <token>" Authorization header.
+// The scheme is case-insensitive, as in RFC 7235.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// RequireRole is RequireAuth for routes that need a role: users without the
+// role, or a more privileged one, get 403.
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		account, _ := CurrentUser(r)
+		if !account.HasRole(role) {
+			addCORSHeaders(w)
+			http.Error(w, fmt.Sprintf("Requires the %s role", role), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// CurrentUser returns the account RequireAuth put in the request context.
+func CurrentUser(r *http.Request) (user.Account, bool) {
+	account, ok := r.Context().Value(accountKey).(user.Account)
+	return account, ok
+}
+
+// actingUser returns the user a request acts on: the logged-in user, or the
+// user named in the request if the logged-in user is an admin. Naming
+// yourself, or nobody, is always allowed. On failure it has already written
+// the error response.
+func actingUser(w http.ResponseWriter, r *http.Request, requested string) (string, bool) {
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return "", false
+	}
+	if requested == "" || requested == account.Username {
+		return account.Username, true
+	}
+	if !account.IsAdmin() {
+		http.Error(w, "You may only act on your own account", http.StatusForbidden)
+		return "", false
+	}
+	if _, ok := user.Lookup(requested); !ok {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return "", false
+	}
+	return requested, true
+}
@@ -3,18 +3,24 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
 )
 
-// AddToCartRequest is the expected JSON payload for adding an item.
+// AddToCartRequest is the expected JSON payload for adding an item. The site is
+// identified by site_id, or by the item's coordinates when no ID is given; the
+// price is always computed server-side.
 type AddToCartRequest struct {
 	Username string                  `json:"username"`
+	SiteID   int                     `json:"site_id"`
+	Tier     string                  `json:"tier"`
 	Item     data.DatacenterLocation `json:"item"`
-	Cost     float64                 `json:"cost"`
 }
 
 func GetCarbonFootprintHandler(w http.ResponseWriter, r *http.Request) {
@@ -28,9 +34,8 @@ func GetCarbonFootprintHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "Missing username query parameter", http.StatusBadRequest)
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
 		return
 	}
 
@@ -63,22 +68,84 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	if req.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
 		return
 	}
-	if err := cart.AddToCart(req.Username, req.Item, req.Cost); err != nil {
+	req.Username = username
+
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
+	if err != nil {
+		http.Error(w, "Error reading datacenter locations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	site, ok := findCatalogSite(locations, req.SiteID, req.Item.Latitude, req.Item.Longitude)
+	if !ok {
+		http.Error(w, "Unknown site", http.StatusBadRequest)
+		return
+	}
+	if req.Tier == "" {
+		req.Tier = data.TierStandard
+	}
+	quote, err := economy.QuoteSite(&site, req.Tier)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error pricing site: %v", err), http.StatusBadRequest)
+		return
+	}
+	site.Tier = quote.Tier
+
+	if err := cart.AddToCart(req.Username, site, quote.Total); err != nil {
 		http.Error(w, fmt.Sprintf("Error adding to cart: %v", err), http.StatusBadRequest)
 		return
 	}
+	moneyLeft := 0.0
+	if c, ok := cart.GetCart(req.Username); ok {
+		moneyLeft = c.MoneyLeft
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "success",
-		"message": "Item added to cart",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    "Item added to cart",
+		"price":      quote,
+		"money_left": moneyLeft,
 	})
 }
 
-// GetCartHandler handles GET /cart?username=...
+// findCatalogSite looks a site up by ID, falling back to its coordinates.
+func findCatalogSite(locations []data.DatacenterLocation, id int, lat, lng float64) (data.DatacenterLocation, bool) {
+	if id > 0 {
+		return data.FindLocationByID(locations, id)
+	}
+	const epsilon = 0.0001
+	for _, loc := range locations {
+		if math.Abs(loc.Latitude-lat) < epsilon && math.Abs(loc.Longitude-lng) < epsilon {
+			return loc, true
+		}
+	}
+	return data.DatacenterLocation{}, false
+}
+
+// GetEconomyHandler handles GET /api/economy and publishes the game's
+// economic rules so the frontend never has to hard-code them.
+func GetEconomyHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"starting_funds": economy.StartingFunds(),
+		"site_acres":     data.SiteAcres,
+		"tiers":          data.Tiers,
+	})
+}
+
+// GetCartHandler handles GET /cart
 func GetCartHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
 	fmt.Println("GetCartHandler called")
@@ -90,9 +157,8 @@ func GetCartHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
 		return
 	}
 	c, exists := cart.GetCart(username)
@@ -104,7 +170,10 @@ func GetCartHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(c)
 }
 
-// DeleteCartItemHandler handles DELETE /cart/item?username=alice&index=0
+// DeleteCartItemHandler handles DELETE /cart/item?index=0&action=sell
+//
+// action is "sell" (the default) or "demolish"; either refunds part of the
+// item's purchase price.
 func DeleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
 	if r.Method == http.MethodOptions {
@@ -115,10 +184,13 @@ func DeleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	username := r.URL.Query().Get("username")
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
 	indexStr := r.URL.Query().Get("index")
-	if username == "" || indexStr == "" {
-		http.Error(w, "username and index parameters are required", http.StatusBadRequest)
+	if indexStr == "" {
+		http.Error(w, "index parameter is required", http.StatusBadRequest)
 		return
 	}
 	index, err := strconv.Atoi(indexStr)
@@ -127,19 +199,38 @@ func DeleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cart.RemoveItemFromCart(username, index); err != nil {
+	var refund float64
+	switch action := r.URL.Query().Get("action"); action {
+	case "", "sell":
+		refund, err = cart.SellItem(username, index)
+	case "demolish":
+		refund, err = cart.DemolishItem(username, index)
+	default:
+		http.Error(w, "Invalid action (expected sell or demolish)", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Error deleting cart item: %v", err), http.StatusBadRequest)
 		return
 	}
+	moneyLeft := 0.0
+	if c, ok := cart.GetCart(username); ok {
+		moneyLeft = c.MoneyLeft
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "success",
-		"message": "Cart item deleted",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    "Cart item deleted",
+		"refund":     refund,
+		"money_left": moneyLeft,
 	})
 }
 
-// DeleteCartHandler handles DELETE /cart?username=alice
+// DeleteCartHandler handles DELETE /cart by resetting the cart
+// to the starting funds. A player with an unfinished game can't reset, or
+// they could wipe out their losses before the game is scored; starting a
+// new game resets the cart instead.
 func DeleteCartHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
 	if r.Method == http.MethodOptions {
@@ -150,18 +241,50 @@ func DeleteCartHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "username parameter is required", http.StatusBadRequest)
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
 		return
 	}
-	if err := cart.DeleteCart(username); err != nil {
+	if g, ok := game.Get(username); ok && !g.Finished {
+		http.Error(w, "Finish your game or start a new one to reset the cart", http.StatusConflict)
+		return
+	}
+	if err := cart.ResetCart(username); err != nil {
 		http.Error(w, fmt.Sprintf("Error deleting cart: %v", err), http.StatusBadRequest)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "success",
-		"message": "Cart deleted",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    "Cart reset",
+		"money_left": economy.StartingFunds(),
 	})
 }
+
+// GetTransactionsHandler handles GET /cart/transactions and
+// returns the user's transaction history, oldest first.
+func GetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	entries, err := cart.GetLedger(username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading transactions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []cart.LedgerEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
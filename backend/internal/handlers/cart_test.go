@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+func TestResetCartDuringGame(t *testing.T) {
+	useTempStore(t)
+	cookie := loggedIn(t, "reset_player")
+	reset := RequireAuth(DeleteCartHandler)
+
+	if _, err := startGame("reset_player", game.Options{Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	site := data.DatacenterLocation{ID: 1, Name: "Ashburn", Tier: data.TierStandard}
+	if err := cart.AddToCart("reset_player", site, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.Advance("reset_player", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := serve(reset, http.MethodDelete, "/cart", cookie); w.Code != http.StatusConflict {
+		t.Fatalf("reset during a game: got %d, want %d", w.Code, http.StatusConflict)
+	}
+	if items := cart.GetItems("reset_player"); len(items) != 1 {
+		t.Errorf("refused reset left %d items, want 1", len(items))
+	}
+
+	if _, err := game.Finish("reset_player", 0); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(reset, http.MethodDelete, "/cart", cookie); w.Code != http.StatusOK {
+		t.Fatalf("reset after the game: got %d: %s", w.Code, w.Body)
+	}
+	c, _ := cart.GetCart("reset_player")
+	if len(c.Items) != 0 || c.MoneyLeft != economy.StartingFunds() {
+		t.Errorf("reset left %d items and %f", len(c.Items), c.MoneyLeft)
+	}
+}
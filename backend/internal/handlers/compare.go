@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/scenario"
+)
+
+// Portfolio sources accepted by the compare endpoint.
+const (
+	portfolioSourceCart     = "cart"
+	portfolioSourceScenario = "scenario"
+	portfolioSourceSites    = "sites"
+)
+
+// PortfolioSpec describes one portfolio to simulate.
+type PortfolioSpec struct {
+	Label    string                   `json:"label"`
+	Source   string                   `json:"source"`             // "cart", "scenario" or "sites"
+	Scenario string                   `json:"scenario,omitempty"` // name of a saved scenario
+	Sites    []scenario.SiteSelection `json:"sites,omitempty"`    // ad-hoc list of site IDs with tiers
+}
+
+// CompareRequest is the expected JSON payload for POST /api/simulation/compare.
+type CompareRequest struct {
+	Username   string          `json:"username"`
+	Portfolios []PortfolioSpec `json:"portfolios"`
+}
+
+// PortfolioProjection is the simulation result of a single portfolio.
+type PortfolioProjection struct {
+	Label                  string              `json:"label"`
+	Source                 string              `json:"source"`
+	SiteCount              int                 `json:"site_count"`
+	Projections            []ClimateProjection `json:"projections"`
+	TotalTimeToEnd         int                 `json:"total_time_to_end"`
+	TimeDatacentersRemoved int                 `json:"time_datacenters_removed"`
+}
+
+// PortfolioDelta compares a portfolio against the reference (first) portfolio.
+// Slices are aligned with CompareResponse.Years.
+type PortfolioDelta struct {
+	Label               string    `json:"label"`
+	Reference           string    `json:"reference"`
+	TemperatureDelta    []float64 `json:"temperature_delta"`   // °C, portfolio minus reference
+	SurvivabilityDelta  []int     `json:"survivability_delta"` // points, portfolio minus reference
+	TotalTimeToEndDelta int       `json:"total_time_to_end_delta"`
+}
+
+// CompareResponse is the overall response from the compare endpoint.
+type CompareResponse struct {
+	Username   string                `json:"username"`
+	Years      []int                 `json:"years"`
+	Baseline   []ClimateProjection   `json:"baseline"` // without any data centers
+	Portfolios []PortfolioProjection `json:"portfolios"`
+	Deltas     []PortfolioDelta      `json:"deltas"`
+}
+
+// CompareSimulationHandler handles POST /api/simulation/compare
+func CompareSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	if len(req.Portfolios) < 2 {
+		http.Error(w, "At least two portfolios are required", http.StatusBadRequest)
+		return
+	}
+
+	resp := CompareResponse{Username: req.Username}
+	var runs []simulationRun
+	for i, spec := range req.Portfolios {
+		if spec.Label == "" {
+			spec.Label = fmt.Sprintf("portfolio-%d", i+1)
+		}
+		items, err := resolvePortfolio(req.Username, spec)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Portfolio %q: %v", spec.Label, err), http.StatusBadRequest)
+			return
+		}
+
+		run := runClimateSimulation(items)
+		runs = append(runs, run)
+		resp.Portfolios = append(resp.Portfolios, PortfolioProjection{
+			Label:                  spec.Label,
+			Source:                 spec.Source,
+			SiteCount:              len(items),
+			Projections:            run.WithDataCenters,
+			TotalTimeToEnd:         run.TotalTimeToEnd,
+			TimeDatacentersRemoved: run.TotalTimeNoDC - run.TotalTimeToEnd,
+		})
+	}
+
+	// Every run shares the same baseline and years, so take them from the first.
+	resp.Baseline = runs[0].WithoutDataCenters
+	for _, p := range resp.Baseline {
+		resp.Years = append(resp.Years, p.Year)
+	}
+
+	ref := resp.Portfolios[0]
+	for i := 1; i < len(resp.Portfolios); i++ {
+		other := resp.Portfolios[i]
+		delta := PortfolioDelta{
+			Label:               other.Label,
+			Reference:           ref.Label,
+			TotalTimeToEndDelta: other.TotalTimeToEnd - ref.TotalTimeToEnd,
+		}
+		for y := range ref.Projections {
+			delta.TemperatureDelta = append(delta.TemperatureDelta,
+				other.Projections[y].TotalTemperature-ref.Projections[y].TotalTemperature)
+			delta.SurvivabilityDelta = append(delta.SurvivabilityDelta,
+				other.Projections[y].Survivability-ref.Projections[y].Survivability)
+		}
+		resp.Deltas = append(resp.Deltas, delta)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// resolvePortfolio turns a portfolio spec into the list of data centers to simulate.
+func resolvePortfolio(username string, spec PortfolioSpec) ([]data.DatacenterLocation, error) {
+	switch spec.Source {
+	case portfolioSourceCart:
+		c, ok := cart.GetCart(username)
+		if !ok {
+			return []data.DatacenterLocation{}, nil
+		}
+		return c.Locations(), nil
+	case portfolioSourceScenario:
+		s, found, err := scenario.Get(username, spec.Scenario)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("scenario %q not found", spec.Scenario)
+		}
+		return resolveSites(s.Sites)
+	case portfolioSourceSites:
+		return resolveSites(spec.Sites)
+	default:
+		return nil, fmt.Errorf("unknown source %q (expected cart, scenario or sites)", spec.Source)
+	}
+}
+
+// resolveSites looks up each selected site in the possible-locations catalog
+// and tags it with the chosen tier (standard if none was given).
+func resolveSites(selections []scenario.SiteSelection) ([]data.DatacenterLocation, error) {
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]data.DatacenterLocation, 0, len(selections))
+	for _, sel := range selections {
+		loc, ok := data.FindLocationByID(locations, sel.SiteID)
+		if !ok {
+			return nil, fmt.Errorf("unknown site ID %d", sel.SiteID)
+		}
+		tierID := sel.Tier
+		if tierID == "" {
+			tierID = data.TierStandard
+		}
+		tier, ok := data.GetTier(tierID)
+		if !ok {
+			return nil, fmt.Errorf("unknown tier %q for site %d", sel.Tier, sel.SiteID)
+		}
+		loc.Tier = tier.ID
+		items = append(items, loc)
+	}
+	return items, nil
+}
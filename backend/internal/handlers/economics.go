@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+// EconomicsResponse is the overall response from the economics endpoint.
+type EconomicsResponse struct {
+	Username string `json:"username"`
+	economy.PortfolioEconomics
+}
+
+// GetEconomicsHandler handles GET /api/economics
+//
+// It projects revenue, opex, cash flow, NPV and payback for every facility in
+// the user's cart and for the portfolio as a whole.
+func GetEconomicsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	var facilities []economy.FacilityEconomics
+	if userCart, ok := cart.GetCart(username); ok {
+		for _, item := range userCart.Items {
+			facilities = append(facilities, economy.EvaluateFacility(&item.DatacenterLocation, itemInvestment(item)))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EconomicsResponse{
+		Username:           username,
+		PortfolioEconomics: economy.EvaluatePortfolio(facilities),
+	})
+}
+
+// DemandHandler handles GET /api/demand?year=2027 and returns the compute
+// demand of each metro area in that year along with the latency model.
+func DemandHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	year := game.StartYear
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil || y < game.StartYear {
+			http.Error(w, fmt.Sprintf("year must be %d or later", game.StartYear), http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+	var total float64
+	metros := make([]data.MetroService, len(data.MetroAreas))
+	for i, m := range data.MetroAreas {
+		metros[i] = data.MetroService{Name: m.Name, DemandMW: m.DemandAfter(float64(year - game.StartYear))}
+		total += metros[i].DemandMW
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"year":                    year,
+		"metros":                  data.MetroAreas,
+		"demand":                  metros,
+		"total_demand_mw":         total,
+		"free_latency_ms":         data.FreeLatencyMs,
+		"max_latency_ms":          data.MaxLatencyMs,
+		"latency_discount_per_ms": data.LatencyDiscountPerMs,
+	})
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/classroom"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+// NewGameRequest is the expected JSON payload for POST /game/new. The carbon
+// policy is a built-in policy name, or a custom policy in custom_policy; the
+// scenario names the event script (see /api/game-scenarios).
+type NewGameRequest struct {
+	Username     string                `json:"username"`
+	Seed         uint64                `json:"seed"` // 0 picks a random seed
+	AutoAdvance  bool                  `json:"auto_advance"`
+	CarbonPolicy string                `json:"carbon_policy"`
+	CustomPolicy *economy.CarbonPolicy `json:"custom_policy,omitempty"`
+	Scenario     string                `json:"scenario"`
+	Classroom    string                `json:"classroom"` // optional class the player is on the roster of
+}
+
+// RECRequest is the expected JSON payload for POST /game/recs.
+type RECRequest struct {
+	Username string  `json:"username"`
+	Share    float64 `json:"share"` // 0-1 share of grid energy to cover with RECs
+}
+
+// AdvanceGameRequest is the expected JSON payload for POST /game/advance.
+type AdvanceGameRequest struct {
+	Username string `json:"username"`
+	Ticks    int    `json:"ticks"` // quarters to play, 1 if omitted
+}
+
+// FinishGameRequest is the expected JSON payload for POST /game/finish.
+type FinishGameRequest struct {
+	Username string `json:"username"`
+}
+
+// AdvanceGameResponse is the response from POST /game/advance.
+type AdvanceGameResponse struct {
+	Game  *game.Game        `json:"game"`
+	Ticks []game.TickReport `json:"ticks"`
+}
+
+// GetGameHandler handles GET /game
+func GetGameHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	g, ok := game.Get(username)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// NewGameHandler handles POST /game/new. Starting a game resets the player's
+// cart to the starting funds.
+func NewGameHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req NewGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	policy, err := gamePolicy(req.CarbonPolicy, req.CustomPolicy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := game.GetScenario(req.Scenario); !ok {
+		http.Error(w, fmt.Sprintf("Unknown scenario %q", req.Scenario), http.StatusBadRequest)
+		return
+	}
+	// Classroom games share a leaderboard, so players can only join a class
+	// they are on the roster of.
+	req.Classroom = strings.TrimSpace(req.Classroom)
+	if req.Classroom != "" {
+		c, ok := classroom.Get(req.Classroom)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown classroom %q", req.Classroom), http.StatusBadRequest)
+			return
+		}
+		if !containsString(c.Students, username) {
+			http.Error(w, fmt.Sprintf("%s is not in classroom %s", username, c.Name), http.StatusForbidden)
+			return
+		}
+	}
+
+	g, err := startGame(req.Username, game.Options{
+		Seed:         req.Seed,
+		AutoAdvance:  req.AutoAdvance,
+		CarbonPolicy: policy,
+		Scenario:     req.Scenario,
+		Classroom:    req.Classroom,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// gamePolicy resolves the carbon policy a new game asks for: a custom policy
+// if one is given, otherwise the named built-in policy.
+func gamePolicy(name string, custom *economy.CarbonPolicy) (economy.CarbonPolicy, error) {
+	policy, ok := economy.GetCarbonPolicy(name)
+	if custom != nil {
+		policy, ok = *custom, true
+		if policy.Name == "" {
+			policy.Name = "custom"
+		}
+	}
+	if !ok {
+		return economy.CarbonPolicy{}, fmt.Errorf("Unknown carbon policy %q", name)
+	}
+	if err := policy.Validate(); err != nil {
+		return economy.CarbonPolicy{}, err
+	}
+	return policy, nil
+}
+
+// startGame resets a player's cart to the starting funds and starts a new
+// game, replacing any game in progress.
+func startGame(username string, opts game.Options) (*game.Game, error) {
+	if _, ok := cart.GetCart(username); ok {
+		if err := cart.ResetCart(username); err != nil {
+			return nil, fmt.Errorf("Error resetting cart: %v", err)
+		}
+	}
+	g, err := game.New(username, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating game: %v", err)
+	}
+	return g, nil
+}
+
+// AdvanceGameHandler handles POST /game/advance
+func AdvanceGameHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req AdvanceGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	if req.Ticks == 0 {
+		req.Ticks = 1
+	}
+	ticks, err := game.Advance(req.Username, req.Ticks)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error advancing game: %v", err), http.StatusBadRequest)
+		return
+	}
+	if ticks == nil {
+		ticks = []game.TickReport{}
+	}
+	g, _ := game.Get(req.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdvanceGameResponse{Game: g, Ticks: ticks})
+}
+
+// SetRECShareHandler handles POST /game/recs
+func SetRECShareHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req RECRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	g, err := game.SetRECShare(req.Username, req.Share)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error setting REC share: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// CarbonPoliciesHandler handles GET /api/carbon-policies
+func CarbonPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"default":  economy.DefaultCarbonPolicy,
+		"policies": economy.ListCarbonPolicies(),
+	})
+}
+
+// GameScenariosHandler handles GET /api/game-scenarios
+func GameScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game.ListScenarios())
+}
+
+// FinishGameHandler handles POST /game/finish. It runs the climate simulation
+// for the final portfolio, scores the game and unlocks achievements.
+func FinishGameHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req FinishGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	var locations []data.DatacenterLocation
+	if c, ok := cart.GetCart(req.Username); ok {
+		locations = c.Locations()
+	}
+	run := runClimateSimulation(locations)
+	result, err := game.Finish(req.Username, run.TotalTimeNoDC-run.TotalTimeToEnd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error finishing game: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetResultsHandler handles GET /game/results and returns the
+// player's finished games and unlocked achievements.
+func GetResultsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	rec, ok := game.GetRecord(username)
+	if !ok {
+		rec = &game.Record{
+			Username:     username,
+			Results:      []game.Result{},
+			Achievements: []game.UnlockedAchievement{},
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// AchievementsHandler handles GET /api/achievements and lists the objectives,
+// achievements and ranks games are scored against.
+func AchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	set := game.GetAchievements()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"objectives":   set.Objectives,
+		"achievements": set.Achievements,
+		"ranks":        game.Ranks,
+	})
+}
@@ -9,9 +9,9 @@ import (
 	"strconv"
 
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterHandler handles POST /register
@@ -38,6 +38,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Username and password required", http.StatusBadRequest)
 		return
 	}
+	if err := user.ValidateUsername(creds.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := user.ValidatePassword(creds.Password, creds.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Check if user already exists
 	if user.Exists(creds.Username) {
@@ -46,14 +54,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hash the password
-	hashed, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+	hashed, err := user.HashPassword(creds.Password)
 	if err != nil {
 		http.Error(w, "Error hashing password", http.StatusInternalServerError)
 		return
 	}
 
 	// Write to users.txt and update in-memory map
-	if err := user.AddUser(creds.Username, string(hashed)); err != nil {
+	if err := user.AddUser(creds.Username, hashed); err != nil {
 		http.Error(w, "Error writing user file", http.StatusInternalServerError)
 		return
 	}
@@ -84,31 +92,25 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPass, ok := user.GetHashedPassword(creds.Username)
-	if !ok {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	// Locked clients are turned away before the password is checked
+	if !checkLockout(w, r, creds.Username) {
 		return
 	}
 
 	// Compare provided password with stored hash
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(creds.Password)); err != nil {
+	if !user.CheckPassword(creds.Username, creds.Password) {
+		recordFailure(r, creds.Username)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	accountFailures.Reset(lockoutKey(creds.Username))
 
-	// Create a session ID
-	sessionID := session.GenerateSessionID()
-	// Store the session -> user mapping
-	session.SetUserForSession(sessionID, creds.Username)
-
-	// Set it in a cookie
-	cookie := &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionID,
-		HttpOnly: true,
-		Path:     "/",
+	sessionID, err := startSession(w, creds.Username)
+	if err != nil {
+		fmt.Printf("Error creating session: %v\n", err)
+		http.Error(w, "Error creating session", http.StatusInternalServerError)
+		return
 	}
-	http.SetCookie(w, cookie)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -118,6 +120,24 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// startSession creates a session for a user who has just logged in and sets
+// it in a cookie that lasts no longer than the session can.
+func startSession(w http.ResponseWriter, username string) (string, error) {
+	sessionID, err := session.Create(username)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   int(session.AbsoluteTimeout().Seconds()),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sessionID, nil
+}
+
 // ProfileHandler handles GET /profile
 func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
@@ -167,7 +187,9 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session.ClearSession(cookie.Value)
+	if err := session.ClearSession(cookie.Value); err != nil {
+		fmt.Printf("Error clearing session: %v\n", err)
+	}
 
 	// Clear the cookie
 	http.SetCookie(w, &http.Cookie{
@@ -184,6 +206,39 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// LogoutAllHandler handles POST /logout/all and ends every session of the
+// logged-in user, on every device.
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	cleared, err := session.ClearUserSessions(account.Username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   "session_id",
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"message":  "Logged out of all devices",
+		"sessions": cleared,
+	})
+}
+
 // AllDataCentersHandler handles GET /alldatacenters
 func AllDataCentersHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
@@ -192,7 +247,7 @@ func AllDataCentersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dataCenters, err := data.ReadAllDataCenters("us_datacenters.csv")
+	dataCenters, err := data.ReadAllDataCenters(data.DatacentersCSV)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to open file: %v", err), http.StatusInternalServerError)
 		return
@@ -217,16 +272,17 @@ func PossibleDataCenterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	locations, err := data.ReadDatacenterLocations("us_possible_locations.csv")
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
 	if err != nil {
 		http.Error(w, "Error reading datacenter locations: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Create a simplified response with only lat/long
-	var response []map[string]float64
+	// Create a simplified response with only the site ID and lat/long
+	var response []map[string]interface{}
 	for _, loc := range locations {
-		response = append(response, map[string]float64{
+		response = append(response, map[string]interface{}{
+			"id":        loc.ID,
 			"latitude":  loc.Latitude,
 			"longitude": loc.Longitude,
 		})
@@ -268,13 +324,13 @@ func GetPropertyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	const epsilon = 0.0001
-	locations, err := data.ReadDatacenterLocations("us_possible_locations.csv")
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
 	if err != nil {
 		http.Error(w, "Error reading datacenter locations: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	existingDCs, err := data.ReadExistingDatacenters("us_datacenters.csv")
+	existingDCs, err := data.ReadExistingDatacenters(data.DatacentersCSV)
 	if err != nil {
 		log.Printf("Warning: Could not read existing datacenter data: %v", err)
 	}
@@ -288,7 +344,7 @@ func GetPropertyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 
 			// Calculate environmental metrics
 			allDCs := append(locations, existingDCs...)
-			CalculateResearchBasedMetrics(matched, allDCs) // see envcalcs.go
+			data.CalculateResearchBasedMetrics(matched, allDCs) // see data/envcalcs.go
 			break
 		}
 	}
@@ -314,6 +370,17 @@ func GetPropertyDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		"compounded_temp_increase": matched.CompoundedTempIncrease,
 		"water_competition":        matched.WaterCompetition,
 	}
+	// Server-side prices, so the frontend shows what /cart/add will charge.
+	if quotes, err := economy.QuoteAllTiers(matched); err == nil {
+		response["land_cost"] = quotes[0].LandCost
+		response["tier_prices"] = quotes
+	}
+	// Connectivity: the closest market and how well it can be served from here.
+	metro, km := data.NearestMetro(matched.Latitude, matched.Longitude)
+	response["nearest_metro"] = metro.Name
+	response["nearest_metro_km"] = km
+	response["latency_ms"] = data.LatencyMs(km)
+	response["service_factor"] = data.ServiceFactor(km)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// useTempStore points the store at a fresh directory for one test.
+func useTempStore(t *testing.T) {
+	t.Helper()
+	store.SetDefault(store.NewFileStore(t.TempDir()))
+}
+
+// loggedIn registers a user unless an earlier test did (users outlive a
+// test) and returns a cookie for a new session of theirs.
+func loggedIn(t *testing.T, username string) *http.Cookie {
+	t.Helper()
+	if !user.Exists(username) {
+		if err := user.AddUser(username, ""); err != nil {
+			t.Fatalf("adding %s: %v", username, err)
+		}
+	}
+	id, err := session.Create(username)
+	if err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	return &http.Cookie{Name: "session_id", Value: id}
+}
+
+// serve runs one request through a handler and returns the response.
+func serve(h http.HandlerFunc, method, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	return serveJSON(h, method, target, cookie, "")
+}
+
+// serveJSON is serve with a JSON request body.
+func serveJSON(h http.HandlerFunc, method, target string, cookie *http.Cookie, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/classroom"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+// CreateClassroomRequest is the expected JSON payload for
+// POST /instructor/classrooms.
+type CreateClassroomRequest struct {
+	Name     string   `json:"name"`
+	Students []string `json:"students"` // optional initial roster
+}
+
+// RosterRequest is the expected JSON payload for
+// POST /instructor/classrooms/students.
+type RosterRequest struct {
+	Classroom string   `json:"classroom"`
+	Add       []string `json:"add"`
+	Remove    []string `json:"remove"`
+}
+
+// ClassroomGamesRequest is the expected JSON payload for
+// POST /instructor/classrooms/games. Every student named, or the whole
+// roster if none are, gets a new game with the same settings; a student's
+// game in progress is replaced and their cart reset, which is how an
+// instructor resets a game.
+type ClassroomGamesRequest struct {
+	Classroom    string                `json:"classroom"`
+	Students     []string              `json:"students"`
+	Seed         uint64                `json:"seed"` // 0 picks one random seed for the class
+	AutoAdvance  bool                  `json:"auto_advance"`
+	CarbonPolicy string                `json:"carbon_policy"`
+	CustomPolicy *economy.CarbonPolicy `json:"custom_policy,omitempty"`
+	Scenario     string                `json:"scenario"`
+}
+
+// StudentProgress is one student's cart, game and finished games, as the
+// classroom roster returns them. Cart and Game are null for students who
+// haven't started.
+type StudentProgress struct {
+	Username string       `json:"username"`
+	Cart     *cart.Cart   `json:"cart"`
+	Game     *game.Game   `json:"game"`
+	Record   *game.Record `json:"record"`
+}
+
+// instructedClassroom returns the classroom named in a request if the
+// logged-in user is its instructor or an admin. On failure it has already
+// written the error response.
+func instructedClassroom(w http.ResponseWriter, r *http.Request, name string) (classroom.View, bool) {
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return classroom.View{}, false
+	}
+	if name == "" {
+		http.Error(w, "classroom is required", http.StatusBadRequest)
+		return classroom.View{}, false
+	}
+	c, ok := classroom.Get(name)
+	if !ok {
+		http.Error(w, "Classroom not found", http.StatusNotFound)
+		return classroom.View{}, false
+	}
+	if c.Instructor != account.Username && !account.IsAdmin() {
+		http.Error(w, "You may only manage your own classrooms", http.StatusForbidden)
+		return classroom.View{}, false
+	}
+	return c, true
+}
+
+// ListClassroomsHandler handles GET /instructor/classrooms. Instructors see
+// their own classrooms, admins see all of them.
+func ListClassroomsHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	instructor := account.Username
+	if account.IsAdmin() {
+		instructor = ""
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"classrooms": classroom.List(instructor),
+	})
+}
+
+// CreateClassroomHandler handles POST /instructor/classrooms. The logged-in
+// user becomes the classroom's instructor.
+func CreateClassroomHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	var req CreateClassroomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	c, err := classroom.Create(strings.TrimSpace(req.Name), account.Username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Students) > 0 {
+		if c, err = classroom.AddStudents(c.Name, req.Students); err != nil {
+			http.Error(w, fmt.Sprintf("Classroom created, but adding students failed: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"classroom": c,
+	})
+}
+
+// DeleteClassroomHandler handles DELETE /instructor/classrooms?name=period-3.
+// The students' games and results are kept.
+func DeleteClassroomHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := instructedClassroom(w, r, r.URL.Query().Get("name"))
+	if !ok {
+		return
+	}
+	if err := classroom.Delete(c.Name); err != nil {
+		http.Error(w, fmt.Sprintf("Error deleting classroom: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Classroom %s deleted", c.Name),
+	})
+}
+
+// ClassroomRosterHandler handles GET /instructor/classrooms/students?classroom=period-3
+// and returns each student's cart, current game and finished games.
+func ClassroomRosterHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := instructedClassroom(w, r, r.URL.Query().Get("classroom"))
+	if !ok {
+		return
+	}
+	students := make([]StudentProgress, 0, len(c.Students))
+	for _, username := range c.Students {
+		p := StudentProgress{Username: username}
+		if sc, ok := cart.GetCart(username); ok {
+			p.Cart = sc
+		}
+		if g, ok := game.Get(username); ok {
+			p.Game = g
+		}
+		if rec, ok := game.GetRecord(username); ok {
+			p.Record = rec
+		} else {
+			p.Record = &game.Record{
+				Username:     username,
+				Results:      []game.Result{},
+				Achievements: []game.UnlockedAchievement{},
+			}
+		}
+		students = append(students, p)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"classroom": c,
+		"students":  students,
+	})
+}
+
+// UpdateRosterHandler handles POST /instructor/classrooms/students, adding
+// and removing students.
+func UpdateRosterHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req RosterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	c, ok := instructedClassroom(w, r, req.Classroom)
+	if !ok {
+		return
+	}
+	var err error
+	if len(req.Add) > 0 {
+		if c, err = classroom.AddStudents(c.Name, req.Add); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Remove) > 0 {
+		if c, err = classroom.RemoveStudents(c.Name, req.Remove); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"classroom": c,
+	})
+}
+
+// StartClassroomGamesHandler handles POST /instructor/classrooms/games. The
+// games are tagged with the classroom, so they share its leaderboard. A
+// student whose game can't be started is reported in "failed" without
+// stopping the others.
+func StartClassroomGamesHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ClassroomGamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	c, ok := instructedClassroom(w, r, req.Classroom)
+	if !ok {
+		return
+	}
+	policy, err := gamePolicy(req.CarbonPolicy, req.CustomPolicy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := game.GetScenario(req.Scenario); !ok {
+		http.Error(w, fmt.Sprintf("Unknown scenario %q", req.Scenario), http.StatusBadRequest)
+		return
+	}
+
+	students := req.Students
+	if len(students) == 0 {
+		students = c.Students
+	}
+	for _, username := range students {
+		if !containsString(c.Students, username) {
+			http.Error(w, fmt.Sprintf("%s is not in classroom %s", username, c.Name), http.StatusBadRequest)
+			return
+		}
+	}
+	// Everyone in the class plays the same world unless told otherwise.
+	if req.Seed == 0 {
+		req.Seed = uint64(time.Now().UnixNano())
+	}
+
+	games := make(map[string]*game.Game)
+	failed := make(map[string]string)
+	for _, username := range students {
+		g, err := startGame(username, game.Options{
+			Seed:         req.Seed,
+			AutoAdvance:  req.AutoAdvance,
+			CarbonPolicy: policy,
+			Scenario:     req.Scenario,
+			Classroom:    c.Name,
+		})
+		if err != nil {
+			failed[username] = err.Error()
+			continue
+		}
+		games[username] = g
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"classroom": c.Name,
+		"seed":      req.Seed,
+		"games":     games,
+		"failed":    failed,
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/leaderboard"
+)
+
+// Leaderboard page sizes.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// leaderboardQuery reads the metric and filter shared by the leaderboard
+// endpoints: metric, scenario, seed and classroom.
+func leaderboardQuery(q url.Values) (string, leaderboard.Filter, error) {
+	metric := q.Get("metric")
+	if metric == "" {
+		metric = leaderboard.MetricScore
+	}
+	f := leaderboard.Filter{
+		Scenario:  q.Get("scenario"),
+		Classroom: q.Get("classroom"),
+	}
+	if s := q.Get("seed"); s != "" {
+		seed, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return "", f, fmt.Errorf("invalid seed %q", s)
+		}
+		f.Seed = seed
+	}
+	return metric, f, nil
+}
+
+// positiveParam reads an optional positive integer query parameter, capped at max.
+func positiveParam(q url.Values, name string, def, max int) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// LeaderboardHandler handles
+// GET /api/leaderboard?metric=score&scenario=&seed=&classroom=&page=1&page_size=20
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	metric, filter, err := leaderboardQuery(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := positiveParam(q, "page", 1, math.MaxInt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageSize, err := positiveParam(q, "page_size", defaultPageSize, maxPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := leaderboard.Compute(metric, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"metric":    metric,
+		"total":     len(entries),
+		"page":      page,
+		"page_size": pageSize,
+		"entries":   leaderboard.Page(entries, page, pageSize),
+		"metrics":   leaderboard.Metrics(),
+	})
+}
+
+// LeaderboardTopHandler handles GET /api/leaderboard/top?n=10 with the same
+// metric and filters as /api/leaderboard.
+func LeaderboardTopHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	metric, filter, err := leaderboardQuery(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := positiveParam(q, "n", 10, maxPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := leaderboard.Compute(metric, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"metric":  metric,
+		"total":   len(entries),
+		"entries": leaderboard.Page(entries, 1, n),
+	})
+}
+
+// LeaderboardMeHandler handles GET /api/leaderboard/me and returns the
+// logged-in player's place under the given metric and filters. Admins may
+// ask for another player's with ?username=.
+func LeaderboardMeHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	username, ok := actingUser(w, r, q.Get("username"))
+	if !ok {
+		return
+	}
+	metric, filter, err := leaderboardQuery(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := leaderboard.Compute(metric, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, ok := leaderboard.Find(entries, username)
+	if !ok {
+		http.Error(w, "No ranked results for this user", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"metric": metric,
+		"total":  len(entries),
+		"entry":  entry,
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/classroom"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/leaderboard"
+)
+
+func TestLeaderboardMe(t *testing.T) {
+	useTempStore(t)
+	ranked := loggedIn(t, "ranked_player")
+	unranked := loggedIn(t, "unranked_player")
+	me := RequireAuth(LeaderboardMeHandler)
+
+	if _, err := startGame("ranked_player", game.Options{Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.Advance("ranked_player", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.Finish("ranked_player", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := serve(me, http.MethodGet, "/api/leaderboard/me?username=ranked_player", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("without logging in: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w := serve(me, http.MethodGet, "/api/leaderboard/me", ranked)
+	if w.Code != http.StatusOK {
+		t.Fatalf("own place: got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Entry leaderboard.Entry `json:"entry"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Entry.Username != "ranked_player" {
+		t.Errorf("got the place of %q", body.Entry.Username)
+	}
+
+	if w := serve(me, http.MethodGet, "/api/leaderboard/me?username=ranked_player", unranked); w.Code != http.StatusForbidden {
+		t.Errorf("another player's place: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := serve(me, http.MethodGet, "/api/leaderboard/me", unranked); w.Code != http.StatusNotFound {
+		t.Errorf("player without results: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestNewGameClassroom(t *testing.T) {
+	useTempStore(t)
+	student := loggedIn(t, "enrolled_player")
+	outsider := loggedIn(t, "outside_player")
+	loggedIn(t, "class_instructor")
+	if _, ok := classroom.Get("ecology-101"); !ok {
+		if _, err := classroom.Create("ecology-101", "class_instructor"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := classroom.AddStudents("ecology-101", []string{"enrolled_player"}); err != nil {
+		t.Fatal(err)
+	}
+	newGame := RequireAuth(NewGameHandler)
+
+	for _, tc := range []struct {
+		cookie    *http.Cookie
+		classroom string
+		want      int
+	}{
+		{outsider, "ecology-101", http.StatusForbidden},
+		{student, "no-such-class", http.StatusBadRequest},
+		{student, " ecology-101 ", http.StatusOK},
+		{outsider, "", http.StatusOK},
+	} {
+		w := serveJSON(newGame, http.MethodPost, "/game/new", tc.cookie, `{"seed":1,"classroom":"`+tc.classroom+`"}`)
+		if w.Code != tc.want {
+			t.Errorf("classroom %q: got %d, want %d: %s", tc.classroom, w.Code, tc.want, w.Body)
+		}
+	}
+	if g, _ := game.Get("enrolled_player"); g.Classroom != "ecology-101" {
+		t.Errorf("student's game is in classroom %q", g.Classroom)
+	}
+	if g, _ := game.Get("outside_player"); g.Classroom != "" {
+		t.Errorf("outsider's game is in classroom %q", g.Classroom)
+	}
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// oidcStateCookie ties a login at a provider to the browser that started it,
+// so nobody can finish their own login in someone else's browser.
+const oidcStateCookie = "oidc_state"
+
+// setOIDCState remembers the state of a login just started.
+func setOIDCState(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		HttpOnly: true,
+		Path:     "/auth/oidc",
+		MaxAge:   int(oidc.AuthTTL.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCProvidersHandler handles GET /auth/oidc/providers, listing the
+// providers users can log in with.
+func OIDCProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"providers": oidc.Providers(),
+	})
+}
+
+// OIDCLoginHandler handles GET /auth/oidc/login?provider=school, sending the
+// browser to the provider to log in. An optional login_hint is passed on.
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("provider")
+	if _, ok := oidc.Get(name); !ok {
+		http.Error(w, "Unknown login provider", http.StatusNotFound)
+		return
+	}
+	authURL, state, err := oidc.Begin(name, "", r.URL.Query().Get("login_hint"))
+	if err != nil {
+		log.Printf("Error starting %s login: %v", name, err)
+		http.Error(w, "Can't reach the login provider; try again later", http.StatusBadGateway)
+		return
+	}
+	setOIDCState(w, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// LinkIdentityHandler handles POST /account/identities/link?provider=school.
+// It starts a login at the provider whose account is then linked to the
+// logged-in user, and returns the URL to send the browser to.
+func LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	name := r.URL.Query().Get("provider")
+	if _, ok := oidc.Get(name); !ok {
+		http.Error(w, "Unknown login provider", http.StatusNotFound)
+		return
+	}
+	authURL, state, err := oidc.Begin(name, account.Username, "")
+	if err != nil {
+		log.Printf("Error starting %s login: %v", name, err)
+		http.Error(w, "Can't reach the login provider; try again later", http.StatusBadGateway)
+		return
+	}
+	setOIDCState(w, state)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "success",
+		"auth_url": authURL,
+	})
+}
+
+// OIDCCallbackHandler handles GET /auth/oidc/callback, where the provider
+// sends the browser back. A linked identity logs its user in; an identity
+// being linked is linked; an unknown identity gets a new player if the
+// provider allows it. Accounts are never matched by email address, which a
+// provider may let users choose.
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/auth/oidc", MaxAge: -1})
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		oidcFail(w, r, http.StatusBadRequest, "This login wasn't started in this browser; start again")
+		return
+	}
+	if e := query.Get("error"); e != "" {
+		oidc.Finish(state, "") // forget the login
+		oidcFail(w, r, http.StatusUnauthorized, fmt.Sprintf("The login provider refused the login (%s)", e))
+		return
+	}
+	result, err := oidc.Finish(state, query.Get("code"))
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		oidcFail(w, r, http.StatusUnauthorized, "Login failed: "+err.Error())
+		return
+	}
+
+	if result.LinkUsername != "" {
+		// The browser must still be logged in as the user who asked.
+		current := ""
+		if c, err := r.Cookie("session_id"); err == nil {
+			current, _ = session.GetUserForSession(c.Value)
+		}
+		if current != result.LinkUsername {
+			oidcFail(w, r, http.StatusForbidden, "Log in again to link this account")
+			return
+		}
+		if err := oidc.LinkIdentity(current, result.Identity); err != nil {
+			oidcFail(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		oidcDone(w, r, "linked", current)
+		return
+	}
+
+	username, ok := oidc.UserFor(result.Identity)
+	outcome := "login"
+	if !ok {
+		if err := oidc.MayAutoRegister(result.Identity); err != nil {
+			oidcFail(w, r, http.StatusForbidden, err.Error())
+			return
+		}
+		if username, err = registerIdentity(result.Identity); err != nil {
+			log.Printf("Error registering %s user: %v", result.Provider, err)
+			oidcFail(w, r, http.StatusInternalServerError, "Error creating your account")
+			return
+		}
+		outcome = "registered"
+	}
+	if _, err := startSession(w, username); err != nil {
+		fmt.Printf("Error creating session: %v\n", err)
+		oidcFail(w, r, http.StatusInternalServerError, "Error creating session")
+		return
+	}
+	oidcDone(w, r, outcome, username)
+}
+
+// registerIdentity creates a player without a password for an identity and
+// links the two, naming the player after what the provider calls them.
+func registerIdentity(id oidc.Identity) (string, error) {
+	suggestion := id.PreferredUsername
+	if at := strings.LastIndex(suggestion, "@"); at >= 0 {
+		suggestion = suggestion[:at]
+	}
+	if suggestion == "" {
+		if at := strings.LastIndex(id.Email, "@"); at > 0 {
+			suggestion = id.Email[:at]
+		}
+	}
+	username := user.AvailableUsername(suggestion)
+	if err := user.AddUser(username, ""); err != nil {
+		return "", err
+	}
+	if err := oidc.LinkIdentity(username, id); err != nil {
+		return "", err
+	}
+	fmt.Printf("Registered %s for %s login %s\n", username, id.Provider, id.Subject)
+	return username, nil
+}
+
+// oidcDone finishes a callback: back to the frontend if a return URL is
+// configured, otherwise a JSON answer.
+func oidcDone(w http.ResponseWriter, r *http.Request, outcome, username string) {
+	if back := oidc.ReturnURL(); back != "" {
+		http.Redirect(w, r, withQuery(back, "oidc", outcome), http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "success",
+		"outcome":  outcome,
+		"username": username,
+	})
+}
+
+// oidcFail reports a failed callback the same way oidcDone reports success.
+func oidcFail(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if back := oidc.ReturnURL(); back != "" {
+		http.Redirect(w, r, withQuery(back, "oidc_error", message), http.StatusFound)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+func withQuery(raw, key, value string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// ListIdentitiesHandler handles GET /account/identities
+func ListIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "success",
+		"identities":   oidc.Links(account.Username),
+		"has_password": user.HasPassword(account.Username),
+	})
+}
+
+// UnlinkIdentityHandler handles DELETE /account/identities?provider=school&subject=...
+func UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	provider, subject := r.URL.Query().Get("provider"), r.URL.Query().Get("subject")
+	if err := oidc.Unlink(account.Username, provider, subject); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Unlinked %s account %s", provider, subject),
+	})
+}
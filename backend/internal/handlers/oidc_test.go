@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc/oidctest"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/session"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// useSchoolProvider starts a mock identity provider and configures it as
+// "school", the only provider.
+func useSchoolProvider(t *testing.T, autoRegister bool) {
+	t.Helper()
+	is, srv, err := oidctest.NewServer("ecology-map", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	config, err := json.Marshal([]oidc.ProviderConfig{{
+		Name:         "school",
+		Issuer:       is.URL,
+		ClientID:     is.ClientID,
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		AutoRegister: autoRegister,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "providers.json")
+	if err := ioutil.WriteFile(filename, config, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := oidc.LoadProviders(filename); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cookieNamed returns the cookie a response sets, or nil.
+func cookieNamed(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// atProvider logs in at the provider as login, as a browser sent to
+// authURL would, and returns where the provider sends the browser back.
+func atProvider(t *testing.T, authURL, login string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("login_hint", login)
+	u.RawQuery = q.Encode()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := resp.Location()
+	if err != nil {
+		t.Fatalf("provider didn't send the browser back: %s", resp.Status)
+	}
+	return back
+}
+
+// callback brings the browser back from the provider with its cookies.
+func callback(back *url.URL, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, back.RequestURI(), nil)
+	for _, c := range cookies {
+		if c != nil {
+			r.AddCookie(c)
+		}
+	}
+	w := httptest.NewRecorder()
+	OIDCCallbackHandler(w, r)
+	return w
+}
+
+// oidcLogin logs in through the provider as login, from the login button
+// to the callback.
+func oidcLogin(t *testing.T, login string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := serve(OIDCLoginHandler, http.MethodGet, "/auth/oidc/login?provider=school&login_hint="+url.QueryEscape(login), nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("starting the login: got %d: %s", w.Code, w.Body)
+	}
+	return callback(atProvider(t, w.Header().Get("Location"), login), cookieNamed(w, oidcStateCookie))
+}
+
+// outcome reads a successful callback's answer.
+func outcome(t *testing.T, w *httptest.ResponseRecorder) (string, string) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Outcome  string `json:"outcome"`
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Outcome, body.Username
+}
+
+func TestOIDCRegistersNewPlayer(t *testing.T) {
+	useTempStore(t)
+	useSchoolProvider(t, true)
+	// Nobody has this name yet, nor logged in with it at the provider.
+	login := user.AvailableUsername("new_student")
+
+	w := oidcLogin(t, login)
+	result, username := outcome(t, w)
+	if result != "registered" || username != login {
+		t.Fatalf("first login: %s as %q, want registered as %q", result, username, login)
+	}
+	if user.HasPassword(username) {
+		t.Error("a registered player has a password")
+	}
+	c := cookieNamed(w, "session_id")
+	if c == nil {
+		t.Fatal("no session after registering")
+	}
+	if got, ok := session.GetUserForSession(c.Value); !ok || got != username {
+		t.Errorf("session is for %q, %v", got, ok)
+	}
+
+	if result, again := outcome(t, oidcLogin(t, login)); result != "login" || again != username {
+		t.Errorf("second login: %s as %q, want login as %q", result, again, username)
+	}
+}
+
+func TestOIDCLinksExistingUser(t *testing.T) {
+	useTempStore(t)
+	useSchoolProvider(t, false)
+	existing := user.AvailableUsername("linking_player")
+	cookie := loggedIn(t, existing)
+	login := existing + "_at_school"
+
+	if w := oidcLogin(t, login); w.Code != http.StatusForbidden {
+		t.Fatalf("unlinked login without auto-registration: got %d: %s", w.Code, w.Body)
+	}
+
+	link := func() (*url.URL, *http.Cookie) {
+		w := serve(RequireSession(LinkIdentityHandler), http.MethodPost, "/account/identities/link?provider=school", cookie)
+		if w.Code != http.StatusOK {
+			t.Fatalf("starting to link: got %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			AuthURL string `json:"auth_url"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		state := cookieNamed(w, oidcStateCookie)
+		return atProvider(t, body.AuthURL, login), state
+	}
+
+	// Coming back logged out, or in another browser, links nothing.
+	back, state := link()
+	if w := callback(back, state); w.Code != http.StatusForbidden {
+		t.Errorf("linking without the session: got %d: %s", w.Code, w.Body)
+	}
+	back, _ = link()
+	if w := callback(back, cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("linking without the state cookie: got %d: %s", w.Code, w.Body)
+	}
+
+	back, state = link()
+	if result, username := outcome(t, callback(back, state, cookie)); result != "linked" || username != existing {
+		t.Fatalf("linking: %s as %q", result, username)
+	}
+	if result, username := outcome(t, oidcLogin(t, login)); result != "login" || username != existing {
+		t.Errorf("login after linking: %s as %q, want login as %q", result, username, existing)
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// paretoObjectives lists the objectives the frontier can be computed over.
+// Every objective is minimised.
+var paretoObjectives = []string{"cost", "carbon", "water", "risk"}
+
+// ParetoSite is one candidate site with its objective values and dominance rank.
+type ParetoSite struct {
+	SiteID      int                `json:"site_id"`
+	Name        string             `json:"name"`
+	Latitude    float64            `json:"latitude"`
+	Longitude   float64            `json:"longitude"`
+	Rank        int                `json:"rank"`         // 1 = on the Pareto frontier
+	DominatedBy int                `json:"dominated_by"` // number of sites that dominate this one
+	Objectives  map[string]float64 `json:"objectives"`
+}
+
+// ParetoResponse is the overall response from the Pareto endpoint.
+type ParetoResponse struct {
+	Tier         string       `json:"tier"`
+	Objectives   []string     `json:"objectives"`
+	FrontierSize int          `json:"frontier_size"`
+	Sites        []ParetoSite `json:"sites"`
+}
+
+// ParetoFrontierHandler handles
+// GET /api/pareto?tier=standard&objectives=cost,carbon&max_cost=..&frontier_only=true
+//
+// Objectives are cost (land + capex + one year of electricity, USD), carbon
+// (metric tons CO2/year), water (gallons/year weighted by water scarcity) and
+// risk (natural disaster risk, 0-1). max_<objective> filters out sites above
+// the given value before ranking.
+func ParetoFrontierHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	tierID := q.Get("tier")
+	if tierID == "" {
+		tierID = data.TierStandard
+	}
+	tier, ok := data.GetTier(tierID)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown tier %q", tierID), http.StatusBadRequest)
+		return
+	}
+
+	objectives := paretoObjectives
+	if raw := q.Get("objectives"); raw != "" {
+		objectives = nil
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if !isParetoObjective(name) {
+				http.Error(w, fmt.Sprintf("Unknown objective %q (expected cost, carbon, water or risk)", name), http.StatusBadRequest)
+				return
+			}
+			objectives = append(objectives, name)
+		}
+	}
+
+	limits := make(map[string]float64)
+	for _, name := range paretoObjectives {
+		raw := q.Get("max_" + name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid max_%s value", name), http.StatusBadRequest)
+			return
+		}
+		limits[name] = v
+	}
+	frontierOnly := q.Get("frontier_only") == "true"
+
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
+	if err != nil {
+		http.Error(w, "Error reading datacenter locations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existingDCs, err := data.ReadExistingDatacenters(data.DatacentersCSV)
+	if err != nil {
+		log.Printf("Warning: Could not read existing datacenter data: %v", err)
+	}
+	allDCs := append(append([]data.DatacenterLocation{}, locations...), existingDCs...)
+
+	var sites []ParetoSite
+	for _, loc := range locations {
+		l := loc
+		l.Tier = tier.ID
+		values, err := siteObjectives(&l, tier, allDCs)
+		if err != nil {
+			continue // unparseable land price
+		}
+		if exceedsLimits(values, limits) {
+			continue
+		}
+		sites = append(sites, ParetoSite{
+			SiteID:     l.ID,
+			Name:       l.Name,
+			Latitude:   l.Latitude,
+			Longitude:  l.Longitude,
+			Objectives: values,
+		})
+	}
+
+	rankPareto(sites, objectives)
+
+	resp := ParetoResponse{Tier: tier.ID, Objectives: objectives, Sites: []ParetoSite{}}
+	for _, s := range sites {
+		if s.Rank == 1 {
+			resp.FrontierSize++
+		}
+		if frontierOnly && s.Rank != 1 {
+			continue
+		}
+		resp.Sites = append(resp.Sites, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func isParetoObjective(name string) bool {
+	for _, o := range paretoObjectives {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// siteObjectives computes every Pareto objective for a site built at the given tier.
+func siteObjectives(loc *data.DatacenterLocation, tier data.Tier, allDCs []data.DatacenterLocation) (map[string]float64, error) {
+	quote, err := economy.QuoteSite(loc, tier.ID)
+	if err != nil {
+		return nil, err
+	}
+	data.CalculateResearchBasedMetrics(loc, allDCs)
+	envData := data.GetEnvironmentalData(loc)
+	electricityCost := data.AnnualEnergyMWh(loc) * 1000 * data.ElectricityPrice(loc)
+
+	return map[string]float64{
+		"cost":   quote.Total + electricityCost,
+		"carbon": loc.CarbonImpact,
+		"water":  loc.WaterUsage * envData.WaterScarcityIndex,
+		"risk":   envData.NaturalDisasterRisk,
+	}, nil
+}
+
+func exceedsLimits(values, limits map[string]float64) bool {
+	for name, max := range limits {
+		if values[name] > max {
+			return true
+		}
+	}
+	return false
+}
+
+// dominates reports whether a is no worse than b in every objective and
+// strictly better in at least one.
+func dominates(a, b map[string]float64, objectives []string) bool {
+	better := false
+	for _, o := range objectives {
+		if a[o] > b[o] {
+			return false
+		}
+		if a[o] < b[o] {
+			better = true
+		}
+	}
+	return better
+}
+
+// rankPareto assigns dominance ranks by non-dominated sorting: rank 1 is the
+// frontier, rank 2 the frontier once rank 1 is removed, and so on. Sites are
+// returned sorted by rank, then by site ID.
+func rankPareto(sites []ParetoSite, objectives []string) {
+	n := len(sites)
+	dominatedBy := make([]int, n)
+	dominating := make([][]int, n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			switch {
+			case dominates(sites[i].Objectives, sites[j].Objectives, objectives):
+				dominating[i] = append(dominating[i], j)
+				dominatedBy[j]++
+			case dominates(sites[j].Objectives, sites[i].Objectives, objectives):
+				dominating[j] = append(dominating[j], i)
+				dominatedBy[i]++
+			}
+		}
+	}
+
+	remaining := make([]int, n)
+	var front []int
+	for i := 0; i < n; i++ {
+		sites[i].DominatedBy = dominatedBy[i]
+		remaining[i] = dominatedBy[i]
+		if remaining[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	for rank := 1; len(front) > 0; rank++ {
+		var next []int
+		for _, i := range front {
+			sites[i].Rank = rank
+			for _, j := range dominating[i] {
+				remaining[j]--
+				if remaining[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		front = next
+	}
+
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Rank != sites[j].Rank {
+			return sites[i].Rank < sites[j].Rank
+		}
+		return sites[i].SiteID < sites[j].SiteID
+	})
+}
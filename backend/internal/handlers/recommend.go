@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+)
+
+// Recommender objectives.
+const (
+	objectiveEcoScore = "eco_score" // maximise the sum of eco scores
+	objectiveCarbon   = "carbon"    // minimise total carbon
+)
+
+// Solver limits. The budget is split into costBins buckets and the
+// maxCandidateSites best sites (ranked by objective per dollar) enter the
+// knapsack, or as many as it takes to reach the target capacity.
+const (
+	costBins          = 200
+	capacityUnits     = 100
+	maxCandidateSites = 80
+	maxAlternatives   = 5
+)
+
+// RecommendRequest is the expected JSON payload for POST /api/recommend.
+type RecommendRequest struct {
+	Budget           float64  `json:"budget"`
+	TargetCapacityMW float64  `json:"target_capacity_mw"`
+	Objective        string   `json:"objective,omitempty"` // "eco_score" (default) or "carbon"
+	Profile          string   `json:"profile,omitempty"`   // scoring profile, defaults to the active one
+	Tiers            []string `json:"tiers,omitempty"`     // restrict the tiers considered
+}
+
+// RecommendedSite is one site/tier pair in a recommendation.
+type RecommendedSite struct {
+	SiteID       int     `json:"site_id"`
+	Name         string  `json:"name"`
+	Tier         string  `json:"tier"`
+	Price        float64 `json:"price"`
+	CapacityMW   float64 `json:"capacity_mw"`
+	EcoScore     int     `json:"eco_score"`
+	CarbonImpact float64 `json:"carbon_impact"` // metric tons CO2/year
+}
+
+// TradeOff describes the marginal effect of taking a next-best alternative.
+type TradeOff struct {
+	RecommendedSite
+	Action        string  `json:"action"`             // "swap" or "add"
+	Replaces      int     `json:"replaces,omitempty"` // site ID swapped out
+	CostDelta     float64 `json:"cost_delta"`
+	CapacityDelta float64 `json:"capacity_delta"`
+	EcoScoreDelta int     `json:"eco_score_delta"`
+	CarbonDelta   float64 `json:"carbon_delta"`
+	FitsBudget    bool    `json:"fits_budget"`
+}
+
+// RecommendResponse is the overall response from the recommender.
+type RecommendResponse struct {
+	Objective        string            `json:"objective"`
+	Profile          string            `json:"profile"`
+	Budget           float64           `json:"budget"`
+	TargetCapacityMW float64           `json:"target_capacity_mw"`
+	TotalCost        float64           `json:"total_cost"`
+	TotalCapacityMW  float64           `json:"total_capacity_mw"`
+	TotalEcoScore    int               `json:"total_eco_score"`
+	TotalCarbon      float64           `json:"total_carbon"`
+	CapacityMet      bool              `json:"capacity_met"`
+	Sites            []RecommendedSite `json:"sites"`
+	Alternatives     []TradeOff        `json:"alternatives"`
+}
+
+// siteOptions groups the tiers that can be built on one site; the solver
+// picks at most one option per group.
+type siteOptions struct {
+	options []RecommendedSite
+	density float64 // best objective value per dollar, used for pruning
+}
+
+// RecommendPortfolioHandler handles POST /api/recommend
+func RecommendPortfolioHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RecommendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Budget <= 0 {
+		http.Error(w, "Budget must be positive", http.StatusBadRequest)
+		return
+	}
+	if req.TargetCapacityMW < 0 {
+		http.Error(w, "Target capacity cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Objective == "" {
+		req.Objective = objectiveEcoScore
+	}
+	if req.Objective != objectiveEcoScore && req.Objective != objectiveCarbon {
+		http.Error(w, fmt.Sprintf("Unknown objective %q (expected eco_score or carbon)", req.Objective), http.StatusBadRequest)
+		return
+	}
+	if req.Objective == objectiveCarbon && req.TargetCapacityMW <= 0 {
+		// Every site adds carbon, so without a target the best portfolio is none.
+		http.Error(w, "The carbon objective needs a positive target capacity", http.StatusBadRequest)
+		return
+	}
+	profile, ok := data.GetScoringProfile(req.Profile)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown scoring profile %q", req.Profile), http.StatusBadRequest)
+		return
+	}
+	tiers, err := selectTiers(req.Tiers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	locations, err := data.ReadDatacenterLocations(data.PossibleLocationsCSV)
+	if err != nil {
+		http.Error(w, "Error reading datacenter locations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existingDCs, err := data.ReadExistingDatacenters(data.DatacentersCSV)
+	if err != nil {
+		log.Printf("Warning: Could not read existing datacenter data: %v", err)
+	}
+
+	groups := buildSiteOptions(locations, existingDCs, tiers, profile, req.Budget, req.Objective)
+	if available := availableCapacityMW(groups); req.TargetCapacityMW > available {
+		http.Error(w, fmt.Sprintf("Target capacity of %.0f MW is more than every affordable site together provides (%.0f MW)", req.TargetCapacityMW, available), http.StatusBadRequest)
+		return
+	}
+	resp := solvePortfolio(groups, req)
+	resp.Profile = profile.Name
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// selectTiers resolves the requested tier IDs, defaulting to every tier.
+func selectTiers(ids []string) ([]data.Tier, error) {
+	if len(ids) == 0 {
+		return data.Tiers, nil
+	}
+	var tiers []data.Tier
+	for _, id := range ids {
+		t, ok := data.GetTier(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown tier %q", id)
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, nil
+}
+
+// objectiveValue is the quantity the solver maximises for one option.
+func objectiveValue(o RecommendedSite, objective string) float64 {
+	if objective == objectiveCarbon {
+		return -o.CarbonImpact
+	}
+	return float64(o.EcoScore)
+}
+
+// buildSiteOptions prices and scores every affordable site/tier pair.
+func buildSiteOptions(locations, existing []data.DatacenterLocation, tiers []data.Tier, profile data.ScoringProfile, budget float64, objective string) []siteOptions {
+	allDCs := append(append([]data.DatacenterLocation{}, locations...), existing...)
+
+	var groups []siteOptions
+	for _, loc := range locations {
+		var g siteOptions
+		for _, tier := range tiers {
+			l := loc
+			l.Tier = tier.ID
+			quote, err := economy.QuoteSite(&l, tier.ID)
+			if err != nil || quote.Total > budget {
+				continue
+			}
+			price := quote.Total
+			data.CalculateResearchBasedMetricsWithProfile(&l, allDCs, profile)
+			o := RecommendedSite{
+				SiteID:       l.ID,
+				Name:         l.Name,
+				Tier:         tier.ID,
+				Price:        price,
+				CapacityMW:   tier.CapacityMW,
+				EcoScore:     l.EcoScore,
+				CarbonImpact: l.CarbonImpact,
+			}
+			g.options = append(g.options, o)
+
+			// For carbon, rank by capacity bought per tonne and dollar instead.
+			d := float64(o.EcoScore) / price
+			if objective == objectiveCarbon {
+				d = o.CapacityMW / (math.Max(o.CarbonImpact, 1) * price)
+			}
+			g.density = math.Max(g.density, d)
+		}
+		if len(g.options) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].density > groups[j].density })
+	return groups
+}
+
+// availableCapacityMW is the capacity of building the largest option on
+// every site, the most any portfolio could reach whatever the budget.
+func availableCapacityMW(groups []siteOptions) float64 {
+	total := 0.0
+	for _, g := range groups {
+		largest := 0.0
+		for _, o := range g.options {
+			largest = math.Max(largest, o.CapacityMW)
+		}
+		total += largest
+	}
+	return total
+}
+
+// solvePortfolio runs a multiple-choice knapsack over the best candidate sites.
+// Capacity is tracked up to the target so the solver prefers portfolios that
+// meet it; among those it maximises the objective within the budget.
+func solvePortfolio(groups []siteOptions, req RecommendRequest) RecommendResponse {
+	resp := RecommendResponse{
+		Objective:        req.Objective,
+		Budget:           req.Budget,
+		TargetCapacityMW: req.TargetCapacityMW,
+		Sites:            []RecommendedSite{},
+		Alternatives:     []TradeOff{},
+	}
+
+	// Take the best sites, and more of them if that is what the target needs.
+	candidates := groups
+	if len(candidates) > maxCandidateSites {
+		n := maxCandidateSites
+		for covered := availableCapacityMW(groups[:n]); n < len(groups) && covered < req.TargetCapacityMW; n++ {
+			covered += availableCapacityMW(groups[n : n+1])
+		}
+		candidates = groups[:n]
+	}
+
+	// A capacity unit is never larger than the smallest option, so every
+	// option counts for at least one unit once rounded.
+	step := req.Budget / costBins
+	unitMW := math.Max(1, req.TargetCapacityMW/capacityUnits)
+	for _, g := range candidates {
+		for _, o := range g.options {
+			if o.CapacityMW > 0 {
+				unitMW = math.Min(unitMW, o.CapacityMW)
+			}
+		}
+	}
+	K := int(math.Ceil(req.TargetCapacityMW / unitMW))
+	width := K + 1
+	size := (costBins + 1) * width
+	idx := func(c, k int) int { return c*width + k }
+
+	negInf := math.Inf(-1)
+	dp := make([]float64, size)
+	for i := range dp {
+		dp[i] = negInf
+	}
+	dp[idx(0, 0)] = 0
+
+	// choice[g][state] is the option picked for group g (0 = none) and
+	// prevK[g][state] the capacity state it came from.
+	choice := make([][]int8, len(candidates))
+	prevK := make([][]int16, len(candidates))
+
+	for g, group := range candidates {
+		next := make([]float64, size)
+		copy(next, dp)
+		choice[g] = make([]int8, size)
+		prevK[g] = make([]int16, size)
+
+		for oi, o := range group.options {
+			cost := int(math.Ceil(o.Price / step))
+			capU := int(math.Round(o.CapacityMW / unitMW))
+			val := objectiveValue(o, req.Objective)
+			for c := 0; c+cost <= costBins; c++ {
+				for k := 0; k < width; k++ {
+					if dp[idx(c, k)] == negInf {
+						continue
+					}
+					nk := k + capU
+					if nk > K {
+						nk = K
+					}
+					ni := idx(c+cost, nk)
+					if v := dp[idx(c, k)] + val; v > next[ni] {
+						next[ni] = v
+						choice[g][ni] = int8(oi + 1)
+						prevK[g][ni] = int16(k)
+					}
+				}
+			}
+		}
+		dp = next
+	}
+
+	// Pick the best reachable state, preferring the highest capacity level.
+	bestC, bestK := -1, -1
+	for k := K; k >= 0 && bestC < 0; k-- {
+		for c := 0; c <= costBins; c++ {
+			if dp[idx(c, k)] == negInf {
+				continue
+			}
+			if bestC < 0 || dp[idx(c, k)] > dp[idx(bestC, k)] {
+				bestC, bestK = c, k
+			}
+		}
+	}
+
+	selected := make(map[int]bool)
+	c, k := bestC, bestK
+	for g := len(candidates) - 1; g >= 0 && c >= 0; g-- {
+		oi := choice[g][idx(c, k)]
+		if oi == 0 {
+			continue
+		}
+		o := candidates[g].options[oi-1]
+		resp.Sites = append(resp.Sites, o)
+		selected[o.SiteID] = true
+		pk := int(prevK[g][idx(c, k)])
+		c -= int(math.Ceil(o.Price / step))
+		k = pk
+	}
+	sort.Slice(resp.Sites, func(i, j int) bool { return resp.Sites[i].SiteID < resp.Sites[j].SiteID })
+
+	for _, s := range resp.Sites {
+		resp.TotalCost += s.Price
+		resp.TotalCapacityMW += s.CapacityMW
+		resp.TotalEcoScore += s.EcoScore
+		resp.TotalCarbon += s.CarbonImpact
+	}
+	resp.CapacityMet = resp.TotalCapacityMW >= req.TargetCapacityMW
+
+	resp.Alternatives = nextBestAlternatives(groups, selected, resp, req)
+	return resp
+}
+
+// nextBestAlternatives reports the marginal effect of bringing in the best
+// unselected sites, either by swapping out a recommended site or by adding them.
+func nextBestAlternatives(groups []siteOptions, selected map[int]bool, resp RecommendResponse, req RecommendRequest) []TradeOff {
+	var alts []TradeOff
+	for _, g := range groups {
+		if len(alts) >= maxAlternatives {
+			break
+		}
+		if selected[g.options[0].SiteID] {
+			continue
+		}
+
+		// Take the option of this site with the best objective value.
+		best := g.options[0]
+		for _, o := range g.options[1:] {
+			if objectiveValue(o, req.Objective) > objectiveValue(best, req.Objective) {
+				best = o
+			}
+		}
+
+		add := TradeOff{
+			RecommendedSite: best,
+			Action:          "add",
+			CostDelta:       best.Price,
+			CapacityDelta:   best.CapacityMW,
+			EcoScoreDelta:   best.EcoScore,
+			CarbonDelta:     best.CarbonImpact,
+			FitsBudget:      resp.TotalCost+best.Price <= req.Budget,
+		}
+		alt := add
+		if !add.FitsBudget {
+			// Find the swap that keeps the portfolio feasible and costs the least objective.
+			found := false
+			for _, s := range resp.Sites {
+				cost := resp.TotalCost - s.Price + best.Price
+				capacity := resp.TotalCapacityMW - s.CapacityMW + best.CapacityMW
+				if cost > req.Budget || (resp.CapacityMet && capacity < req.TargetCapacityMW) {
+					continue
+				}
+				swap := TradeOff{
+					RecommendedSite: best,
+					Action:          "swap",
+					Replaces:        s.SiteID,
+					CostDelta:       best.Price - s.Price,
+					CapacityDelta:   best.CapacityMW - s.CapacityMW,
+					EcoScoreDelta:   best.EcoScore - s.EcoScore,
+					CarbonDelta:     best.CarbonImpact - s.CarbonImpact,
+					FitsBudget:      true,
+				}
+				if !found || tradeOffValue(swap, req.Objective) > tradeOffValue(alt, req.Objective) {
+					alt = swap
+					found = true
+				}
+			}
+		}
+		alts = append(alts, alt)
+	}
+	return alts
+}
+
+// tradeOffValue is the change in the objective caused by a trade-off.
+func tradeOffValue(t TradeOff, objective string) float64 {
+	if objective == objectiveCarbon {
+		return -t.CarbonDelta
+	}
+	return float64(t.EcoScoreDelta)
+}
+
+// ScoringProfilesHandler handles GET /api/scoring-profiles
+func ScoringProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":   data.ActiveScoringProfile().Name,
+		"profiles": data.ListScoringProfiles(),
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+// tierGroups gives n sites one option each, alternating between the 14 and
+// 16 MW tier sizes, all at the same price.
+func tierGroups(n int) []siteOptions {
+	var groups []siteOptions
+	for i := 0; i < n; i++ {
+		capacity := 14.0
+		if i%2 == 1 {
+			capacity = 16
+		}
+		groups = append(groups, siteOptions{options: []RecommendedSite{{
+			SiteID:       i + 1,
+			Price:        1000000,
+			CapacityMW:   capacity,
+			EcoScore:     50,
+			CarbonImpact: 1000,
+		}}})
+	}
+	return groups
+}
+
+func TestSolvePortfolioMeetsLargeTargets(t *testing.T) {
+	// Targets whose hundredth part is larger than a site used to count
+	// every site as no capacity at all.
+	for _, target := range []float64{150, 1500, 1700} {
+		groups := tierGroups(120)
+		req := RecommendRequest{Budget: 200000000, TargetCapacityMW: target, Objective: objectiveCarbon}
+		resp := solvePortfolio(groups, req)
+		if !resp.CapacityMet || resp.TotalCapacityMW < target {
+			t.Errorf("target %.0f MW: got %.0f MW from %d sites", target, resp.TotalCapacityMW, len(resp.Sites))
+		}
+		// Meeting the target with the fewest sites is the lowest carbon.
+		if resp.TotalCapacityMW > target+16 {
+			t.Errorf("target %.0f MW: built %.0f MW", target, resp.TotalCapacityMW)
+		}
+	}
+}
+
+func TestSolvePortfolioFlagsShortfall(t *testing.T) {
+	// Only ten sites fit in the budget.
+	req := RecommendRequest{Budget: 10000000, TargetCapacityMW: 300, Objective: objectiveEcoScore}
+	resp := solvePortfolio(tierGroups(40), req)
+	if resp.CapacityMet || len(resp.Sites) != 10 {
+		t.Errorf("got %d sites, %.0f MW, capacity met %v", len(resp.Sites), resp.TotalCapacityMW, resp.CapacityMet)
+	}
+}
+
+func TestRecommendRejectsUnusableRequests(t *testing.T) {
+	t.Chdir("../..") // the site CSVs
+	for _, body := range []string{
+		`{"budget":50000000,"objective":"carbon"}`,
+		`{"budget":50000000,"target_capacity_mw":0,"objective":"carbon"}`,
+		`{"budget":50000000,"target_capacity_mw":1000000}`,
+	} {
+		w := serveJSON(RecommendPortfolioHandler, http.MethodPost, "/api/recommend", nil, body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d: %s", body, w.Code, w.Body)
+		}
+	}
+}
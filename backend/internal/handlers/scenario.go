@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/scenario"
+)
+
+// SaveScenarioRequest is the expected JSON payload for POST /api/scenarios.
+type SaveScenarioRequest struct {
+	Username string                   `json:"username"`
+	Name     string                   `json:"name"`
+	Sites    []scenario.SiteSelection `json:"sites"`
+}
+
+// ListScenariosHandler handles GET /api/scenarios
+func ListScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	list, err := scenario.List(username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading scenarios: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []scenario.Scenario{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// SaveScenarioHandler handles POST /api/scenarios
+func SaveScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req SaveScenarioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+
+	// Reject unknown sites or tiers up front rather than at compare time.
+	if _, err := resolveSites(req.Sites); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid scenario: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := scenario.Save(req.Username, scenario.Scenario{Name: req.Name, Sites: req.Sites}); err != nil {
+		http.Error(w, fmt.Sprintf("Error saving scenario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Scenario saved",
+	})
+}
+
+// DeleteScenarioHandler handles DELETE /api/scenarios?name=plan-b
+func DeleteScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name parameter is required", http.StatusBadRequest)
+		return
+	}
+	if err := scenario.Delete(username, name); err != nil {
+		http.Error(w, fmt.Sprintf("Error deleting scenario: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Scenario deleted",
+	})
+}
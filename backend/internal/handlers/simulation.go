@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"math"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
 	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
 )
 
 // Minimal constants for the demonstration.
@@ -37,7 +39,7 @@ type SimulationResponse struct {
 	TimeDatacentersRemoved int                 `json:"time_datacenters_removed"`
 }
 
-// GetUserClimateSimulationHandler handles GET /api/simulation?username=alice
+// GetUserClimateSimulationHandler handles GET /api/simulation
 func GetUserClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
 	addCORSHeaders(w)
 	if r.Method == http.MethodOptions {
@@ -49,9 +51,8 @@ func GetUserClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "Missing username query parameter", http.StatusBadRequest)
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
 		return
 	}
 
@@ -61,23 +62,62 @@ func GetUserClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
 		// If not found, use an empty cart with default money
 		userCart = &cart.Cart{
 			Username:  username,
-			Items:     []data.DatacenterLocation{},
-			MoneyLeft: 1000000,
+			Items:     []cart.CartItem{},
+			MoneyLeft: economy.StartingFunds(),
 		}
 	}
 
-	// 2. Calculate total data center contribution
-	dataCenterContribution := calcDataCenterContribution(userCart.Items)
+	// 2. Run the simulation for the cart
+	run := runClimateSimulation(userCart.Locations())
+	resp := SimulationResponse{
+		Username:               username,
+		WithDataCenters:        run.WithDataCenters,
+		WithoutDataCenters:     run.WithoutDataCenters,
+		TotalTimeToEnd:         run.TotalTimeToEnd,
+		TimeDatacentersRemoved: run.TotalTimeNoDC - run.TotalTimeToEnd,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// simulationRun holds the projections of one simulated portfolio.
+type simulationRun struct {
+	WithDataCenters    []ClimateProjection
+	WithoutDataCenters []ClimateProjection
+	TotalTimeToEnd     int
+	TotalTimeNoDC      int
+}
+
+// runClimateSimulation projects the climate from startYear to endYear with and
+// without the given data centers.
+func runClimateSimulation(items []data.DatacenterLocation) simulationRun {
+	var projections, baseline []ClimateProjection
+	run, _ := simulateClimate(context.Background(), items, func(withDC, withoutDC ClimateProjection) error {
+		projections = append(projections, withDC)
+		baseline = append(baseline, withoutDC)
+		return nil
+	})
+	run.WithDataCenters = projections
+	run.WithoutDataCenters = baseline
+	return run
+}
 
-	var (
-		projectionsWithDC    []ClimateProjection
-		projectionsWithoutDC []ClimateProjection
-		totalTimeToEnd       int
-		totalTimeNoDC        int
-	)
+// simulateClimate computes the projections year by year and hands each pair to
+// emit as soon as it is ready. It stops early if ctx is cancelled or emit fails;
+// the returned run only carries the threshold crossings.
+func simulateClimate(ctx context.Context, items []data.DatacenterLocation, emit func(withDC, withoutDC ClimateProjection) error) (simulationRun, error) {
+	// Calculate total data center contribution
+	dataCenterContribution := calcDataCenterContribution(items)
+
+	var run simulationRun
 
 	// Iterate over simulation years
 	for year := startYear; year <= endYear; year++ {
+		if err := ctx.Err(); err != nil {
+			return run, err
+		}
+
 		// Baseline from 1.2°C (2025) to 3.7°C (2100)
 		baselineTemp := getBaselineTemperature(year)
 
@@ -97,7 +137,6 @@ func GetUserClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
 			Survivability:          int(math.Round(surv)),
 			DegradationLevel:       degradation,
 		}
-		projectionsWithDC = append(projectionsWithDC, projDC)
 
 		// Without Data Centers scenario (baseline only)
 		noDCtemp := baselineTemp
@@ -112,36 +151,28 @@ func GetUserClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
 			Survivability:          int(math.Round(noDCsurv)),
 			DegradationLevel:       noDCdegradation,
 		}
-		projectionsWithoutDC = append(projectionsWithoutDC, projNoDC)
+		if err := emit(projDC, projNoDC); err != nil {
+			return run, err
+		}
 
 		// Determine threshold crossing for survivability for with-DC scenario.
-		if totalTimeToEnd == 0 && surv <= thresholdSurvivability {
-			totalTimeToEnd = year - startYear
+		if run.TotalTimeToEnd == 0 && surv <= thresholdSurvivability {
+			run.TotalTimeToEnd = year - startYear
 		}
 		// And for the without-DC scenario.
-		if totalTimeNoDC == 0 && noDCsurv <= thresholdSurvivability {
-			totalTimeNoDC = year - startYear
+		if run.TotalTimeNoDC == 0 && noDCsurv <= thresholdSurvivability {
+			run.TotalTimeNoDC = year - startYear
 		}
 	}
 
 	// If threshold never crossed, set to maximum simulation period.
-	if totalTimeToEnd == 0 {
-		totalTimeToEnd = endYear - startYear
+	if run.TotalTimeToEnd == 0 {
+		run.TotalTimeToEnd = endYear - startYear
 	}
-	if totalTimeNoDC == 0 {
-		totalTimeNoDC = endYear - startYear
+	if run.TotalTimeNoDC == 0 {
+		run.TotalTimeNoDC = endYear - startYear
 	}
-
-	resp := SimulationResponse{
-		Username:               username,
-		WithDataCenters:        projectionsWithDC,
-		WithoutDataCenters:     projectionsWithoutDC,
-		TotalTimeToEnd:         totalTimeToEnd,
-		TimeDatacentersRemoved: totalTimeNoDC - totalTimeToEnd,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	return run, nil
 }
 
 // ----------------------------------------------------------
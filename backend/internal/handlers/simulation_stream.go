@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+)
+
+// SimulationStreamRow is the payload of a "projection" event.
+type SimulationStreamRow struct {
+	Year               int               `json:"year"`
+	WithDataCenters    ClimateProjection `json:"with_data_centers"`
+	WithoutDataCenters ClimateProjection `json:"without_data_centers"`
+}
+
+// SimulationProgress is the payload of a "progress" event.
+type SimulationProgress struct {
+	Completed int     `json:"completed"`
+	Total     int     `json:"total"`
+	Percent   float64 `json:"percent"`
+}
+
+// SimulationSummary is the payload of the final "summary" event.
+type SimulationSummary struct {
+	Username               string `json:"username"`
+	TotalTimeToEnd         int    `json:"total_time_to_end"`
+	TimeDatacentersRemoved int    `json:"time_datacenters_removed"`
+}
+
+// StreamClimateSimulationHandler handles GET /api/simulation/stream
+//
+// It runs the same simulation as GetUserClimateSimulationHandler but sends it
+// as Server-Sent Events: a "start" event, then a "projection" and a "progress"
+// event per year, and finally a "summary" event. The run stops as soon as the
+// client disconnects.
+func StreamClimateSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, ok := actingUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	items := []data.DatacenterLocation{}
+	if userCart, ok := cart.GetCart(username); ok {
+		items = userCart.Locations()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, payload interface{}) error {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	total := endYear - startYear + 1
+	if err := send("start", map[string]interface{}{
+		"username":   username,
+		"start_year": startYear,
+		"end_year":   endYear,
+		"total":      total,
+	}); err != nil {
+		return
+	}
+
+	completed := 0
+	run, err := simulateClimate(r.Context(), items, func(withDC, withoutDC ClimateProjection) error {
+		completed++
+		if err := send("projection", SimulationStreamRow{
+			Year:               withDC.Year,
+			WithDataCenters:    withDC,
+			WithoutDataCenters: withoutDC,
+		}); err != nil {
+			return err
+		}
+		return send("progress", SimulationProgress{
+			Completed: completed,
+			Total:     total,
+			Percent:   100 * float64(completed) / float64(total),
+		})
+	})
+	if err != nil {
+		log.Printf("Simulation stream for %s stopped after %d/%d years: %v", username, completed, total, err)
+		return
+	}
+
+	send("summary", SimulationSummary{
+		Username:               username,
+		TotalTimeToEnd:         run.TotalTimeToEnd,
+		TimeDatacentersRemoved: run.TotalTimeNoDC - run.TotalTimeToEnd,
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/economy"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/stress"
+)
+
+// StressTestResponse is the overall response from the stress-test endpoint.
+type StressTestResponse struct {
+	Username string `json:"username"`
+	stress.Report
+}
+
+// GetStressTestHandler handles GET /api/stress-test?runs=500&seed=42
+func GetStressTestHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	username, ok := actingUser(w, r, q.Get("username"))
+	if !ok {
+		return
+	}
+	opts := stress.Options{
+		StartYear: startYear,
+		Years:     endYear - startYear + 1,
+	}
+	if raw := q.Get("runs"); raw != "" {
+		runs, err := strconv.Atoi(raw)
+		if err != nil || runs <= 0 {
+			http.Error(w, "Invalid runs value", http.StatusBadRequest)
+			return
+		}
+		opts.Runs = runs
+	}
+	if raw := q.Get("seed"); raw != "" {
+		seed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid seed value", http.StatusBadRequest)
+			return
+		}
+		opts.Seed = seed
+	}
+
+	var facilities []stress.Facility
+	if userCart, ok := cart.GetCart(username); ok {
+		for _, item := range userCart.Items {
+			facilities = append(facilities, stressFacility(item))
+		}
+	}
+
+	resp := StressTestResponse{Username: username, Report: stress.Run(facilities, opts)}
+	if resp.Facilities == nil {
+		resp.Facilities = []stress.FacilityReport{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// stressFacility values a cart item for the stress test at its purchase
+// price, with the revenue the economy model gives it in normal operation.
+func stressFacility(item cart.CartItem) stress.Facility {
+	_, ops := economy.FacilityOperations(&item.DatacenterLocation)
+	return stress.Facility{
+		Name:          item.Name,
+		Latitude:      item.Latitude,
+		Longitude:     item.Longitude,
+		AssetValue:    itemInvestment(item),
+		AnnualRevenue: ops.Revenue,
+	}
+}
+
+// itemTier returns the tier of a cart item; untiered items are standard facilities.
+func itemTier(item cart.CartItem) data.Tier {
+	tier, ok := data.GetTier(item.Tier)
+	if !ok {
+		tier, _ = data.GetTier(data.TierStandard)
+	}
+	return tier
+}
+
+// itemInvestment is what a cart item cost. Older items without a purchase
+// price are repriced, and unparseable land prices count as bare capex.
+func itemInvestment(item cart.CartItem) float64 {
+	if item.PurchasePrice > 0 {
+		return item.PurchasePrice
+	}
+	tier := itemTier(item)
+	quote, err := economy.QuoteSite(&item.DatacenterLocation, tier.ID)
+	if err != nil {
+		return tier.Capex
+	}
+	return quote.Total
+}
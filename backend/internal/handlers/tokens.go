@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+)
+
+// CreateTokenRequest is the expected JSON payload for POST /account/tokens.
+type CreateTokenRequest struct {
+	Name          string `json:"name"`
+	Scope         string `json:"scope"`           // "read" or "read-write"
+	ExpiresInDays int    `json:"expires_in_days"` // 1 to 365; 0 never expires
+}
+
+// ListTokensHandler handles GET /account/tokens. Tokens themselves are never
+// listed, only their descriptions.
+func ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"tokens": apitoken.List(account.Username),
+	})
+}
+
+// CreateTokenHandler handles POST /account/tokens. The response holds the
+// only copy of the token.
+func CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	// Checked in days, before converting: a large count would overflow the
+	// duration and come out as a token that never expires.
+	maxDays := int(apitoken.MaxLifetime / (24 * time.Hour))
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxDays {
+		http.Error(w, fmt.Sprintf("expires_in_days must be 1 to %d, or 0 for a token that doesn't expire", maxDays), http.StatusBadRequest)
+		return
+	}
+	token, t, err := apitoken.Create(account.Username, req.Name, req.Scope, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Copy the token now; it won't be shown again",
+		"token":   token,
+		"details": t,
+	})
+}
+
+// RevokeTokenHandler handles DELETE /account/tokens?id=tok_0123456789abcdef
+func RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if err := apitoken.Revoke(account.Username, id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Token %s revoked", id),
+	})
+}
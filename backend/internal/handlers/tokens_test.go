@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/apitoken"
+)
+
+func TestCreateTokenExpiry(t *testing.T) {
+	useTempStore(t)
+	cookie := loggedIn(t, "token_player")
+	create := RequireSession(CreateTokenHandler)
+	t.Cleanup(func() { apitoken.RevokeAll("token_player") })
+
+	for _, days := range []int{-1, 366, 1 << 40} {
+		body := fmt.Sprintf(`{"name": "ci", "scope": "read", "expires_in_days": %d}`, days)
+		if w := serveJSON(create, http.MethodPost, "/account/tokens", cookie, body); w.Code != http.StatusBadRequest {
+			t.Errorf("expires_in_days %d: got %d, want %d", days, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	for _, tc := range []struct {
+		body    string
+		expires time.Duration // 0 for never
+	}{
+		{`{"name": "ci", "scope": "read", "expires_in_days": 365}`, 365 * 24 * time.Hour},
+		{`{"name": "ci", "scope": "read", "expires_in_days": 1}`, 24 * time.Hour},
+		{`{"name": "ci", "scope": "read"}`, 0},
+	} {
+		w := serveJSON(create, http.MethodPost, "/account/tokens", cookie, tc.body)
+		if w.Code != http.StatusCreated {
+			t.Errorf("%s: got %d: %s", tc.body, w.Code, w.Body)
+			continue
+		}
+		var resp struct {
+			Details apitoken.Token `json:"details"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		switch got := resp.Details.ExpiresAt; {
+		case tc.expires == 0 && got != nil:
+			t.Errorf("%s: expires at %s, want never", tc.body, got)
+		case tc.expires != 0 && (got == nil || got.Sub(resp.Details.CreatedAt) != tc.expires):
+			t.Errorf("%s: expires at %v, want %s after creation", tc.body, got, tc.expires)
+		}
+	}
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	useTempStore(t)
+	cookie := loggedIn(t, "header_player")
+	t.Cleanup(func() { apitoken.RevokeAll("header_player") })
+	token, _, err := apitoken.Create("header_player", "ci", apitoken.ScopeRead, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whoami := RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		account, _ := CurrentUser(r)
+		fmt.Fprint(w, account.Username)
+	})
+
+	for _, tc := range []struct {
+		header string
+		want   int
+	}{
+		{"Bearer " + token, http.StatusOK},
+		{"bearer " + token, http.StatusOK},
+		{"BEARER  " + token, http.StatusOK},
+		{"Bearer " + token + "x", http.StatusUnauthorized},
+		{"Bearer" + token, http.StatusUnauthorized},
+		{"Bearer ", http.StatusUnauthorized},
+		{"Basic aGVhZGVyX3BsYXllcjpwdw==", http.StatusUnauthorized},
+		{token, http.StatusUnauthorized},
+	} {
+		// The session cookie is sent too: a bad header must not fall back to it.
+		r := httptest.NewRequest(http.MethodGet, "/cart", nil)
+		r.Header.Set("Authorization", tc.header)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		whoami(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%q: got %d, want %d: %s", tc.header, w.Code, tc.want, w.Body)
+		} else if tc.want == http.StatusOK && w.Body.String() != "header_player" {
+			t.Errorf("%q: authenticated as %q", tc.header, w.Body)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/cart"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+// UpgradeRequest is the expected JSON payload for POST /cart/upgrade. Tier is
+// the target tier of a tier upgrade and ignored otherwise.
+type UpgradeRequest struct {
+	Username string `json:"username"`
+	Index    int    `json:"index"` // position of the facility in the cart
+	Upgrade  string `json:"upgrade"`
+	Tier     string `json:"tier"`
+}
+
+// UpgradesHandler handles GET /api/upgrades.
+func UpgradesHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upgrades":             data.Upgrades,
+		"tier_upgrade_premium": data.TierUpgradePremium,
+	})
+}
+
+// OrderUpgradeHandler handles POST /cart/upgrade. In a running game the
+// upgrade takes effect after its installation delay; without a game it is
+// installed at once.
+func OrderUpgradeHandler(w http.ResponseWriter, r *http.Request) {
+	addCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req UpgradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	username, ok := actingUser(w, r, req.Username)
+	if !ok {
+		return
+	}
+	req.Username = username
+	if req.Upgrade == "" {
+		http.Error(w, "Upgrade is required", http.StatusBadRequest)
+		return
+	}
+
+	nextTick := 0
+	if g, ok := game.Get(req.Username); ok {
+		nextTick = g.Quarter + 1
+	}
+	item, quote, err := cart.OrderUpgrade(req.Username, req.Index, req.Upgrade, req.Tier, nextTick)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error ordering upgrade: %v", err), http.StatusBadRequest)
+		return
+	}
+	readyTick := 0
+	for _, p := range item.PendingUpgrades {
+		if p.Upgrade == quote.Upgrade {
+			readyTick = p.ReadyTick
+		}
+	}
+	moneyLeft := 0.0
+	if c, ok := cart.GetCart(req.Username); ok {
+		moneyLeft = c.MoneyLeft
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"item":       item,
+		"price":      quote,
+		"ready_tick": readyTick, // 0 when installed immediately
+		"money_left": moneyLeft,
+	})
+}
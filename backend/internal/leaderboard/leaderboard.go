@@ -0,0 +1,164 @@
+// Package leaderboard ranks players by the persisted results of their
+// finished games. Each player appears once, with their best result for the
+// chosen metric among the games that match the filter.
+package leaderboard
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/game"
+)
+
+// Ranking metrics.
+const (
+	MetricScore            = "score"
+	MetricEcoScore         = "eco_score"
+	MetricCarbonPerMW      = "carbon_per_mw"
+	MetricBudgetEfficiency = "budget_efficiency"
+)
+
+// metric describes how a ranking metric is read from a result.
+type metric struct {
+	value         func(r game.Result) (float64, bool)
+	lowerIsBetter bool
+}
+
+var metrics = map[string]metric{
+	MetricScore: {value: func(r game.Result) (float64, bool) {
+		return r.Score, true
+	}},
+	MetricEcoScore: {value: func(r game.Result) (float64, bool) {
+		return r.Outcome.EcoScore, r.Outcome.Facilities > 0
+	}},
+	// Tonnes of CO2e emitted over the game per MW of final capacity.
+	MetricCarbonPerMW: {value: func(r game.Result) (float64, bool) {
+		if r.Outcome.CapacityMW <= 0 {
+			return 0, false
+		}
+		return r.Outcome.CarbonTons / r.Outcome.CapacityMW, true
+	}, lowerIsBetter: true},
+	MetricBudgetEfficiency: {value: func(r game.Result) (float64, bool) {
+		return r.Outcome.BudgetEfficiency, true
+	}},
+}
+
+// Metrics returns the names of the ranking metrics.
+func Metrics() []string {
+	return []string{MetricScore, MetricEcoScore, MetricCarbonPerMW, MetricBudgetEfficiency}
+}
+
+// Filter selects the results a leaderboard is computed from. Empty fields
+// match every result; a seed selects players who played the same game.
+type Filter struct {
+	Scenario  string
+	Seed      uint64
+	Classroom string
+}
+
+func (f Filter) matches(r game.Result) bool {
+	if f.Scenario != "" && r.Scenario != f.Scenario {
+		return false
+	}
+	if f.Seed != 0 && r.Seed != f.Seed {
+		return false
+	}
+	if f.Classroom != "" && r.Classroom != f.Classroom {
+		return false
+	}
+	return true
+}
+
+// Entry is a player's place on a leaderboard.
+type Entry struct {
+	Rank      int     `json:"rank"` // 1-based; tied values share a rank
+	Username  string  `json:"username"`
+	Value     float64 `json:"value"` // the player's best value of the metric
+	GameID    string  `json:"game_id"`
+	Scenario  string  `json:"scenario"`
+	Classroom string  `json:"classroom,omitempty"`
+	Score     float64 `json:"score"`
+	RankName  string  `json:"rank_name"` // title earned by that game
+}
+
+// Compute ranks every player with a matching result by the metric.
+func Compute(metricName string, f Filter) ([]Entry, error) {
+	m, ok := metrics[metricName]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metricName)
+	}
+	better := func(a, b float64) bool {
+		if m.lowerIsBetter {
+			return a < b
+		}
+		return a > b
+	}
+
+	best := make(map[string]Entry)
+	for _, r := range game.AllResults() {
+		if !f.matches(r) {
+			continue
+		}
+		v, ok := m.value(r)
+		if !ok {
+			continue
+		}
+		if cur, seen := best[r.Username]; seen && !better(v, cur.Value) {
+			continue
+		}
+		best[r.Username] = Entry{
+			Username:  r.Username,
+			Value:     v,
+			GameID:    r.GameID,
+			Scenario:  r.Scenario,
+			Classroom: r.Classroom,
+			Score:     r.Score,
+			RankName:  r.Rank,
+		}
+	}
+
+	entries := make([]Entry, 0, len(best))
+	for _, e := range best {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return better(entries[i].Value, entries[j].Value)
+		}
+		return entries[i].Username < entries[j].Username
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Value == entries[i-1].Value {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries, nil
+}
+
+// Page returns the entries on a 1-based page of the given size.
+func Page(entries []Entry, page, size int) []Entry {
+	if page < 1 || size < 1 || page-1 > len(entries)/size {
+		return []Entry{}
+	}
+	start := (page - 1) * size
+	if start >= len(entries) {
+		return []Entry{}
+	}
+	end := start + size
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[start:end]
+}
+
+// Find returns the entry of a player, if they are on the leaderboard.
+func Find(entries []Entry, username string) (Entry, bool) {
+	for _, e := range entries {
+		if e.Username == username {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
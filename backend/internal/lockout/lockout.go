@@ -0,0 +1,110 @@
+// Package lockout slows down password guessing. Failed logins are counted
+// per key (an account or a client IP); once a key reaches the policy's
+// threshold it is locked, and every further failure doubles the lock.
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy is when and for how long a key is locked.
+type Policy struct {
+	Threshold int           // failures that lock the key the first time
+	BaseDelay time.Duration // length of the first lock
+	MaxDelay  time.Duration // locks never get longer than this
+	// Window is how long a key must go without failing, and without being
+	// locked, before its failures are forgotten.
+	Window time.Duration
+}
+
+// Default policies: accounts lock quickly, IPs, which may be shared by a
+// classroom behind one address, only after many failures.
+var (
+	AccountPolicy = Policy{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: time.Hour}
+	IPPolicy      = Policy{Threshold: 20, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: time.Hour}
+)
+
+// pruneAt is how many keys a tracker holds before it drops forgotten ones.
+const pruneAt = 10000
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Tracker counts failures for one kind of key.
+type Tracker struct {
+	policy  Policy
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// New returns a tracker that locks keys by the given policy.
+func New(p Policy) *Tracker {
+	return &Tracker{policy: p, entries: make(map[string]*entry)}
+}
+
+// forgottenNoLock reports whether a key's failures are old enough to drop.
+func (t *Tracker) forgottenNoLock(e *entry, now time.Time) bool {
+	return now.After(e.lockedUntil) && now.Sub(e.lastFailure) > t.policy.Window
+}
+
+// Locked reports whether a key is locked, and for how much longer.
+func (t *Tracker) Locked(key string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[key]
+	if !ok {
+		return 0, false
+	}
+	now := time.Now()
+	if now.Before(e.lockedUntil) {
+		return e.lockedUntil.Sub(now), true
+	}
+	return 0, false
+}
+
+// Fail records a failure and returns how long the key is now locked for,
+// zero if it isn't.
+func (t *Tracker) Fail(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if len(t.entries) >= pruneAt {
+		for k, e := range t.entries {
+			if t.forgottenNoLock(e, now) {
+				delete(t.entries, k)
+			}
+		}
+	}
+	e, ok := t.entries[key]
+	if !ok || t.forgottenNoLock(e, now) {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	over := e.failures - t.policy.Threshold
+	if over < 0 {
+		return 0
+	}
+	delay := t.policy.BaseDelay
+	for i := 0; i < over && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+	e.lockedUntil = now.Add(delay)
+	return delay
+}
+
+// Reset forgets a key's failures, after a successful login or a password
+// reset.
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+}
@@ -0,0 +1,109 @@
+// Package notify delivers messages to users, such as password reset tokens.
+// The server sends no email; a notifier writes messages somewhere an
+// operator can pass them on from, and another delivery channel can be added
+// by implementing Notifier.
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Notifier names accepted by Open.
+const (
+	NotifierLog  = "log"
+	NotifierFile = "file"
+)
+
+// Message is a notification for a user.
+type Message struct {
+	To      string    `json:"to"` // username
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(m Message) error
+}
+
+// LogNotifier writes messages to the server log.
+type LogNotifier struct{}
+
+// Notify logs the message.
+func (LogNotifier) Notify(m Message) error {
+	log.Printf("Notification for %s: %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// FileNotifier appends messages to a file as JSON lines. The file is only
+// readable by its owner, since messages can carry reset tokens.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Notify appends the message to the file.
+func (n *FileNotifier) Notify(m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Open returns a notifier by name. For the file notifier path is the file
+// messages are appended to.
+func Open(kind, path string) (Notifier, error) {
+	switch kind {
+	case NotifierLog, "":
+		return LogNotifier{}, nil
+	case NotifierFile:
+		if path == "" {
+			path = "notifications.log"
+		}
+		return &FileNotifier{Path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q", kind)
+}
+
+var (
+	current   Notifier = LogNotifier{}
+	currentMu sync.RWMutex
+)
+
+// Default returns the notifier messages are sent through. Until SetDefault
+// is called it logs them.
+func Default() Notifier {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetDefault replaces the notifier messages are sent through. Call it at
+// startup.
+func SetDefault(n Notifier) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = n
+}
+
+// Send stamps a message and delivers it through the default notifier.
+func Send(to, subject, body string) error {
+	return Default().Notify(Message{To: to, Subject: subject, Body: body, SentAt: time.Now().UTC()})
+}
@@ -0,0 +1,251 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthTTL is how long a user has to log in at the provider.
+const AuthTTL = 10 * time.Minute
+
+// maxPending bounds the logins in progress, so a flood of started logins
+// can't use up memory.
+const maxPending = 10000
+
+// pendingAuth is a login sent to a provider and not back yet. It lives only
+// in memory; a restart sends users round again.
+type pendingAuth struct {
+	provider     string
+	verifier     string // PKCE code verifier
+	nonce        string
+	linkUsername string // set when a logged-in user is linking an identity
+	expires      time.Time
+}
+
+var (
+	pending   = make(map[string]pendingAuth) // state -> login
+	pendingMu sync.Mutex
+)
+
+// Identity is a provider account, as its ID token describes it.
+type Identity struct {
+	Provider          string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// Result is a finished login: who the provider says the user is, and the
+// local user who asked to link the identity, if any.
+type Result struct {
+	Identity
+	LinkUsername string
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating login state: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Begin starts a login at a provider and returns the URL to send the
+// browser to and the state the provider will send back. linkUsername names
+// the logged-in user when an identity is being linked rather than used to
+// log in. loginHint, if set, is passed on to pre-fill the provider's login.
+func Begin(providerName, linkUsername, loginHint string) (authURL, state string, err error) {
+	p, ok := lookup(providerName)
+	if !ok {
+		return "", "", fmt.Errorf("unknown provider %q", providerName)
+	}
+	meta, err := p.discover()
+	if err != nil {
+		return "", "", err
+	}
+	var verifier, nonce string
+	for _, s := range []*string{&state, &verifier, &nonce} {
+		if *s, err = randomString(32); err != nil {
+			return "", "", err
+		}
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	scopes := []string{"openid"}
+	for _, s := range p.Scopes {
+		if !contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+	for key, values := range u.Query() {
+		if query.Get(key) == "" {
+			query[key] = values
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	now := time.Now()
+	if len(pending) >= maxPending {
+		for s, a := range pending {
+			if now.After(a.expires) {
+				delete(pending, s)
+			}
+		}
+		if len(pending) >= maxPending {
+			return "", "", fmt.Errorf("too many logins in progress; try again later")
+		}
+	}
+	pending[state] = pendingAuth{
+		provider:     p.Name,
+		verifier:     verifier,
+		nonce:        nonce,
+		linkUsername: linkUsername,
+		expires:      now.Add(AuthTTL),
+	}
+	return u.String(), state, nil
+}
+
+// Finish completes a login with the state and code the provider sent back:
+// it redeems the code for an ID token and verifies it. A state can only be
+// used once, whether or not the login succeeds.
+func Finish(state, code string) (Result, error) {
+	pendingMu.Lock()
+	auth, ok := pending[state]
+	delete(pending, state)
+	pendingMu.Unlock()
+	if !ok || time.Now().After(auth.expires) {
+		return Result{}, fmt.Errorf("login expired or was already used; start again")
+	}
+	if code == "" {
+		return Result{}, fmt.Errorf("provider sent no authorization code")
+	}
+	p, ok := lookup(auth.provider)
+	if !ok {
+		return Result{}, fmt.Errorf("provider %q is no longer configured", auth.provider)
+	}
+	rawIDToken, err := p.exchange(code, auth.verifier)
+	if err != nil {
+		return Result{}, err
+	}
+	c, err := p.verifyIDToken(rawIDToken, auth.nonce)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Identity: Identity{
+			Provider:          p.Name,
+			Subject:           c.Subject,
+			Email:             c.Email,
+			EmailVerified:     bool(c.EmailVerified),
+			PreferredUsername: c.PreferredUsername,
+			Name:              c.Name,
+		},
+		LinkUsername: auth.linkUsername,
+	}, nil
+}
+
+// exchange redeems an authorization code at the token endpoint and returns
+// the ID token. Confidential clients authenticate with HTTP Basic.
+func (p *provider) exchange(code, verifier string) (string, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.ClientID},
+	}
+	req, err := http.NewRequest(http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("redeeming code at %s: %v", p.Name, err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("redeeming code at %s: %s", p.Name, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("redeeming code at %s: %s %s", p.Name, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("redeeming code at %s: no ID token in the response", p.Name)
+	}
+	return body.IDToken, nil
+}
+
+// MayAutoRegister reports whether an identity that isn't linked yet may get
+// a new local user, and why not.
+func MayAutoRegister(id Identity) error {
+	p, ok := lookup(id.Provider)
+	if !ok || !p.AutoRegister {
+		return fmt.Errorf("no account is linked to this %s login; log in with your password and link it first", displayName(id.Provider))
+	}
+	if len(p.AllowedDomains) == 0 {
+		return nil
+	}
+	at := strings.LastIndex(id.Email, "@")
+	if !id.EmailVerified || at < 0 {
+		return fmt.Errorf("%s didn't share a verified email address", displayName(id.Provider))
+	}
+	domain := strings.ToLower(id.Email[at+1:])
+	for _, allowed := range p.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("accounts at %s can't register here", domain)
+}
+
+// displayName returns a provider's display name, or its name if it is gone.
+func displayName(name string) string {
+	if p, ok := lookup(name); ok {
+		return p.DisplayName
+	}
+	return name
+}
@@ -0,0 +1,239 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// keyRefreshInterval is how often an unknown key ID may make us fetch the
+// provider's keys again, which is how key rotation is picked up.
+const keyRefreshInterval = time.Minute
+
+// clockSkew is how far our clock and the provider's may disagree.
+const clockSkew = time.Minute
+
+// jwk is a JSON Web Key; only the fields for RSA and P-256 keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes a signing key. Keys of other types return nil.
+func (k jwk) publicKey() (interface{}, error) {
+	switch {
+	case k.Use != "" && k.Use != "sig":
+		return nil, nil
+	case k.Kty == "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC key is not on P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("bad key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// key returns the provider's signing key with the given ID, fetching the
+// keys again if it isn't known and they haven't been fetched recently. A
+// token without a key ID may use the provider's only key.
+func (p *provider) key(kid string) (interface{}, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.findKeyNoLock(kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysFetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching %s signing keys: %v", p.Name, err)
+	}
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s signing key %q: %v", p.Name, k.Kid, err)
+		}
+		if pub != nil {
+			keys[k.Kid] = pub
+		}
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+	if k, ok := p.findKeyNoLock(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *provider) findKeyNoLock(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// audience is the aud claim, which may be a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = many
+	return nil
+}
+
+// flexBool is a boolean claim some providers send as a string.
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	*f = s == "true"
+	return nil
+}
+
+// claims are the ID token claims the flow reads.
+type claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+}
+
+// verifyIDToken checks an ID token's signature and claims: it must come from
+// the provider, be meant for us, be current and carry the nonce we sent.
+func (p *provider) verifyIDToken(raw, nonce string) (claims, error) {
+	var c claims
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return c, fmt.Errorf("ID token is not a signed JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return c, fmt.Errorf("ID token header: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return c, fmt.Errorf("ID token signature: %v", err)
+	}
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return c, fmt.Errorf("ID token algorithm %q is not allowed", header.Alg)
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return c, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return c, fmt.Errorf("ID token signature is invalid")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 ||
+			!ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return c, fmt.Errorf("ID token signature is invalid")
+		}
+	default:
+		return c, fmt.Errorf("ID token signing key has an unsupported type")
+	}
+
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return c, fmt.Errorf("ID token claims: %v", err)
+	}
+	now := time.Now()
+	switch {
+	case c.Issuer != p.Issuer:
+		return c, fmt.Errorf("ID token issuer is %q, not %q", c.Issuer, p.Issuer)
+	case !contains(c.Audience, p.ClientID):
+		return c, fmt.Errorf("ID token is not meant for this client")
+	case len(c.Audience) > 1 && c.AuthorizedParty != p.ClientID:
+		return c, fmt.Errorf("ID token was issued to another client")
+	case c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(clockSkew)):
+		return c, fmt.Errorf("ID token has expired")
+	case time.Unix(c.IssuedAt, 0).After(now.Add(clockSkew)):
+		return c, fmt.Errorf("ID token was issued in the future")
+	case c.Nonce != nonce:
+		return c, fmt.Errorf("ID token nonce doesn't match")
+	case c.Subject == "":
+		return c, fmt.Errorf("ID token has no subject")
+	}
+	return c, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,145 @@
+package oidc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Link is an identity linked to a user, as its owner sees it.
+type Link struct {
+	Provider    string    `json:"provider"`
+	DisplayName string    `json:"display_name"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	LinkedAt    time.Time `json:"linked_at"`
+}
+
+type linkKey struct{ provider, subject string }
+
+var (
+	links   = make(map[linkKey]store.IdentityRecord)
+	linksMu sync.Mutex
+)
+
+// LoadLinks loads every identity link from the store. Links of users that
+// no longer exist are dropped; links to providers no longer configured are
+// kept, in case the provider comes back. Users must be loaded first.
+func LoadLinks() error {
+	stored, err := store.Default().LoadIdentities()
+	if err != nil {
+		return err
+	}
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	for _, rec := range stored {
+		if _, ok := user.UsernameFor(rec.UserID); !ok {
+			if err := store.Default().DeleteIdentity(rec.Provider, rec.Subject); err != nil {
+				return err
+			}
+			continue
+		}
+		links[linkKey{rec.Provider, rec.Subject}] = rec
+	}
+	return nil
+}
+
+// UserFor returns the user an identity is linked to.
+func UserFor(id Identity) (string, bool) {
+	linksMu.Lock()
+	rec, ok := links[linkKey{id.Provider, id.Subject}]
+	linksMu.Unlock()
+	if !ok {
+		return "", false
+	}
+	return user.UsernameFor(rec.UserID)
+}
+
+// LinkIdentity links an identity to a user. An identity belongs to one user,
+// and a user may link one account per provider.
+func LinkIdentity(username string, id Identity) error {
+	userID, err := user.StoreKey(username)
+	if err != nil {
+		return err
+	}
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	key := linkKey{id.Provider, id.Subject}
+	if rec, ok := links[key]; ok && rec.UserID != userID {
+		return fmt.Errorf("this %s account is already linked to another user", displayName(id.Provider))
+	}
+	for k, rec := range links {
+		if k.provider == id.Provider && k != key && rec.UserID == userID {
+			return fmt.Errorf("you already linked another %s account; unlink it first", displayName(id.Provider))
+		}
+	}
+	rec := store.IdentityRecord{
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		UserID:   userID,
+		Email:    id.Email,
+		LinkedAt: time.Now().UTC(),
+	}
+	if old, ok := links[key]; ok {
+		rec.LinkedAt = old.LinkedAt
+	}
+	if err := store.Default().SaveIdentity(rec); err != nil {
+		return err
+	}
+	links[key] = rec
+	return nil
+}
+
+// Links returns the identities linked to a user, oldest first.
+func Links(username string) []Link {
+	userID, _ := user.IDFor(username)
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	list := make([]Link, 0)
+	for _, rec := range links {
+		if userID != "" && rec.UserID == userID {
+			list = append(list, Link{
+				Provider:    rec.Provider,
+				DisplayName: displayName(rec.Provider),
+				Subject:     rec.Subject,
+				Email:       rec.Email,
+				LinkedAt:    rec.LinkedAt,
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LinkedAt.Before(list[j].LinkedAt) })
+	return list
+}
+
+// Unlink removes one of a user's identities. A user without a password
+// can't remove their last identity, or they couldn't log in at all.
+func Unlink(username, provider, subject string) error {
+	userID, _ := user.IDFor(username)
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	key := linkKey{provider, subject}
+	rec, ok := links[key]
+	if !ok || userID == "" || rec.UserID != userID {
+		return fmt.Errorf("no %s account %q is linked", provider, subject)
+	}
+	if !user.HasPassword(username) {
+		count := 0
+		for _, r := range links {
+			if r.UserID == userID {
+				count++
+			}
+		}
+		if count == 1 {
+			return fmt.Errorf("this is the only way you can log in; set a password before unlinking it")
+		}
+	}
+	if err := store.Default().DeleteIdentity(provider, subject); err != nil {
+		return err
+	}
+	delete(links, key)
+	return nil
+}
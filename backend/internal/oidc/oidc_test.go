@@ -0,0 +1,235 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/oidc/oidctest"
+)
+
+// useIssuer starts a mock provider and configures it as "school", the only
+// provider.
+func useIssuer(t *testing.T) *oidctest.Issuer {
+	t.Helper()
+	is, srv, err := oidctest.NewServer("ecology-map", "client-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	config, err := json.Marshal([]ProviderConfig{{
+		Name:         "school",
+		Issuer:       is.URL,
+		ClientID:     is.ClientID,
+		ClientSecret: is.ClientSecret,
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		Scopes:       []string{"email", "profile"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "providers.json")
+	if err := ioutil.WriteFile(filename, config, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadProviders(filename); err != nil {
+		t.Fatal(err)
+	}
+	return is
+}
+
+// authorize logs in at the provider as login, as a browser sent to authURL
+// would, and returns the state and code it sends back.
+func authorize(t *testing.T, authURL, login string) (state, code string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("login_hint", login)
+	u.RawQuery = q.Encode()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := resp.Location()
+	if err != nil {
+		t.Fatalf("provider didn't send the browser back: %s", resp.Status)
+	}
+	if e := back.Query().Get("error"); e != "" {
+		t.Fatalf("provider refused the login: %s", e)
+	}
+	return back.Query().Get("state"), back.Query().Get("code")
+}
+
+// login runs a login up to the provider's answer and returns the state and
+// code to finish it with.
+func login(t *testing.T, who string) (state, code string) {
+	t.Helper()
+	authURL, began, err := Begin("school", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code = authorize(t, authURL, who)
+	if state != began {
+		t.Fatalf("provider sent back state %q, want %q", state, began)
+	}
+	return state, code
+}
+
+func TestLoginRoundTrip(t *testing.T) {
+	useIssuer(t)
+	authURL, state, err := Begin("school", "some_player", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := mustQuery(t, authURL)
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("nonce") == "" {
+		t.Errorf("authorization request without PKCE or nonce: %s", authURL)
+	}
+	if q.Get("scope") != "openid email profile" {
+		t.Errorf("scope %q", q.Get("scope"))
+	}
+
+	_, code := authorize(t, authURL, "ada")
+	result, err := Finish(state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{
+		Provider:          "school",
+		Subject:           "mock-ada",
+		Email:             "ada@example.edu",
+		EmailVerified:     true,
+		PreferredUsername: "ada",
+		Name:              "ada",
+	}
+	if result.Identity != want || result.LinkUsername != "some_player" {
+		t.Errorf("got %+v", result)
+	}
+
+	if _, err := Finish(state, code); err == nil {
+		t.Error("a state was used twice")
+	}
+}
+
+func TestFinishChecksState(t *testing.T) {
+	useIssuer(t)
+	_, code := login(t, "ada")
+	if _, err := Finish("made-up-state", code); err == nil {
+		t.Error("finished a login that was never started")
+	}
+
+	// The provider only redeems a code with the verifier of the login it
+	// was issued for, so a code can't be carried over to another login.
+	first, _ := login(t, "ada")
+	_, second := login(t, "mallory")
+	if _, err := Finish(first, second); err == nil || !strings.Contains(err.Error(), "code_verifier") {
+		t.Errorf("finished a login with another login's code: %v", err)
+	}
+}
+
+// TestRejectedIDTokens has the provider send ID tokens it shouldn't and
+// checks that each ends the login.
+func TestRejectedIDTokens(t *testing.T) {
+	is := useIssuer(t)
+	for _, tc := range []struct {
+		name   string
+		claims func(map[string]interface{})
+		err    string
+	}{
+		{"nonce", func(c map[string]interface{}) { c["nonce"] = "another-login" }, "nonce"},
+		{"audience", func(c map[string]interface{}) { c["aud"] = "another-client" }, "not meant for this client"},
+		{"authorized party", func(c map[string]interface{}) {
+			c["aud"] = []string{is.ClientID, "another-client"}
+			c["azp"] = "another-client"
+		}, "issued to another client"},
+		{"issuer", func(c map[string]interface{}) { c["iss"] = "https://idp.example.com" }, "issuer"},
+		{"expired", func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-2 * clockSkew).Unix()
+		}, "expired"},
+		{"issued in the future", func(c map[string]interface{}) {
+			c["iat"] = time.Now().Add(2 * clockSkew).Unix()
+		}, "future"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is.Claims = tc.claims
+			defer func() { is.Claims = nil }()
+			state, code := login(t, "ada")
+			if _, err := Finish(state, code); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want one about %q", err, tc.err)
+			}
+		})
+	}
+
+	// The same provider accepts a good token again afterwards.
+	state, code := login(t, "ada")
+	if _, err := Finish(state, code); err != nil {
+		t.Errorf("a good token after the bad ones: %v", err)
+	}
+}
+
+func TestIDTokenSignature(t *testing.T) {
+	is := useIssuer(t)
+	p, _ := lookup("school")
+	good, err := is.IDToken("ada", "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(good, "n"); err != nil {
+		t.Fatalf("good token: %v", err)
+	}
+
+	// Claims changed after signing.
+	parts := strings.Split(good, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte(strings.Replace(string(payload), `"mock-ada"`, `"mock-admin"`, 1))
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if _, err := p.verifyIDToken(tampered, "n"); err == nil || !strings.Contains(err.Error(), "signature is invalid") {
+		t.Errorf("tampered token: %v", err)
+	}
+
+	// Signed by someone else's key under the provider's key ID.
+	other, err := oidctest.New(is.URL, is.ClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := other.IDToken("ada", "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedParts := strings.Split(forged, ".")
+	if _, err := p.verifyIDToken(parts[0]+"."+forgedParts[1]+"."+forgedParts[2], "n"); err == nil || !strings.Contains(err.Error(), "signature is invalid") {
+		t.Errorf("token signed with another key: %v", err)
+	}
+	if _, err := p.verifyIDToken(forged, "n"); err == nil {
+		t.Error("accepted a token signed with an unknown key")
+	}
+
+	// Algorithms other than the provider's are refused outright.
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	if _, err := p.verifyIDToken(none, "n"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("unsigned token: %v", err)
+	}
+}
+
+func mustQuery(t *testing.T, raw string) url.Values {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
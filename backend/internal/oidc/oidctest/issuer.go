@@ -0,0 +1,303 @@
+// Package oidctest is a minimal OpenID Connect provider for development and
+// tests, so the OIDC login can be tried without a real identity provider or
+// network access. It signs ID tokens with a throwaway RSA key and approves
+// every login: the login_hint parameter says who is logging in, or a form
+// asks if there is none. Don't expose it to anyone.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codeTTL is how long an authorization code can be redeemed.
+const codeTTL = time.Minute
+
+// Issuer is a mock provider. It is an http.Handler serving discovery, keys,
+// authorization and token endpoints under URL.
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string // if set, the token endpoint requires it
+	EmailDomain  string // users' email addresses are <login>@EmailDomain
+	// Claims, if set, may change an ID token's claims before it is signed,
+	// for trying out tokens a provider shouldn't send.
+	Claims func(claims map[string]interface{})
+
+	key   *rsa.PrivateKey
+	kid   string
+	mux   *http.ServeMux
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+// authCode is an authorization code not yet redeemed.
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	login       string
+	expires     time.Time
+}
+
+// New returns an issuer that will be served at issuerURL.
+func New(issuerURL, clientID, clientSecret string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid := make([]byte, 4)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	is := &Issuer{
+		URL:          strings.TrimSuffix(issuerURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		EmailDomain:  "example.edu",
+		key:          key,
+		kid:          hex.EncodeToString(kid),
+		mux:          http.NewServeMux(),
+		codes:        make(map[string]authCode),
+	}
+	is.mux.HandleFunc("/.well-known/openid-configuration", is.discovery)
+	is.mux.HandleFunc("/keys", is.keys)
+	is.mux.HandleFunc("/authorize", is.authorize)
+	is.mux.HandleFunc("/token", is.token)
+	return is, nil
+}
+
+// NewServer starts an issuer on a loopback port. Close the server when done.
+func NewServer(clientID, clientSecret string) (*Issuer, *httptest.Server, error) {
+	srv := httptest.NewUnstartedServer(nil)
+	is, err := New("http://"+srv.Listener.Addr().String(), clientID, clientSecret)
+	if err != nil {
+		srv.Close()
+		return nil, nil, err
+	}
+	srv.Config.Handler = is
+	srv.Start()
+	return is, srv, nil
+}
+
+func (is *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	is.mux.ServeHTTP(w, r)
+}
+
+func (is *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                is.URL,
+		"authorization_endpoint":                is.URL + "/authorize",
+		"token_endpoint":                        is.URL + "/token",
+		"jwks_uri":                              is.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (is *Issuer) keys(w http.ResponseWriter, r *http.Request) {
+	pub := is.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": is.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<title>Mock identity provider</title>
+<form method="get">
+{{range $k, $v := .}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">{{end}}{{end}}
+<label>Log in as <input name="login_hint" autofocus></label>
+<button>Log in</button>
+</form>
+`))
+
+// authorize approves a login as login_hint and sends the browser back with
+// a code, or shows a form asking who to log in as.
+func (is *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	switch {
+	case q.Get("client_id") != is.ClientID:
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	case redirectURI == "":
+		http.Error(w, "redirect_uri is required", http.StatusBadRequest)
+		return
+	}
+	back, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+	reply := back.Query()
+	reply.Set("state", q.Get("state"))
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" ||
+		!strings.Contains(" "+q.Get("scope")+" ", " openid ") {
+		reply.Set("error", "invalid_request")
+		back.RawQuery = reply.Encode()
+		http.Redirect(w, r, back.String(), http.StatusFound)
+		return
+	}
+	login := q.Get("login_hint")
+	if login == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginForm.Execute(w, q)
+		return
+	}
+
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	is.mu.Lock()
+	is.codes[hex.EncodeToString(code)] = authCode{
+		clientID:    is.ClientID,
+		redirectURI: redirectURI,
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		login:       login,
+		expires:     time.Now().Add(codeTTL),
+	}
+	is.mu.Unlock()
+	reply.Set("code", hex.EncodeToString(code))
+	back.RawQuery = reply.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token redeems a code, checking the client, redirect URI and PKCE verifier
+// the way a real provider does.
+func (is *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+	if is.ClientSecret != "" {
+		id, secret, ok := r.BasicAuth()
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		if !ok || id != is.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(is.ClientSecret)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	is.mu.Lock()
+	code, ok := is.codes[r.PostForm.Get("code")]
+	delete(is.codes, r.PostForm.Get("code"))
+	is.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(code.expires):
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	case r.PostForm.Get("client_id") != code.clientID:
+		tokenError(w, "invalid_grant", "code was issued to another client")
+		return
+	case r.PostForm.Get("redirect_uri") != code.redirectURI:
+		tokenError(w, "invalid_grant", "redirect_uri doesn't match")
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge:
+		tokenError(w, "invalid_grant", "code_verifier doesn't match")
+		return
+	}
+
+	idToken, err := is.IDToken(code.login, code.nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// IDToken returns a signed ID token for a login, as the token endpoint
+// issues it.
+func (is *Issuer) IDToken(login, nonce string) (string, error) {
+	now := time.Now()
+	email := login
+	if !strings.Contains(email, "@") {
+		email = login + "@" + is.EmailDomain
+	}
+	claims := map[string]interface{}{
+		"iss":                is.URL,
+		"sub":                "mock-" + login,
+		"aud":                is.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"email":              email,
+		"email_verified":     true,
+		"preferred_username": login,
+		"name":               login,
+	}
+	if is.Claims != nil {
+		is.Claims(claims)
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": is.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, is.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("Error writing response: %v\n", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package oidc logs users in through OpenID Connect providers, such as a
+// school's identity provider, with the authorization code flow and PKCE.
+// Providers are configured in a JSON file; their endpoints and signing keys
+// are discovered from the issuer the first time they are needed. Accounts at
+// a provider are linked to local users, who keep their carts, games and
+// roles whichever way they log in.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderConfig is one identity provider, as configured by the operator.
+type ProviderConfig struct {
+	// Name identifies the provider in URLs and identity links. Don't change
+	// it once users have linked accounts.
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Issuer      string `json:"issuer"`
+	ClientID    string `json:"client_id"`
+	// ClientSecret is empty for a public client, which relies on PKCE alone.
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is this server's /auth/oidc/callback, exactly as
+	// registered with the provider.
+	RedirectURL string   `json:"redirect_url"`
+	Scopes      []string `json:"scopes"` // "openid" is always requested
+	// AutoRegister creates a player for identities that aren't linked to a
+	// local user yet. Without it, users must log in with their password and
+	// link the identity first.
+	AutoRegister bool `json:"auto_register"`
+	// AllowedDomains, if set, limits auto-registration to identities with
+	// a verified email address at one of these domains.
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+// Info is what anyone may know about a provider, for showing login buttons.
+type Info struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// metadata is the part of a provider's discovery document the flow uses.
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// provider is a configured provider and what has been discovered about it.
+type provider struct {
+	ProviderConfig
+	mu          sync.Mutex
+	meta        *metadata
+	keys        map[string]interface{} // kid -> *rsa.PublicKey or *ecdsa.PublicKey
+	keysFetched time.Time
+}
+
+var (
+	providers = make(map[string]*provider)
+	order     []string // provider names in the order configured
+	mu        sync.RWMutex
+
+	returnURL string
+)
+
+// httpClient talks to the providers. Discovery, keys and token requests are
+// all small, so anything slow is a provider problem.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// maxResponseSize bounds what is read from a provider.
+const maxResponseSize = 1 << 20
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// LoadProviders reads the provider configuration file, a JSON array of
+// providers. A missing file means OIDC login is off.
+func LoadProviders(filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var configs []ProviderConfig
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return fmt.Errorf("parsing %s: %v", filename, err)
+	}
+	loaded := make(map[string]*provider)
+	var names []string
+	for _, c := range configs {
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("provider %q: %v", c.Name, err)
+		}
+		if loaded[c.Name] != nil {
+			return fmt.Errorf("provider %q is configured twice", c.Name)
+		}
+		if c.DisplayName == "" {
+			c.DisplayName = c.Name
+		}
+		loaded[c.Name] = &provider{ProviderConfig: c}
+		names = append(names, c.Name)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	providers, order = loaded, names
+	fmt.Printf("Loaded %d OIDC providers from %s\n", len(names), filename)
+	return nil
+}
+
+func (c ProviderConfig) validate() error {
+	if !namePattern.MatchString(c.Name) {
+		return fmt.Errorf("name must be 1 to 32 lowercase letters, digits or '-'")
+	}
+	if err := checkURL(c.Issuer); err != nil {
+		return fmt.Errorf("issuer: %v", err)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if u, err := url.Parse(c.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("redirect_url must be an absolute URL")
+	}
+	return nil
+}
+
+// checkURL requires https, except on the loopback interface so a local mock
+// provider can be used in development.
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", raw)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); u.Scheme == "http" && (host == "localhost" || (ip != nil && ip.IsLoopback())) {
+		return nil
+	}
+	return fmt.Errorf("%q must use https", raw)
+}
+
+// SetReturnURL sets where the browser is sent after an OIDC login, usually
+// the frontend. Without one the callback answers with JSON.
+func SetReturnURL(u string) {
+	mu.Lock()
+	defer mu.Unlock()
+	returnURL = u
+}
+
+// ReturnURL returns the URL set by SetReturnURL.
+func ReturnURL() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return returnURL
+}
+
+// Providers lists the configured providers.
+func Providers() []Info {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Info, 0, len(order))
+	for _, name := range order {
+		list = append(list, Info{Name: name, DisplayName: providers[name].DisplayName})
+	}
+	return list
+}
+
+// Get returns a provider's configuration.
+func Get(name string) (ProviderConfig, bool) {
+	p, ok := lookup(name)
+	if !ok {
+		return ProviderConfig{}, false
+	}
+	return p.ProviderConfig, true
+}
+
+func lookup(name string) (*provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// discover returns the provider's metadata, fetching it the first time.
+// Failures aren't cached, so a provider that was down is tried again.
+func (p *provider) discover() (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	var meta metadata
+	if err := getJSON(strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("discovering %s: %v", p.Name, err)
+	}
+	if meta.Issuer != p.Issuer {
+		return nil, fmt.Errorf("discovering %s: issuer is %q, not %q", p.Name, meta.Issuer, p.Issuer)
+	}
+	for _, endpoint := range []string{meta.AuthorizationEndpoint, meta.TokenEndpoint, meta.JWKSURI} {
+		if err := checkURL(endpoint); err != nil {
+			return nil, fmt.Errorf("discovering %s: %v", p.Name, err)
+		}
+	}
+	if len(meta.CodeChallengeMethods) > 0 && !contains(meta.CodeChallengeMethods, "S256") {
+		return nil, fmt.Errorf("discovering %s: provider doesn't support PKCE with S256", p.Name)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// getJSON fetches a JSON document from a provider.
+func getJSON(u string, v interface{}) error {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
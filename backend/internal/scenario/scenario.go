@@ -0,0 +1,194 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+var (
+	// scenarios holds the in-memory mapping from username to saved scenarios.
+	scenarios  = make(map[string][]Scenario)
+	scenarioMu sync.RWMutex
+)
+
+// SiteSelection picks a catalog site (by ID) and the tier to build on it.
+type SiteSelection struct {
+	SiteID int    `json:"site_id"`
+	Tier   string `json:"tier,omitempty"`
+}
+
+// Scenario is a named portfolio a player saved to compare against their cart.
+type Scenario struct {
+	Name      string          `json:"name"`
+	Sites     []SiteSelection `json:"sites"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Load loads every user's saved scenarios from the store when the app
+// starts. Users must be loaded first.
+func Load() error {
+	docs, err := store.Default().LoadScenarios()
+	if err != nil {
+		return err
+	}
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	for key, content := range docs {
+		username, ok := user.UsernameFor(key)
+		if !ok {
+			fmt.Printf("Skipping saved scenarios of unknown user %s\n", key)
+			continue
+		}
+		var list []Scenario
+		if err := json.Unmarshal(content, &list); err != nil {
+			fmt.Printf("Error unmarshaling saved scenarios for %s: %v\n", username, err)
+			continue
+		}
+		scenarios[username] = list
+	}
+	return nil
+}
+
+// ImportDir moves scenario files from the directory they were kept in before
+// they went through the store into the store, and returns how many users'
+// files it imported. Files are named after the username or the user ID;
+// users who already have scenarios in the store and unknown users are
+// skipped, so it is safe to run at every start. Call it after Load.
+func ImportDir(dir string) (int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	imported := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		username, ok := name, user.Exists(name)
+		if user.IsID(name) {
+			username, ok = user.UsernameFor(name)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := scenarios[username]; ok {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return imported, err
+		}
+		var list []Scenario
+		if err := json.Unmarshal(content, &list); err != nil {
+			return imported, fmt.Errorf("failed to parse scenario file %s: %v", path, err)
+		}
+		if err := saveNoLock(username, list); err != nil {
+			return imported, err
+		}
+		scenarios[username] = list
+		imported++
+	}
+	return imported, nil
+}
+
+// saveNoLock writes a user's scenarios to the store assuming the lock is held.
+func saveNoLock(username string, list []Scenario) error {
+	key, err := user.StoreKey(username)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Default().SaveScenarios(key, content)
+}
+
+// List returns all scenarios saved by a user.
+func List(username string) ([]Scenario, error) {
+	scenarioMu.RLock()
+	defer scenarioMu.RUnlock()
+	return scenarios[username], nil
+}
+
+// Get returns a single scenario by name.
+func Get(username, name string) (Scenario, bool, error) {
+	list, err := List(username)
+	if err != nil {
+		return Scenario{}, false, err
+	}
+	for _, s := range list {
+		if s.Name == name {
+			return s, true, nil
+		}
+	}
+	return Scenario{}, false, nil
+}
+
+// Save stores a scenario, replacing any existing scenario with the same name.
+func Save(username string, s Scenario) error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return fmt.Errorf("scenario name is required")
+	}
+	if len(s.Sites) == 0 {
+		return fmt.Errorf("scenario %q has no sites", s.Name)
+	}
+
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	list := scenarios[username]
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+
+	updated := make([]Scenario, 0, len(list)+1)
+	for _, existing := range list {
+		if existing.Name != s.Name {
+			updated = append(updated, existing)
+		}
+	}
+	updated = append(updated, s)
+	if err := saveNoLock(username, updated); err != nil {
+		return err
+	}
+	scenarios[username] = updated
+	return nil
+}
+
+// Delete removes a scenario by name.
+func Delete(username, name string) error {
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	list := scenarios[username]
+	updated := make([]Scenario, 0, len(list))
+	for _, existing := range list {
+		if existing.Name != name {
+			updated = append(updated, existing)
+		}
+	}
+	if len(updated) == len(list) {
+		return fmt.Errorf("scenario %q not found", name)
+	}
+	if err := saveNoLock(username, updated); err != nil {
+		return err
+	}
+	scenarios[username] = updated
+	return nil
+}
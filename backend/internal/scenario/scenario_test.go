@@ -0,0 +1,128 @@
+package scenario
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// forgetScenarios empties the in-memory scenarios, as a restart would.
+func forgetScenarios() {
+	scenarioMu.Lock()
+	defer scenarioMu.Unlock()
+	scenarios = make(map[string][]Scenario)
+}
+
+// addUser registers a user unless an earlier test did; users outlive a test.
+func addUser(t *testing.T, username string) {
+	t.Helper()
+	if user.Exists(username) {
+		return
+	}
+	if err := user.AddUser(username, ""); err != nil {
+		t.Fatalf("adding %s: %v", username, err)
+	}
+}
+
+func names(t *testing.T, username string) []string {
+	t.Helper()
+	list, err := List(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range list {
+		got = append(got, s.Name)
+	}
+	return got
+}
+
+func TestScenariosSurviveRestart(t *testing.T) {
+	for _, backend := range []string{store.BackendFile, store.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			path := t.TempDir()
+			if backend == store.BackendSQLite {
+				path = filepath.Join(path, "test.db")
+			}
+			st, err := store.Open(backend, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+			store.SetDefault(st)
+			forgetScenarios()
+			addUser(t, "planner")
+
+			for _, name := range []string{"coastal", "inland", "northern"} {
+				if err := Save("planner", Scenario{Name: name, Sites: []SiteSelection{{SiteID: 1}}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := Delete("planner", "inland"); err != nil {
+				t.Fatal(err)
+			}
+
+			forgetScenarios()
+			if err := Load(); err != nil {
+				t.Fatal(err)
+			}
+			if got := names(t, "planner"); len(got) != 2 || got[0] != "coastal" || got[1] != "northern" {
+				t.Errorf("after a restart: %v", got)
+			}
+			id, _ := user.IDFor("planner")
+			if docs, _ := st.LoadScenarios(); docs[id] == nil {
+				t.Error("scenarios are not stored under the user's ID")
+			}
+		})
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	store.SetDefault(store.NewFileStore(t.TempDir()))
+	forgetScenarios()
+	for _, username := range []string{"old_planner", "id_planner", "current_planner"} {
+		addUser(t, username)
+	}
+	if err := Save("current_planner", Scenario{Name: "kept", Sites: []SiteSelection{{SiteID: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file from before user IDs, one from after, one for a user already
+	// in the store and one for nobody.
+	legacy := t.TempDir()
+	id, _ := user.IDFor("id_planner")
+	for name, doc := range map[string]string{
+		"old_planner":     `[{"name":"by-name","sites":[{"site_id":1}]}]`,
+		id:                `[{"name":"by-id","sites":[{"site_id":2}]}]`,
+		"current_planner": `[{"name":"stale","sites":[{"site_id":3}]}]`,
+		"nobody_planner":  `[{"name":"orphan","sites":[{"site_id":4}]}]`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(legacy, name+".json"), []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imported, err := ImportDir(legacy)
+	if err != nil || imported != 2 {
+		t.Fatalf("imported %d (%v), want 2", imported, err)
+	}
+	forgetScenarios()
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	for username, want := range map[string]string{"old_planner": "by-name", "id_planner": "by-id", "current_planner": "kept"} {
+		if got := names(t, username); len(got) != 1 || got[0] != want {
+			t.Errorf("%s: %v, want [%s]", username, got, want)
+		}
+	}
+
+	if again, err := ImportDir(legacy); err != nil || again != 0 {
+		t.Errorf("second import: %d (%v)", again, err)
+	}
+	if n, err := ImportDir(filepath.Join(legacy, "missing")); err != nil || n != 0 {
+		t.Errorf("missing directory: %d (%v)", n, err)
+	}
+}
@@ -2,43 +2,307 @@ package session
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"log"
 	"sync"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// Default timeouts. A session ends after IdleTimeout without a request, or
+// AbsoluteTimeout after login however active it is.
+const (
+	DefaultIdleTimeout     = 2 * time.Hour
+	DefaultAbsoluteTimeout = 7 * 24 * time.Hour
 )
 
+// renewInterval is how stale LastSeen may get before a request renews it.
+// Renewing on every request would write to the store on every request.
+const renewInterval = time.Minute
+
+// Session is a logged-in user's session. Only a hash of the session ID is
+// kept, in memory and in the store, as for API tokens; the ID itself is
+// only in the user's cookie.
+type Session struct {
+	Hash      string // SHA-256 hash of the session ID
+	Username  string
+	CreatedAt time.Time
+	LastSeen  time.Time
+}
+
 var (
-	sessions = make(map[string]string) // sessionID -> username
-	mu       sync.RWMutex
+	sessions        = make(map[string]*Session) // hash of session ID -> session
+	mu              sync.RWMutex
+	idleTimeout     = DefaultIdleTimeout
+	absoluteTimeout = DefaultAbsoluteTimeout
+
+	// storeMu serialises writing sessions to the store, which is done
+	// outside mu so lookups never wait on the store. Taken before mu,
+	// never while holding it.
+	storeMu sync.Mutex
 )
 
-// GenerateSessionID creates a random 16-byte hex string
-func GenerateSessionID() string {
+// SetTimeouts sets the idle and absolute timeouts. Call it at startup.
+func SetTimeouts(idle, absolute time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	idleTimeout, absoluteTimeout = idle, absolute
+}
+
+// AbsoluteTimeout returns the longest a session can last, for cookie expiry.
+func AbsoluteTimeout() time.Duration {
+	mu.RLock()
+	defer mu.RUnlock()
+	return absoluteTimeout
+}
+
+// GenerateSessionID creates a random 16-byte hex string. It fails rather
+// than hand out a predictable ID when the system's random source does.
+func GenerateSessionID() (string, error) {
 	b := make([]byte, 16)
-	_, err := rand.Read(b)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating session ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// isPlainID reports whether a stored ID is a session ID stored before IDs
+// were hashed: 32 hex digits, as GenerateSessionID makes them.
+func isPlainID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// expiredNoLock reports whether a session has timed out at the given time.
+func expiredNoLock(s *Session, now time.Time) bool {
+	return now.Sub(s.LastSeen) > idleTimeout || now.Sub(s.CreatedAt) > absoluteTimeout
+}
+
+// record converts a session for the store, which keys users by ID.
+func record(s *Session) (store.SessionRecord, error) {
+	key, err := user.StoreKey(s.Username)
+	if err != nil {
+		return store.SessionRecord{}, err
+	}
+	return store.SessionRecord{ID: s.Hash, UserID: key, CreatedAt: s.CreatedAt, LastSeen: s.LastSeen}, nil
+}
+
+// LoadSessions loads the sessions kept by the store, so logins survive a
+// restart. Sessions that expired while the server was down, or whose user
+// no longer exists, are dropped. Sessions stored before IDs were hashed are
+// stored again under their hash. Users must be loaded first.
+func LoadSessions() error {
+	stored, err := store.Default().LoadSessions()
+	if err != nil {
+		return err
+	}
+	var drop []string
+	var rehashed []store.SessionRecord
+	now := time.Now()
+	mu.Lock()
+	for _, rec := range stored {
+		username, ok := user.UsernameFor(rec.UserID)
+		s := &Session{Hash: rec.ID, Username: username, CreatedAt: rec.CreatedAt, LastSeen: rec.LastSeen}
+		if !ok || expiredNoLock(s, now) || (len(rec.ID) != 2*sha256.Size && !isPlainID(rec.ID)) {
+			drop = append(drop, rec.ID)
+			continue
+		}
+		if isPlainID(rec.ID) {
+			drop = append(drop, rec.ID)
+			s.Hash = hashID(rec.ID)
+			rec.ID = s.Hash
+			rehashed = append(rehashed, rec)
+		}
+		sessions[s.Hash] = s
+	}
+	mu.Unlock()
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	for _, rec := range rehashed {
+		if err := store.Default().SaveSession(rec); err != nil {
+			return err
+		}
+	}
+	return store.Default().DeleteSessions(drop...)
+}
+
+// Create starts a new session for a user and returns its ID.
+func Create(username string) (string, error) {
+	id, err := GenerateSessionID()
 	if err != nil {
-		return "fallback-session-id"
+		return "", err
+	}
+	if err := SetUserForSession(id, username); err != nil {
+		return "", err
 	}
-	return hex.EncodeToString(b)
+	return id, nil
 }
 
 // SetUserForSession sets the mapping sessionID -> username
-func SetUserForSession(sessionID, username string) {
+func SetUserForSession(sessionID, username string) error {
+	now := time.Now().UTC()
+	s := &Session{Hash: hashID(sessionID), Username: username, CreatedAt: now, LastSeen: now}
+	rec, err := record(s)
+	if err != nil {
+		return err
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if err := store.Default().SaveSession(rec); err != nil {
+		return err
+	}
 	mu.Lock()
-	defer mu.Unlock()
-	sessions[sessionID] = username
+	sessions[s.Hash] = s
+	mu.Unlock()
+	return nil
 }
 
-// GetUserForSession retrieves the username for a given sessionID
+// GetUserForSession retrieves the username for a given sessionID. Each call
+// counts as activity and slides the idle timeout forward; expired sessions
+// are not found.
 func GetUserForSession(sessionID string) (string, bool) {
-	mu.RLock()
-	defer mu.RUnlock()
-	username, ok := sessions[sessionID]
-	return username, ok
+	hash := hashID(sessionID)
+	now := time.Now().UTC()
+	mu.Lock()
+	s, ok := sessions[hash]
+	if !ok {
+		mu.Unlock()
+		return "", false
+	}
+	if expiredNoLock(s, now) {
+		delete(sessions, hash)
+		mu.Unlock()
+		if err := deleteStored(hash); err != nil {
+			log.Printf("Error deleting expired session: %v", err)
+		}
+		return "", false
+	}
+	username := s.Username
+	renew := now.Sub(s.LastSeen) >= renewInterval
+	if renew {
+		s.LastSeen = now
+	}
+	mu.Unlock()
+	if renew {
+		renewStored(hash)
+	}
+	return username, true
 }
 
-// ClearSession removes a session from the map
-func ClearSession(sessionID string) {
+// ClearSession removes a session from the map and the store
+func ClearSession(sessionID string) error {
+	hash := hashID(sessionID)
 	mu.Lock()
-	defer mu.Unlock()
-	delete(sessions, sessionID)
+	delete(sessions, hash)
+	mu.Unlock()
+	return deleteStored(hash)
+}
+
+// ClearUserSessions ends every session of a user, logging them out on all
+// devices, and returns how many there were.
+func ClearUserSessions(username string) (int, error) {
+	return ClearOtherSessions(username, "")
+}
+
+// ClearOtherSessions ends every session of a user except the one with ID
+// keep, logging them out everywhere else, and returns how many there were.
+func ClearOtherSessions(username, keep string) (int, error) {
+	keepHash := ""
+	if keep != "" {
+		keepHash = hashID(keep)
+	}
+	return removeWhere(func(hash string, s *Session) bool {
+		return s.Username == username && hash != keepHash
+	})
+}
+
+// Sweep removes every expired session and returns how many there were.
+func Sweep() (int, error) {
+	now := time.Now()
+	return removeWhere(func(_ string, s *Session) bool {
+		return expiredNoLock(s, now)
+	})
+}
+
+// removeWhere ends the sessions a predicate picks, with one store write,
+// and returns how many there were. The predicate runs under mu.
+func removeWhere(pick func(hash string, s *Session) bool) (int, error) {
+	var hashes []string
+	mu.Lock()
+	for hash, s := range sessions {
+		if pick(hash, s) {
+			delete(sessions, hash)
+			hashes = append(hashes, hash)
+		}
+	}
+	mu.Unlock()
+	return len(hashes), deleteStored(hashes...)
+}
+
+// renewStored writes a session's renewed LastSeen to the store. The session
+// is read again under storeMu, so a renewal never brings back a session
+// ended in the meantime, nor overwrites a later renewal.
+func renewStored(hash string) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	mu.RLock()
+	s, ok := sessions[hash]
+	var rec store.SessionRecord
+	var err error
+	if ok {
+		rec, err = record(s)
+	}
+	mu.RUnlock()
+	if !ok || err != nil {
+		return
+	}
+	if err := store.Default().SaveSession(rec); err != nil {
+		log.Printf("Error renewing session: %v", err)
+	}
+}
+
+// deleteStored removes sessions, by hash, from the store.
+func deleteStored(hashes ...string) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return store.Default().DeleteSessions(hashes...)
+}
+
+// StartSweeper sweeps expired sessions once per interval until the returned
+// stop function is called.
+func StartSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := Sweep(); err != nil {
+					log.Printf("Session sweep failed: %v", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
 }
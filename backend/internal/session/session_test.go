@@ -0,0 +1,257 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/user"
+)
+
+// useTempStore gives a test an empty store of its own and forgets the
+// sessions of earlier tests.
+func useTempStore(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	store.SetDefault(store.NewFileStore(dir))
+	forgetSessions()
+	return dir
+}
+
+// forgetSessions empties the in-memory sessions, as a restart would.
+func forgetSessions() {
+	mu.Lock()
+	defer mu.Unlock()
+	sessions = make(map[string]*Session)
+}
+
+// addUser registers a user unless an earlier test did; users outlive a test.
+func addUser(t *testing.T, username string) {
+	t.Helper()
+	if user.Exists(username) {
+		return
+	}
+	if err := user.AddUser(username, ""); err != nil {
+		t.Fatalf("adding %s: %v", username, err)
+	}
+}
+
+// age moves a session's times back, as if it was created and last used
+// that long ago.
+func age(id string, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := sessions[hashID(id)]
+	s.CreatedAt = s.CreatedAt.Add(-d)
+	s.LastSeen = s.LastSeen.Add(-d)
+}
+
+// countingStore counts the session deletions that reach the store.
+type countingStore struct {
+	store.Store
+	mu      sync.Mutex
+	deletes int
+}
+
+func (s *countingStore) DeleteSessions(ids ...string) error {
+	s.mu.Lock()
+	s.deletes++
+	s.mu.Unlock()
+	return s.Store.DeleteSessions(ids...)
+}
+
+func TestStoresOnlyHashes(t *testing.T) {
+	dir := useTempStore(t)
+	addUser(t, "hashed_player")
+	id, err := Create("hashed_player")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := store.Default().LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != hashID(id) {
+		t.Fatalf("stored %+v, want one session under the hash of %s", stored, id)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "sessions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), id) {
+		t.Error("the session ID itself is in the store")
+	}
+
+	forgetSessions()
+	if err := LoadSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if username, ok := GetUserForSession(id); !ok || username != "hashed_player" {
+		t.Errorf("after a restart: got %q, %v", username, ok)
+	}
+	if _, ok := GetUserForSession(hashID(id)); ok {
+		t.Error("the stored hash works as a session ID")
+	}
+}
+
+func TestLoadRehashesPlainSessions(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "legacy_session_player")
+	key, err := user.StoreKey("legacy_session_player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := GenerateSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for _, rec := range []store.SessionRecord{
+		{ID: plain, UserID: key, CreatedAt: now, LastSeen: now},
+		{ID: "not-a-session", UserID: key, CreatedAt: now, LastSeen: now},
+		{ID: hashID("expired"), UserID: key, CreatedAt: now.Add(-DefaultAbsoluteTimeout - time.Hour), LastSeen: now},
+	} {
+		if err := store.Default().SaveSession(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if username, ok := GetUserForSession(plain); !ok || username != "legacy_session_player" {
+		t.Errorf("session stored before hashing: got %q, %v", username, ok)
+	}
+	stored, err := store.Default().LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != hashID(plain) {
+		t.Errorf("stored after loading: %+v", stored)
+	}
+}
+
+func TestSweepDeletesInOneWrite(t *testing.T) {
+	useTempStore(t)
+	counting := &countingStore{Store: store.Default()}
+	store.SetDefault(counting)
+	addUser(t, "sweep_player")
+
+	var stale []string
+	for i := 0; i < 5; i++ {
+		id, err := Create("sweep_player")
+		if err != nil {
+			t.Fatal(err)
+		}
+		age(id, DefaultIdleTimeout+time.Minute)
+		stale = append(stale, id)
+	}
+	live, err := Create("sweep_player")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(stale) {
+		t.Errorf("swept %d sessions, want %d", n, len(stale))
+	}
+	if counting.deletes != 1 {
+		t.Errorf("sweep wrote to the store %d times, want 1", counting.deletes)
+	}
+	for _, id := range stale {
+		if _, ok := GetUserForSession(id); ok {
+			t.Errorf("expired session %s still works", id)
+		}
+	}
+	if _, ok := GetUserForSession(live); !ok {
+		t.Error("the live session was swept")
+	}
+	if stored, _ := store.Default().LoadSessions(); len(stored) != 1 {
+		t.Errorf("%d sessions left in the store, want 1", len(stored))
+	}
+}
+
+func TestClearOtherSessions(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "many_devices")
+	addUser(t, "other_player")
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := Create("many_devices")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	other, err := Create("other_player")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ClearOtherSessions("many_devices", ids[0])
+	if err != nil || n != 2 {
+		t.Fatalf("cleared %d sessions (%v), want 2", n, err)
+	}
+	if _, ok := GetUserForSession(ids[0]); !ok {
+		t.Error("the kept session was ended")
+	}
+	for _, id := range ids[1:] {
+		if _, ok := GetUserForSession(id); ok {
+			t.Error("another session of the user still works")
+		}
+	}
+	if _, ok := GetUserForSession(other); !ok {
+		t.Error("another user's session was ended")
+	}
+
+	forgetSessions()
+	if err := LoadSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetUserForSession(ids[1]); ok {
+		t.Error("an ended session came back after a restart")
+	}
+}
+
+// TestConcurrentUse is meant for go test -race: lookups, renewals, logins
+// and logouts at once, with no ended session coming back from the store.
+func TestConcurrentUse(t *testing.T) {
+	useTempStore(t)
+	addUser(t, "busy_player")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				id, err := Create("busy_player")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				age(id, renewInterval)
+				if _, ok := GetUserForSession(id); !ok {
+					t.Error("a new session was not found")
+				}
+				if err := ClearSession(id); err != nil {
+					t.Error(err)
+				}
+				if _, err := Sweep(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stored, _ := store.Default().LoadSessions(); len(stored) != 0 {
+		t.Errorf("%d ended sessions left in the store", len(stored))
+	}
+}
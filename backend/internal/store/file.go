@@ -0,0 +1,604 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore keeps the historical flat-file layout under a data directory:
+// users.txt with "id:username:hash[:role]" lines, carts/<id>.cart, append-only
+// carts/<id>.ledger and carts/<id>.journal files, games/<id>.json,
+// results/<id>.json and scenarios/<id>.json, where <id> is the user ID,
+// sessions.json, tokens.json, identities.json and classrooms/<name>.json.
+//
+// Documents are replaced atomically (temp file, fsync, rename) and appends
+// are fsynced, so a crash leaves either the old or the new version of a
+// document and at most a torn last line in an append-only file.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex // serialises appends to users.txt, ledgers and journals
+}
+
+// tempMarker is part of the name of every temp file writeDoc creates.
+const tempMarker = ".tmp-"
+
+// NewFileStore returns a file store rooted at dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) usersPath() string    { return filepath.Join(s.dir, "users.txt") }
+func (s *FileStore) cartDir() string      { return filepath.Join(s.dir, "carts") }
+func (s *FileStore) gameDir() string      { return filepath.Join(s.dir, "games") }
+func (s *FileStore) resultDir() string    { return filepath.Join(s.dir, "results") }
+func (s *FileStore) scenarioDir() string  { return filepath.Join(s.dir, "scenarios") }
+func (s *FileStore) classroomDir() string { return filepath.Join(s.dir, "classrooms") }
+
+// LoadUsers reads users.txt; a missing file means no users yet. Lines from
+// before user IDs existed have the form "username:hash" and load without an
+// ID.
+func (s *FileStore) LoadUsers() ([]UserRecord, error) {
+	lines, err := readLines(s.usersPath())
+	if err != nil {
+		return nil, err
+	}
+	var users []UserRecord
+	for _, line := range lines {
+		parts := strings.Split(string(line), ":")
+		switch len(parts) {
+		case 2:
+			users = append(users, UserRecord{Username: parts[0], PasswordHash: parts[1]})
+		case 3:
+			users = append(users, UserRecord{ID: parts[0], Username: parts[1], PasswordHash: parts[2]})
+		case 4:
+			users = append(users, UserRecord{ID: parts[0], Username: parts[1], PasswordHash: parts[2], Role: parts[3]})
+		}
+	}
+	return users, nil
+}
+
+// AddUser appends a user to users.txt.
+func (s *FileStore) AddUser(u UserRecord) error {
+	line, err := userLine(u)
+	if err != nil {
+		return err
+	}
+	return s.appendLine(s.usersPath(), line)
+}
+
+// UpdateUser rewrites users.txt with the user's record replaced.
+func (s *FileStore) UpdateUser(u UserRecord) error {
+	if _, err := userLine(u); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.LoadUsers()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	found := false
+	for _, existing := range users {
+		if existing.Username == u.Username {
+			existing, found = u, true
+		}
+		line, err := userLine(existing)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if !found {
+		return fmt.Errorf("user %s not found", u.Username)
+	}
+	return s.writeDoc(s.dir, "users.txt", buf.Bytes())
+}
+
+// userLine formats a users.txt line, refusing fields that would break it.
+func userLine(u UserRecord) ([]byte, error) {
+	for _, field := range []string{u.ID, u.Username, u.PasswordHash, u.Role} {
+		if strings.ContainsAny(field, ":\n") {
+			return nil, fmt.Errorf("user record for %q contains ':' or a newline", u.Username)
+		}
+	}
+	switch {
+	case u.ID == "" && u.Role == "":
+		return []byte(fmt.Sprintf("%s:%s", u.Username, u.PasswordHash)), nil
+	case u.ID == "":
+		return nil, fmt.Errorf("user record for %q has a role but no ID", u.Username)
+	case u.Role == "":
+		return []byte(fmt.Sprintf("%s:%s:%s", u.ID, u.Username, u.PasswordHash)), nil
+	}
+	return []byte(fmt.Sprintf("%s:%s:%s:%s", u.ID, u.Username, u.PasswordHash, u.Role)), nil
+}
+
+// LoadSessions reads sessions.json; a missing file means no sessions.
+func (s *FileStore) LoadSessions() ([]SessionRecord, error) {
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "sessions.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []SessionRecord
+	if err := json.Unmarshal(content, &sessions); err != nil {
+		return nil, fmt.Errorf("parsing sessions.json: %v", err)
+	}
+	return sessions, nil
+}
+
+// SaveSession rewrites sessions.json with the session added or replaced.
+func (s *FileStore) SaveSession(session SessionRecord) error {
+	return s.updateSessions(func(sessions []SessionRecord) []SessionRecord {
+		for i := range sessions {
+			if sessions[i].ID == session.ID {
+				sessions[i] = session
+				return sessions
+			}
+		}
+		return append(sessions, session)
+	})
+}
+
+// DeleteSessions rewrites sessions.json once without the sessions.
+func (s *FileStore) DeleteSessions(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	gone := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	return s.updateSessions(func(sessions []SessionRecord) []SessionRecord {
+		kept := sessions[:0]
+		for _, session := range sessions {
+			if !gone[session.ID] {
+				kept = append(kept, session)
+			}
+		}
+		return kept
+	})
+}
+
+// updateSessions applies a change to sessions.json and writes it back.
+func (s *FileStore) updateSessions(change func([]SessionRecord) []SessionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions, err := s.LoadSessions()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(change(sessions), "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.writeDoc(s.dir, "sessions.json", content)
+}
+
+// LoadTokens reads tokens.json; a missing file means no tokens.
+func (s *FileStore) LoadTokens() ([]TokenRecord, error) {
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "tokens.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var tokens []TokenRecord
+	if err := json.Unmarshal(content, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing tokens.json: %v", err)
+	}
+	return tokens, nil
+}
+
+// SaveToken rewrites tokens.json with the token added or replaced.
+func (s *FileStore) SaveToken(token TokenRecord) error {
+	return s.updateTokens(func(tokens []TokenRecord) []TokenRecord {
+		for i := range tokens {
+			if tokens[i].ID == token.ID {
+				tokens[i] = token
+				return tokens
+			}
+		}
+		return append(tokens, token)
+	})
+}
+
+// DeleteToken rewrites tokens.json without the token.
+func (s *FileStore) DeleteToken(id string) error {
+	return s.updateTokens(func(tokens []TokenRecord) []TokenRecord {
+		kept := tokens[:0]
+		for _, token := range tokens {
+			if token.ID != id {
+				kept = append(kept, token)
+			}
+		}
+		return kept
+	})
+}
+
+// updateTokens applies a change to tokens.json and writes it back.
+func (s *FileStore) updateTokens(change func([]TokenRecord) []TokenRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.LoadTokens()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(change(tokens), "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.writeDoc(s.dir, "tokens.json", content)
+}
+
+// LoadIdentities reads identities.json; a missing file means no links.
+func (s *FileStore) LoadIdentities() ([]IdentityRecord, error) {
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "identities.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var identities []IdentityRecord
+	if err := json.Unmarshal(content, &identities); err != nil {
+		return nil, fmt.Errorf("parsing identities.json: %v", err)
+	}
+	return identities, nil
+}
+
+// SaveIdentity rewrites identities.json with the link added or replaced.
+func (s *FileStore) SaveIdentity(identity IdentityRecord) error {
+	return s.updateIdentities(func(identities []IdentityRecord) []IdentityRecord {
+		for i := range identities {
+			if identities[i].Provider == identity.Provider && identities[i].Subject == identity.Subject {
+				identities[i] = identity
+				return identities
+			}
+		}
+		return append(identities, identity)
+	})
+}
+
+// DeleteIdentity rewrites identities.json without the link.
+func (s *FileStore) DeleteIdentity(provider, subject string) error {
+	return s.updateIdentities(func(identities []IdentityRecord) []IdentityRecord {
+		kept := identities[:0]
+		for _, identity := range identities {
+			if identity.Provider != provider || identity.Subject != subject {
+				kept = append(kept, identity)
+			}
+		}
+		return kept
+	})
+}
+
+// updateIdentities applies a change to identities.json and writes it back.
+func (s *FileStore) updateIdentities(change func([]IdentityRecord) []IdentityRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identities, err := s.LoadIdentities()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(change(identities), "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.writeDoc(s.dir, "identities.json", content)
+}
+
+// LoadCarts reads every carts/<id>.cart file.
+func (s *FileStore) LoadCarts() (map[string][]byte, error) {
+	return s.loadDir(s.cartDir(), ".cart")
+}
+
+// SaveCart writes carts/<id>.cart.
+func (s *FileStore) SaveCart(userID string, doc []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.writeDoc(s.cartDir(), userID+".cart", doc)
+}
+
+// LoadLedger reads carts/<id>.ledger; a missing file is an empty ledger.
+func (s *FileStore) LoadLedger(userID string) ([][]byte, error) {
+	if err := checkKey(userID); err != nil {
+		return nil, err
+	}
+	return readLines(filepath.Join(s.cartDir(), userID+".ledger"))
+}
+
+// AppendLedger appends an entry as a line to carts/<id>.ledger.
+func (s *FileStore) AppendLedger(userID string, entry []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.appendLine(filepath.Join(s.cartDir(), userID+".ledger"), entry)
+}
+
+// LoadJournal reads carts/<id>.journal; a missing file is an empty journal.
+func (s *FileStore) LoadJournal(userID string) ([][]byte, error) {
+	if err := checkKey(userID); err != nil {
+		return nil, err
+	}
+	return readLines(filepath.Join(s.cartDir(), userID+".journal"))
+}
+
+// AppendJournal appends an entry as a line to carts/<id>.journal.
+func (s *FileStore) AppendJournal(userID string, entry []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.appendLine(filepath.Join(s.cartDir(), userID+".journal"), entry)
+}
+
+// JournalUsers lists the users with a carts/<id>.journal file.
+func (s *FileStore) JournalUsers() ([]string, error) {
+	files, err := ioutil.ReadDir(s.cartDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var users []string
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".journal" {
+			users = append(users, strings.TrimSuffix(file.Name(), ".journal"))
+		}
+	}
+	return users, nil
+}
+
+// LoadGames reads every games/<id>.json file.
+func (s *FileStore) LoadGames() (map[string][]byte, error) {
+	return s.loadDir(s.gameDir(), ".json")
+}
+
+// SaveGame writes games/<id>.json.
+func (s *FileStore) SaveGame(userID string, doc []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.writeDoc(s.gameDir(), userID+".json", doc)
+}
+
+// LoadResults reads every results/<id>.json file.
+func (s *FileStore) LoadResults() (map[string][]byte, error) {
+	return s.loadDir(s.resultDir(), ".json")
+}
+
+// SaveResults writes results/<id>.json.
+func (s *FileStore) SaveResults(userID string, doc []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.writeDoc(s.resultDir(), userID+".json", doc)
+}
+
+// LoadScenarios reads every scenarios/<id>.json file.
+func (s *FileStore) LoadScenarios() (map[string][]byte, error) {
+	return s.loadDir(s.scenarioDir(), ".json")
+}
+
+// SaveScenarios writes scenarios/<id>.json.
+func (s *FileStore) SaveScenarios(userID string, doc []byte) error {
+	if err := checkKey(userID); err != nil {
+		return err
+	}
+	return s.writeDoc(s.scenarioDir(), userID+".json", doc)
+}
+
+// LoadClassrooms reads every classrooms/<name>.json file.
+func (s *FileStore) LoadClassrooms() (map[string][]byte, error) {
+	return s.loadDir(s.classroomDir(), ".json")
+}
+
+// SaveClassroom writes classrooms/<name>.json.
+func (s *FileStore) SaveClassroom(name string, doc []byte) error {
+	if err := checkKey(name); err != nil {
+		return err
+	}
+	return s.writeDoc(s.classroomDir(), name+".json", doc)
+}
+
+// DeleteClassroom removes classrooms/<name>.json; a missing file is not an
+// error.
+func (s *FileStore) DeleteClassroom(name string) error {
+	if err := checkKey(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.classroomDir(), name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return syncDir(s.classroomDir())
+}
+
+// RenameKey renames every file stored under oldKey.
+func (s *FileStore) RenameKey(oldKey, newKey string) error {
+	if err := checkKey(oldKey); err != nil {
+		return err
+	}
+	if err := checkKey(newKey); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := []struct{ dir, ext string }{
+		{s.cartDir(), ".cart"},
+		{s.cartDir(), ".ledger"},
+		{s.cartDir(), ".journal"},
+		{s.gameDir(), ".json"},
+		{s.resultDir(), ".json"},
+		{s.scenarioDir(), ".json"},
+	}
+	for _, f := range files {
+		from := filepath.Join(f.dir, oldKey+f.ext)
+		to := filepath.Join(f.dir, newKey+f.ext)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			return fmt.Errorf("%s already exists", to)
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		if err := syncDir(f.dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing; files are closed after every operation.
+func (s *FileStore) Close() error { return nil }
+
+// loadDir reads every file with the given extension in dir, keyed by the
+// file name without the extension. Unreadable files are skipped, and temp
+// files left behind by an interrupted write are removed.
+func (s *FileStore) loadDir(dir, ext string) (map[string][]byte, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string][]byte)
+	for _, file := range files {
+		if strings.Contains(file.Name(), tempMarker) {
+			os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		if file.IsDir() || filepath.Ext(file.Name()) != ext {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", path, err)
+			continue
+		}
+		docs[strings.TrimSuffix(file.Name(), ext)] = content
+	}
+	return docs, nil
+}
+
+// writeDoc atomically replaces dir/name: the document is written to a temp
+// file in the same directory, fsynced and renamed over the old one, and the
+// directory is fsynced so the rename itself survives a crash.
+func (s *FileStore) writeDoc(dir, name string, doc []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, name+tempMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(doc); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, name)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(dir)
+}
+
+// appendLine appends a single line to a file and fsyncs it. If an earlier
+// append was torn, the new line is started on a line of its own so only the
+// torn entry is lost.
+func (s *FileStore) appendLine(path string, line []byte) error {
+	if bytes.ContainsRune(line, '\n') {
+		return fmt.Errorf("appended entries must be a single line")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readLines returns the lines of a file; a missing file has none. A torn
+// last line from an interrupted append is returned as is for the caller to
+// judge.
+func readLines(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	return lines, scanner.Err()
+}
+
+// syncDir fsyncs a directory so renames and new files in it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
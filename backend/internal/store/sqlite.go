@@ -0,0 +1,540 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, registered as "sqlite"
+)
+
+// migration is a numbered schema change. Migrations are applied in order and
+// never edited once released; change the schema by appending a new one.
+type migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+var migrations = []migration{
+	{1, "initial schema", []string{
+		`CREATE TABLE users (
+			username      TEXT PRIMARY KEY,
+			password_hash TEXT NOT NULL,
+			created_at    TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE sessions (
+			id         TEXT PRIMARY KEY,
+			username   TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE carts (
+			username   TEXT PRIMARY KEY,
+			doc        TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE ledger_entries (
+			id       INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL,
+			entry    TEXT NOT NULL
+		)`,
+		`CREATE INDEX ledger_entries_username ON ledger_entries (username, id)`,
+		`CREATE TABLE games (
+			username   TEXT PRIMARY KEY,
+			doc        TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE results (
+			username   TEXT PRIMARY KEY,
+			doc        TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+	}},
+	{2, "cart journal", []string{
+		`CREATE TABLE cart_journal (
+			id       INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL,
+			entry    TEXT NOT NULL
+		)`,
+		`CREATE INDEX cart_journal_username ON cart_journal (username, id)`,
+	}},
+	// Everything but the users table is keyed by user ID from here on; rows
+	// still holding usernames are re-keyed by user.MigrateKeys at startup.
+	{3, "user ids", []string{
+		`ALTER TABLE users ADD COLUMN id TEXT`,
+		`CREATE UNIQUE INDEX users_id ON users (id)`,
+		`ALTER TABLE sessions RENAME COLUMN username TO user_id`,
+		`ALTER TABLE carts RENAME COLUMN username TO user_id`,
+		`ALTER TABLE ledger_entries RENAME COLUMN username TO user_id`,
+		`ALTER TABLE cart_journal RENAME COLUMN username TO user_id`,
+		`ALTER TABLE games RENAME COLUMN username TO user_id`,
+		`ALTER TABLE results RENAME COLUMN username TO user_id`,
+	}},
+	{4, "user roles", []string{
+		`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+	}},
+	{5, "session last seen", []string{
+		`ALTER TABLE sessions ADD COLUMN last_seen TIMESTAMP`,
+		`UPDATE sessions SET last_seen = created_at`,
+	}},
+	{6, "classrooms", []string{
+		`CREATE TABLE classrooms (
+			name       TEXT PRIMARY KEY,
+			doc        TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+	}},
+	{7, "api tokens", []string{
+		`CREATE TABLE api_tokens (
+			id         TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			name       TEXT NOT NULL,
+			hash       TEXT NOT NULL UNIQUE,
+			scope      TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			last_used  TIMESTAMP,
+			expires_at TIMESTAMP
+		)`,
+		`CREATE INDEX api_tokens_user_id ON api_tokens (user_id)`,
+	}},
+	{8, "identities", []string{
+		`CREATE TABLE identities (
+			provider  TEXT NOT NULL,
+			subject   TEXT NOT NULL,
+			user_id   TEXT NOT NULL,
+			email     TEXT NOT NULL,
+			linked_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (provider, subject)
+		)`,
+		`CREATE INDEX identities_user_id ON identities (user_id)`,
+	}},
+	{9, "saved scenarios", []string{
+		`CREATE TABLE scenarios (
+			user_id    TEXT PRIMARY KEY,
+			doc        TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+	}},
+}
+
+// keyedTables are the tables whose rows belong to a user, by user_id.
+var keyedTables = []string{"sessions", "api_tokens", "identities", "carts", "ledger_entries", "cart_journal", "games", "results", "scenarios"}
+
+// SQLiteStore keeps everything in a single SQLite database file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (creating if needed) a SQLite database and brings its
+// schema up to date.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; a single connection avoids
+	// "database is locked" errors between our own goroutines.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 5000", "PRAGMA foreign_keys = ON"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %v", pragma, err)
+		}
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// SchemaVersion returns the version of the last migration applied.
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// migrate applies the migrations the database hasn't seen yet, each in its
+// own transaction.
+func (s *SQLiteStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  TIMESTAMP NOT NULL
+	)`); err != nil {
+		return err
+	}
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range m.Statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d (%s): %v", m.Version, m.Description, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Description, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadUsers returns every user record.
+func (s *SQLiteStore) LoadUsers() ([]UserRecord, error) {
+	rows, err := s.db.Query(`SELECT COALESCE(id, ''), username, password_hash, role FROM users ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []UserRecord
+	for rows.Next() {
+		var u UserRecord
+		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// AddUser inserts a user; it fails if the username or ID is taken.
+func (s *SQLiteStore) AddUser(u UserRecord) error {
+	_, err := s.db.Exec(`INSERT INTO users (id, username, password_hash, role, created_at) VALUES (?, ?, ?, ?, ?)`,
+		nullIfEmpty(u.ID), u.Username, u.PasswordHash, u.Role, time.Now().UTC())
+	return err
+}
+
+// UpdateUser replaces a user's ID, password hash and role.
+func (s *SQLiteStore) UpdateUser(u UserRecord) error {
+	res, err := s.db.Exec(`UPDATE users SET id = ?, password_hash = ?, role = ? WHERE username = ?`,
+		nullIfEmpty(u.ID), u.PasswordHash, u.Role, u.Username)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("user %s not found", u.Username)
+	}
+	return nil
+}
+
+// LoadSessions returns every stored session.
+func (s *SQLiteStore) LoadSessions() ([]SessionRecord, error) {
+	rows, err := s.db.Query(`SELECT id, user_id, created_at, last_seen FROM sessions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []SessionRecord
+	for rows.Next() {
+		var rec SessionRecord
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&rec.ID, &rec.UserID, &rec.CreatedAt, &lastSeen); err != nil {
+			return nil, err
+		}
+		rec.LastSeen = rec.CreatedAt
+		if lastSeen.Valid {
+			rec.LastSeen = lastSeen.Time
+		}
+		sessions = append(sessions, rec)
+	}
+	return sessions, rows.Err()
+}
+
+// SaveSession stores a session.
+func (s *SQLiteStore) SaveSession(rec SessionRecord) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO sessions (id, user_id, created_at, last_seen) VALUES (?, ?, ?, ?)`,
+		rec.ID, rec.UserID, rec.CreatedAt.UTC(), rec.LastSeen.UTC())
+	return err
+}
+
+// DeleteSessions removes sessions in one transaction.
+func (s *SQLiteStore) DeleteSessions(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadTokens returns every API token.
+func (s *SQLiteStore) LoadTokens() ([]TokenRecord, error) {
+	rows, err := s.db.Query(`SELECT id, user_id, name, hash, scope, created_at, last_used, expires_at FROM api_tokens`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []TokenRecord
+	for rows.Next() {
+		var t TokenRecord
+		var lastUsed, expiresAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Hash, &t.Scope, &t.CreatedAt, &lastUsed, &expiresAt); err != nil {
+			return nil, err
+		}
+		t.LastUsed, t.ExpiresAt = lastUsed.Time, expiresAt.Time
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// SaveToken stores an API token.
+func (s *SQLiteStore) SaveToken(t TokenRecord) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO api_tokens (id, user_id, name, hash, scope, created_at, last_used, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.UserID, t.Name, t.Hash, t.Scope, t.CreatedAt.UTC(), nullIfZero(t.LastUsed), nullIfZero(t.ExpiresAt))
+	return err
+}
+
+// DeleteToken removes an API token.
+func (s *SQLiteStore) DeleteToken(id string) error {
+	_, err := s.db.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	return err
+}
+
+// LoadIdentities returns every identity link.
+func (s *SQLiteStore) LoadIdentities() ([]IdentityRecord, error) {
+	rows, err := s.db.Query(`SELECT provider, subject, user_id, email, linked_at FROM identities`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var identities []IdentityRecord
+	for rows.Next() {
+		var i IdentityRecord
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+// SaveIdentity stores an identity link.
+func (s *SQLiteStore) SaveIdentity(i IdentityRecord) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO identities (provider, subject, user_id, email, linked_at) VALUES (?, ?, ?, ?, ?)`,
+		i.Provider, i.Subject, i.UserID, i.Email, i.LinkedAt.UTC())
+	return err
+}
+
+// DeleteIdentity removes an identity link.
+func (s *SQLiteStore) DeleteIdentity(provider, subject string) error {
+	_, err := s.db.Exec(`DELETE FROM identities WHERE provider = ? AND subject = ?`, provider, subject)
+	return err
+}
+
+// LoadCarts returns every cart document.
+func (s *SQLiteStore) LoadCarts() (map[string][]byte, error) {
+	return s.loadDocs("carts")
+}
+
+// SaveCart stores a cart document.
+func (s *SQLiteStore) SaveCart(userID string, doc []byte) error {
+	return s.saveDoc("carts", userID, doc)
+}
+
+// LoadLedger returns a user's ledger entries, oldest first.
+func (s *SQLiteStore) LoadLedger(userID string) ([][]byte, error) {
+	return s.loadEntries("ledger_entries", userID)
+}
+
+// AppendLedger appends an entry to a user's ledger.
+func (s *SQLiteStore) AppendLedger(userID string, entry []byte) error {
+	_, err := s.db.Exec(`INSERT INTO ledger_entries (user_id, entry) VALUES (?, ?)`, userID, string(entry))
+	return err
+}
+
+// LoadJournal returns a user's cart journal entries, oldest first.
+func (s *SQLiteStore) LoadJournal(userID string) ([][]byte, error) {
+	return s.loadEntries("cart_journal", userID)
+}
+
+// AppendJournal appends an entry to a user's cart journal.
+func (s *SQLiteStore) AppendJournal(userID string, entry []byte) error {
+	_, err := s.db.Exec(`INSERT INTO cart_journal (user_id, entry) VALUES (?, ?)`, userID, string(entry))
+	return err
+}
+
+// JournalUsers lists the users with cart journal entries.
+func (s *SQLiteStore) JournalUsers() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT user_id FROM cart_journal`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// LoadGames returns every game document.
+func (s *SQLiteStore) LoadGames() (map[string][]byte, error) {
+	return s.loadDocs("games")
+}
+
+// SaveGame stores a game document.
+func (s *SQLiteStore) SaveGame(userID string, doc []byte) error {
+	return s.saveDoc("games", userID, doc)
+}
+
+// LoadResults returns every result record document.
+func (s *SQLiteStore) LoadResults() (map[string][]byte, error) {
+	return s.loadDocs("results")
+}
+
+// SaveResults stores a result record document.
+func (s *SQLiteStore) SaveResults(userID string, doc []byte) error {
+	return s.saveDoc("results", userID, doc)
+}
+
+// LoadScenarios returns every saved scenarios document.
+func (s *SQLiteStore) LoadScenarios() (map[string][]byte, error) {
+	return s.loadDocs("scenarios")
+}
+
+// SaveScenarios stores a saved scenarios document.
+func (s *SQLiteStore) SaveScenarios(userID string, doc []byte) error {
+	return s.saveDoc("scenarios", userID, doc)
+}
+
+// LoadClassrooms returns every classroom document.
+func (s *SQLiteStore) LoadClassrooms() (map[string][]byte, error) {
+	rows, err := s.db.Query(`SELECT name, doc FROM classrooms`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	docs := make(map[string][]byte)
+	for rows.Next() {
+		var name, doc string
+		if err := rows.Scan(&name, &doc); err != nil {
+			return nil, err
+		}
+		docs[name] = []byte(doc)
+	}
+	return docs, rows.Err()
+}
+
+// SaveClassroom stores a classroom document.
+func (s *SQLiteStore) SaveClassroom(name string, doc []byte) error {
+	_, err := s.db.Exec(`INSERT INTO classrooms (name, doc, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET doc = excluded.doc, updated_at = excluded.updated_at`,
+		name, string(doc), time.Now().UTC())
+	return err
+}
+
+// DeleteClassroom removes a classroom.
+func (s *SQLiteStore) DeleteClassroom(name string) error {
+	_, err := s.db.Exec(`DELETE FROM classrooms WHERE name = ?`, name)
+	return err
+}
+
+// RenameKey re-keys a user's rows in every table in one transaction.
+func (s *SQLiteStore) RenameKey(oldKey, newKey string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, table := range keyedTables {
+		if _, err := tx.Exec(`UPDATE `+table+` SET user_id = ? WHERE user_id = ?`, newKey, oldKey); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("re-keying %s: %v", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// loadDocs reads a user ID -> doc table. The table name is never user input.
+func (s *SQLiteStore) loadDocs(table string) (map[string][]byte, error) {
+	rows, err := s.db.Query(`SELECT user_id, doc FROM ` + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	docs := make(map[string][]byte)
+	for rows.Next() {
+		var userID, doc string
+		if err := rows.Scan(&userID, &doc); err != nil {
+			return nil, err
+		}
+		docs[userID] = []byte(doc)
+	}
+	return docs, rows.Err()
+}
+
+// loadEntries reads a user's rows from an append-only table, oldest first.
+// The table name is never user input.
+func (s *SQLiteStore) loadEntries(table, userID string) ([][]byte, error) {
+	rows, err := s.db.Query(`SELECT entry FROM `+table+` WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries [][]byte
+	for rows.Next() {
+		var entry string
+		if err := rows.Scan(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, []byte(entry))
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteStore) saveDoc(table, userID string, doc []byte) error {
+	_, err := s.db.Exec(`INSERT INTO `+table+` (user_id, doc, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET doc = excluded.doc, updated_at = excluded.updated_at`,
+		userID, string(doc), time.Now().UTC())
+	return err
+}
+
+// nullIfEmpty stores an empty string as NULL, so unique indexes ignore it.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero stores a zero time as NULL.
+func nullIfZero(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,311 @@
+// Package store persists users, sessions, API tokens, identity links, carts,
+// ledgers, games, saved scenarios and classrooms. The domain packages keep their in-memory maps and hand the
+// store JSON documents, so a backend only has to store bytes by user ID.
+// There is a flat-file backend that keeps the historical on-disk layout and
+// an embedded SQLite backend for running without a separate database server.
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend names accepted by Open.
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+)
+
+// UserRecord is a registered user. ID is the opaque key everything else the
+// user owns is stored under; usernames are only ever stored here. Records
+// written before IDs existed load with an empty ID, and records written
+// before roles existed with an empty role.
+type UserRecord struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	Role         string
+}
+
+// UserStore stores user records.
+type UserStore interface {
+	LoadUsers() ([]UserRecord, error)
+	AddUser(u UserRecord) error
+	UpdateUser(u UserRecord) error // matched by username; replaces ID, hash and role
+}
+
+// SessionRecord is a login session. Like API tokens, sessions are stored by
+// a hash of their ID, so the store can't be used to take them over.
+type SessionRecord struct {
+	ID        string    `json:"id"` // SHA-256 hash of the session ID
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SessionStore stores login sessions.
+type SessionStore interface {
+	LoadSessions() ([]SessionRecord, error)
+	SaveSession(s SessionRecord) error  // inserts or replaces by ID
+	DeleteSessions(ids ...string) error // in one write
+}
+
+// CartStore stores each user's cart as a JSON document.
+type CartStore interface {
+	LoadCarts() (map[string][]byte, error) // user ID -> doc
+	SaveCart(userID string, doc []byte) error
+}
+
+// LedgerStore stores each user's append-only ledger as JSON entries.
+type LedgerStore interface {
+	LoadLedger(userID string) ([][]byte, error) // oldest entry first
+	AppendLedger(userID string, entry []byte) error
+}
+
+// JournalStore stores each user's append-only journal of cart operations,
+// which can rebuild a cart whose snapshot was lost or corrupted.
+type JournalStore interface {
+	LoadJournal(userID string) ([][]byte, error) // oldest entry first
+	AppendJournal(userID string, entry []byte) error
+	JournalUsers() ([]string, error) // IDs of users with a journal
+}
+
+// GameStore stores each user's current game and their finished-game record.
+type GameStore interface {
+	LoadGames() (map[string][]byte, error) // user ID -> doc
+	SaveGame(userID string, doc []byte) error
+	LoadResults() (map[string][]byte, error) // user ID -> doc
+	SaveResults(userID string, doc []byte) error
+}
+
+// ScenarioStore stores the portfolios each user saved, as one JSON document
+// per user.
+type ScenarioStore interface {
+	LoadScenarios() (map[string][]byte, error) // user ID -> doc
+	SaveScenarios(userID string, doc []byte) error
+}
+
+// ClassroomStore stores each classroom as a JSON document, keyed by the
+// classroom's name. Classrooms refer to their instructor and students by
+// user ID, so they are not re-keyed by RenameKey.
+type ClassroomStore interface {
+	LoadClassrooms() (map[string][]byte, error) // name -> doc
+	SaveClassroom(name string, doc []byte) error
+	DeleteClassroom(name string) error
+}
+
+// TokenRecord is a personal API token. Only a hash of the token is stored;
+// the token itself is shown to its owner once, when it is created.
+type TokenRecord struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`  // zero if never used
+	ExpiresAt time.Time `json:"expires_at"` // zero if it never expires
+}
+
+// TokenStore stores API tokens.
+type TokenStore interface {
+	LoadTokens() ([]TokenRecord, error)
+	SaveToken(t TokenRecord) error // inserts or replaces by ID
+	DeleteToken(id string) error
+}
+
+// IdentityRecord links an account at an OpenID Connect provider, named by
+// the provider's issuer-unique subject, to a local user.
+type IdentityRecord struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"` // as the provider reported it when linked
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// IdentityStore stores identity links.
+type IdentityStore interface {
+	LoadIdentities() ([]IdentityRecord, error)
+	SaveIdentity(i IdentityRecord) error // inserts or replaces by provider and subject
+	DeleteIdentity(provider, subject string) error
+}
+
+// Store is a complete persistence backend.
+type Store interface {
+	UserStore
+	SessionStore
+	CartStore
+	LedgerStore
+	JournalStore
+	GameStore
+	ScenarioStore
+	ClassroomStore
+	TokenStore
+	IdentityStore
+	// RenameKey moves everything stored under one user key to another. It
+	// exists to move data kept under usernames over to user IDs, and fails
+	// rather than overwrite data already under the new key.
+	RenameKey(oldKey, newKey string) error
+	Close() error
+}
+
+// checkKey rejects keys that could name something outside the store, such as
+// "../x" or "a/b". Keys are user IDs, usernames in data not yet migrated, or
+// classroom names.
+func checkKey(key string) error {
+	if key == "" || key == "." || key == ".." || filepath.Base(key) != key ||
+		strings.ContainsAny(key, "/\\\x00") {
+		return fmt.Errorf("invalid store key %q", key)
+	}
+	return nil
+}
+
+// Open opens a backend. For the file backend path is the data directory; for
+// SQLite it is the database file.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case BackendFile, "":
+		if path == "" {
+			path = "."
+		}
+		return NewFileStore(path), nil
+	case BackendSQLite:
+		if path == "" {
+			path = "ecology.db"
+		}
+		return OpenSQLite(path)
+	}
+	return nil, fmt.Errorf("unknown store backend %q", backend)
+}
+
+var (
+	current   Store = NewFileStore(".")
+	currentMu sync.RWMutex
+)
+
+// Default returns the store the domain packages persist to. Until SetDefault
+// is called it is a file store in the working directory.
+func Default() Store {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetDefault replaces the store the domain packages persist to. Call it at
+// startup, before any data is loaded.
+func SetDefault(s Store) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = s
+}
+
+// Copy copies every user, API token, identity link, cart, ledger, journal,
+// game, result, saved scenario and classroom from src to dst, for moving a deployment from one backend to
+// another. Sessions are not copied.
+func Copy(dst, src Store) error {
+	users, err := src.LoadUsers()
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := dst.AddUser(u); err != nil {
+			return err
+		}
+	}
+
+	tokens, err := src.LoadTokens()
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := dst.SaveToken(t); err != nil {
+			return err
+		}
+	}
+
+	identities, err := src.LoadIdentities()
+	if err != nil {
+		return err
+	}
+	for _, i := range identities {
+		if err := dst.SaveIdentity(i); err != nil {
+			return err
+		}
+	}
+
+	carts, err := src.LoadCarts()
+	if err != nil {
+		return err
+	}
+	for key, doc := range carts {
+		if err := dst.SaveCart(key, doc); err != nil {
+			return err
+		}
+		entries, err := src.LoadLedger(key)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := dst.AppendLedger(key, e); err != nil {
+				return err
+			}
+		}
+	}
+	journalUsers, err := src.JournalUsers()
+	if err != nil {
+		return err
+	}
+	for _, key := range journalUsers {
+		entries, err := src.LoadJournal(key)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := dst.AppendJournal(key, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	games, err := src.LoadGames()
+	if err != nil {
+		return err
+	}
+	for key, doc := range games {
+		if err := dst.SaveGame(key, doc); err != nil {
+			return err
+		}
+	}
+	results, err := src.LoadResults()
+	if err != nil {
+		return err
+	}
+	for key, doc := range results {
+		if err := dst.SaveResults(key, doc); err != nil {
+			return err
+		}
+	}
+	scenarios, err := src.LoadScenarios()
+	if err != nil {
+		return err
+	}
+	for key, doc := range scenarios {
+		if err := dst.SaveScenarios(key, doc); err != nil {
+			return err
+		}
+	}
+	classrooms, err := src.LoadClassrooms()
+	if err != nil {
+		return err
+	}
+	for name, doc := range classrooms {
+		if err := dst.SaveClassroom(name, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
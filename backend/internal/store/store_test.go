@@ -0,0 +1,389 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// backends opens an empty store of every kind, for tests that every backend
+// must pass alike.
+func backends(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]Store{
+		BackendFile:   NewFileStore(t.TempDir()),
+		BackendSQLite: db,
+	}
+}
+
+var (
+	created = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	used    = time.Date(2025, 3, 2, 8, 30, 0, 0, time.UTC)
+)
+
+func TestUsers(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			alice := UserRecord{ID: "u_0000000000000001", Username: "alice", PasswordHash: "$2a$10$hash", Role: "player"}
+			bob := UserRecord{ID: "u_0000000000000002", Username: "bob", PasswordHash: ""}
+			for _, u := range []UserRecord{alice, bob} {
+				if err := s.AddUser(u); err != nil {
+					t.Fatal(err)
+				}
+			}
+			alice.Role = "admin"
+			alice.PasswordHash = "$2a$10$other"
+			if err := s.UpdateUser(alice); err != nil {
+				t.Fatal(err)
+			}
+			users, err := s.LoadUsers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+			if want := []UserRecord{alice, bob}; !reflect.DeepEqual(users, want) {
+				t.Errorf("loaded %+v, want %+v", users, want)
+			}
+		})
+	}
+}
+
+func TestSessionsTokensAndIdentities(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			session := SessionRecord{ID: "s1", UserID: "u_0000000000000001", CreatedAt: created, LastSeen: created}
+			if err := s.SaveSession(session); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"s2", "s3"} {
+				if err := s.SaveSession(SessionRecord{ID: id, UserID: "u_0000000000000002", CreatedAt: created, LastSeen: created}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			session.LastSeen = used
+			if err := s.SaveSession(session); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteSessions("s2", "s3", "never-stored"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteSessions(); err != nil {
+				t.Fatal(err)
+			}
+			sessions, err := s.LoadSessions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sessions) != 1 || sessions[0].ID != "s1" || !sessions[0].LastSeen.Equal(used) || !sessions[0].CreatedAt.Equal(created) {
+				t.Errorf("sessions: %+v", sessions)
+			}
+
+			token := TokenRecord{ID: "t1", UserID: "u_0000000000000001", Name: "ci", Hash: "abc", Scope: "read", CreatedAt: created}
+			if err := s.SaveToken(token); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveToken(TokenRecord{ID: "t2", UserID: "u_0000000000000001", Name: "old", Hash: "def", Scope: "write", CreatedAt: created, ExpiresAt: used}); err != nil {
+				t.Fatal(err)
+			}
+			token.LastUsed = used
+			if err := s.SaveToken(token); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteToken("t2"); err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := s.LoadTokens()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tokens) != 1 || tokens[0].ID != "t1" || !tokens[0].LastUsed.Equal(used) || !tokens[0].ExpiresAt.IsZero() {
+				t.Errorf("tokens: %+v", tokens)
+			}
+
+			link := IdentityRecord{Provider: "school", Subject: "123", UserID: "u_0000000000000001", Email: "a@example.edu", LinkedAt: created}
+			if err := s.SaveIdentity(link); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveIdentity(IdentityRecord{Provider: "school", Subject: "456", UserID: "u_0000000000000002", LinkedAt: created}); err != nil {
+				t.Fatal(err)
+			}
+			link.Email = "alice@example.edu"
+			if err := s.SaveIdentity(link); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteIdentity("school", "456"); err != nil {
+				t.Fatal(err)
+			}
+			links, err := s.LoadIdentities()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(links) != 1 || links[0].Email != "alice@example.edu" || !links[0].LinkedAt.Equal(created) {
+				t.Errorf("identities: %+v", links)
+			}
+		})
+	}
+}
+
+func TestDocumentsAndEntries(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			const id = "u_0000000000000001"
+			if err := s.SaveCart(id, []byte(`{"v":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveCart(id, []byte(`{"v":2}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveGame(id, []byte(`{"game":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveResults(id, []byte(`{"results":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveScenarios(id, []byte(`[{"name":"a"}]`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveScenarios(id, []byte(`[{"name":"b"}]`)); err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range []string{`{"seq":1}`, `{"seq":2}`} {
+				if err := s.AppendLedger(id, []byte(line)); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.AppendJournal(id, []byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.SaveClassroom("physics", []byte(`{"name":"physics"}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveClassroom("chemistry", []byte(`{"name":"chemistry"}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteClassroom("chemistry"); err != nil {
+				t.Fatal(err)
+			}
+
+			checkDoc(t, "cart", s.LoadCarts, id, `{"v":2}`)
+			checkDoc(t, "game", s.LoadGames, id, `{"game":1}`)
+			checkDoc(t, "results", s.LoadResults, id, `{"results":1}`)
+			checkDoc(t, "scenarios", s.LoadScenarios, id, `[{"name":"b"}]`)
+			checkDoc(t, "classroom", s.LoadClassrooms, "physics", `{"name":"physics"}`)
+			checkEntries(t, "ledger", s.LoadLedger, id, `{"seq":1}`, `{"seq":2}`)
+			checkEntries(t, "journal", s.LoadJournal, id, `{"seq":1}`, `{"seq":2}`)
+			if users, err := s.JournalUsers(); err != nil || !reflect.DeepEqual(users, []string{id}) {
+				t.Errorf("journal users %v, %v", users, err)
+			}
+			if entries, err := s.LoadLedger("u_0000000000000009"); err != nil || len(entries) != 0 {
+				t.Errorf("ledger of a user without one: %q, %v", entries, err)
+			}
+		})
+	}
+}
+
+func TestRenameKey(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			const from, to, taken = "alice", "u_0000000000000001", "u_0000000000000002"
+			for _, key := range []string{from, taken} {
+				if err := s.SaveCart(key, []byte(`{"owner":"`+key+`"}`)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.AppendLedger(from, []byte(`{"seq":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AppendJournal(from, []byte(`{"seq":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveGame(from, []byte(`{"game":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveResults(from, []byte(`{"results":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveScenarios(from, []byte(`[{"name":"a"}]`)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.RenameKey(from, taken); err == nil {
+				t.Error("renamed onto a key that already has a cart")
+			}
+			checkDoc(t, "cart after a refused rename", s.LoadCarts, taken, `{"owner":"`+taken+`"}`)
+			checkDoc(t, "cart after a refused rename", s.LoadCarts, from, `{"owner":"`+from+`"}`)
+
+			if err := s.RenameKey(from, to); err != nil {
+				t.Fatal(err)
+			}
+			checkDoc(t, "cart", s.LoadCarts, to, `{"owner":"`+from+`"}`)
+			checkDoc(t, "game", s.LoadGames, to, `{"game":1}`)
+			checkDoc(t, "results", s.LoadResults, to, `{"results":1}`)
+			checkDoc(t, "scenarios", s.LoadScenarios, to, `[{"name":"a"}]`)
+			checkEntries(t, "ledger", s.LoadLedger, to, `{"seq":1}`)
+			checkEntries(t, "journal", s.LoadJournal, to, `{"seq":1}`)
+			carts, _ := s.LoadCarts()
+			if _, ok := carts[from]; ok {
+				t.Error("cart is still under the old key")
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsPathKeys(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	for _, key := range []string{"", ".", "..", "../x", "a/b", `a\b`} {
+		if err := s.SaveCart(key, []byte(`{}`)); err == nil {
+			t.Errorf("saved a cart under %q", key)
+		}
+	}
+}
+
+func TestSQLiteMigratesOldDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+
+	// Build a database as the release with only the first two migrations
+	// left it, with data still keyed by username.
+	all := migrations
+	migrations = all[:2]
+	old, err := OpenSQLite(path)
+	migrations = all
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO users (username, password_hash, created_at) VALUES ('alice', 'hash', '2025-01-01 00:00:00')`,
+		`INSERT INTO carts (username, doc, updated_at) VALUES ('alice', '{"v":1}', '2025-01-01 00:00:00')`,
+		`INSERT INTO cart_journal (username, entry) VALUES ('alice', '{"seq":1}')`,
+	} {
+		if _, err := old.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, _ := old.SchemaVersion(); v != 2 {
+		t.Fatalf("old database is at version %d", v)
+	}
+	old.Close()
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, err := s.SchemaVersion(); err != nil || v != len(migrations) {
+		t.Fatalf("migrated to version %d (%v), want %d", v, err, len(migrations))
+	}
+	users, err := s.LoadUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []UserRecord{{Username: "alice", PasswordHash: "hash"}}; !reflect.DeepEqual(users, want) {
+		t.Errorf("users %+v, want %+v", users, want)
+	}
+	checkDoc(t, "cart", s.LoadCarts, "alice", `{"v":1}`)
+	checkEntries(t, "journal", s.LoadJournal, "alice", `{"seq":1}`)
+	if err := s.SaveIdentity(IdentityRecord{Provider: "school", Subject: "1", UserID: "alice", LinkedAt: created}); err != nil {
+		t.Errorf("identities table missing after migrating: %v", err)
+	}
+
+	// Opening an up-to-date database applies nothing.
+	s.Close()
+	again, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	var applied int
+	if err := again.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil || applied != len(migrations) {
+		t.Errorf("%d migrations recorded (%v), want %d", applied, err, len(migrations))
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := NewFileStore(t.TempDir())
+	const id = "u_0000000000000001"
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(src.AddUser(UserRecord{ID: id, Username: "alice", PasswordHash: "hash", Role: "instructor"}))
+	must(src.SaveSession(SessionRecord{ID: "s1", UserID: id, CreatedAt: created, LastSeen: created}))
+	must(src.SaveToken(TokenRecord{ID: "t1", UserID: id, Name: "ci", Hash: "abc", Scope: "read", CreatedAt: created}))
+	must(src.SaveIdentity(IdentityRecord{Provider: "school", Subject: "123", UserID: id, LinkedAt: created}))
+	must(src.SaveCart(id, []byte(`{"v":1}`)))
+	must(src.AppendLedger(id, []byte(`{"seq":1}`)))
+	must(src.AppendLedger(id, []byte(`{"seq":2}`)))
+	must(src.AppendJournal(id, []byte(`{"seq":1}`)))
+	must(src.SaveGame(id, []byte(`{"game":1}`)))
+	must(src.SaveResults(id, []byte(`{"results":1}`)))
+	must(src.SaveScenarios(id, []byte(`[{"name":"a"}]`)))
+	must(src.SaveClassroom("physics", []byte(`{"name":"physics"}`)))
+
+	dst, err := Open(BackendSQLite, filepath.Join(t.TempDir(), "copy.db"))
+	must(err)
+	defer dst.Close()
+	must(Copy(dst, src))
+
+	users, err := dst.LoadUsers()
+	must(err)
+	if len(users) != 1 || users[0].ID != id || users[0].Role != "instructor" {
+		t.Errorf("users %+v", users)
+	}
+	if sessions, _ := dst.LoadSessions(); len(sessions) != 0 {
+		t.Errorf("sessions were copied: %+v", sessions)
+	}
+	if tokens, _ := dst.LoadTokens(); len(tokens) != 1 || tokens[0].Hash != "abc" {
+		t.Errorf("tokens %+v", tokens)
+	}
+	if links, _ := dst.LoadIdentities(); len(links) != 1 || links[0].UserID != id {
+		t.Errorf("identities %+v", links)
+	}
+	checkDoc(t, "cart", dst.LoadCarts, id, `{"v":1}`)
+	checkEntries(t, "ledger", dst.LoadLedger, id, `{"seq":1}`, `{"seq":2}`)
+	checkEntries(t, "journal", dst.LoadJournal, id, `{"seq":1}`)
+	checkDoc(t, "game", dst.LoadGames, id, `{"game":1}`)
+	checkDoc(t, "results", dst.LoadResults, id, `{"results":1}`)
+	checkDoc(t, "scenarios", dst.LoadScenarios, id, `[{"name":"a"}]`)
+	checkDoc(t, "classroom", dst.LoadClassrooms, "physics", `{"name":"physics"}`)
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open("postgres", ""); err == nil {
+		t.Error("opened an unknown backend")
+	}
+}
+
+func checkDoc(t *testing.T, what string, load func() (map[string][]byte, error), key, want string) {
+	t.Helper()
+	docs, err := load()
+	if err != nil {
+		t.Fatalf("loading %s: %v", what, err)
+	}
+	if got := string(docs[key]); got != want {
+		t.Errorf("%s under %s is %q, want %q", what, key, got, want)
+	}
+}
+
+func checkEntries(t *testing.T, what string, load func(string) ([][]byte, error), key string, want ...string) {
+	t.Helper()
+	entries, err := load(key)
+	if err != nil {
+		t.Fatalf("loading %s: %v", what, err)
+	}
+	got := make([]string, len(entries))
+	for i, e := range entries {
+		got[i] = string(e)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s of %s is %q, want %q", what, key, got, want)
+	}
+}
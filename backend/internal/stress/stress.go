@@ -0,0 +1,180 @@
+// Package stress runs Monte Carlo climate-risk stress tests of a portfolio
+// against the hurricane, earthquake, wildfire and tornado zone tables.
+package stress
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/data"
+)
+
+// hazardModel holds the damage assumptions for one hazard type.
+type hazardModel struct {
+	annualRate     float64 // event probability per year at a risk of 1.0
+	maxDowntimeDay float64 // days offline for the most severe event
+	damageFraction float64 // share of the asset value destroyed by the most severe event
+}
+
+var hazardModels = map[string]hazardModel{
+	data.HazardHurricane:  {annualRate: 0.30, maxDowntimeDay: 30, damageFraction: 0.35},
+	data.HazardEarthquake: {annualRate: 0.05, maxDowntimeDay: 60, damageFraction: 0.50},
+	data.HazardWildfire:   {annualRate: 0.20, maxDowntimeDay: 14, damageFraction: 0.20},
+	data.HazardTornado:    {annualRate: 0.15, maxDowntimeDay: 10, damageFraction: 0.25},
+}
+
+// Defaults used when Options leaves a field at zero.
+const (
+	DefaultRuns = 500
+	maxRuns     = 10000
+)
+
+// Options configures a stress test.
+type Options struct {
+	StartYear int
+	Years     int // simulation horizon in years
+	Runs      int // Monte Carlo runs
+	Seed      uint64
+}
+
+// Facility is one portfolio item with the values needed to price losses.
+type Facility struct {
+	Name          string
+	Latitude      float64
+	Longitude     float64
+	AssetValue    float64 // USD
+	AnnualRevenue float64 // USD per year in normal operation, lost while offline
+}
+
+// FacilityReport summarises the losses of one facility across all runs.
+type FacilityReport struct {
+	Name                  string             `json:"name"`
+	Exposure              map[string]float64 `json:"exposure"`                 // per-hazard risk, 0-1
+	ExpectedEventsPerYear map[string]float64 `json:"expected_events_per_year"` // mean events per hazard
+	ExpectedDowntimeDays  float64            `json:"expected_downtime_days"`   // per year
+	ExpectedRepairCost    float64            `json:"expected_repair_cost"`     // per year
+	ExpectedLostRevenue   float64            `json:"expected_lost_revenue"`    // per year
+	ExpectedAnnualLoss    float64            `json:"expected_annual_loss"`
+	WorstCaseLoss         float64            `json:"worst_case_loss"` // worst run over the horizon
+}
+
+// Report is the outcome of a stress test.
+type Report struct {
+	StartYear          int              `json:"start_year"`
+	Years              int              `json:"years"`
+	Runs               int              `json:"runs"`
+	Seed               uint64           `json:"seed"`
+	Facilities         []FacilityReport `json:"facilities"`
+	ExpectedAnnualLoss float64          `json:"expected_annual_loss"`
+	LossP95            float64          `json:"loss_p95"` // portfolio loss over the horizon, 95th percentile
+	LossP99            float64          `json:"loss_p99"`
+	WorstCaseLoss      float64          `json:"worst_case_loss"`      // worst run over the horizon
+	WorstYearLoss      float64          `json:"worst_year_loss"`      // worst single year in any run
+	WorstYear          int              `json:"worst_year,omitempty"` // calendar year of WorstYearLoss
+}
+
+// Run samples disaster events for every facility over the horizon and
+// aggregates downtime, repair cost and lost revenue. The same seed always
+// produces the same report.
+func Run(facilities []Facility, opts Options) Report {
+	if opts.Runs <= 0 {
+		opts.Runs = DefaultRuns
+	}
+	if opts.Runs > maxRuns {
+		opts.Runs = maxRuns
+	}
+	if opts.Years <= 0 {
+		opts.Years = 1
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, 0x5eed))
+	report := Report{StartYear: opts.StartYear, Years: opts.Years, Runs: opts.Runs, Seed: opts.Seed}
+
+	exposures := make([]map[string]float64, len(facilities))
+	reports := make([]FacilityReport, len(facilities))
+	for i, f := range facilities {
+		exposures[i] = data.DisasterExposure(f.Latitude, f.Longitude)
+		reports[i] = FacilityReport{
+			Name:                  f.Name,
+			Exposure:              exposures[i],
+			ExpectedEventsPerYear: make(map[string]float64),
+		}
+	}
+
+	runLosses := make([]float64, opts.Runs)
+	facilityRunLoss := make([]float64, len(facilities))
+	for run := 0; run < opts.Runs; run++ {
+		var runTotal float64
+		for i := range facilityRunLoss {
+			facilityRunLoss[i] = 0
+		}
+		for year := 0; year < opts.Years; year++ {
+			var yearLoss float64
+			for i, f := range facilities {
+				dailyRevenue := f.AnnualRevenue / 365
+				for _, hazard := range data.Hazards {
+					model := hazardModels[hazard]
+					if rng.Float64() >= exposures[i][hazard]*model.annualRate {
+						continue
+					}
+					// Most events are minor; squaring skews severity towards 0.
+					severity := math.Pow(rng.Float64(), 2)
+					downtime := severity * model.maxDowntimeDay
+					repair := severity * model.damageFraction * f.AssetValue
+					lost := downtime * dailyRevenue
+
+					r := &reports[i]
+					r.ExpectedEventsPerYear[hazard]++
+					r.ExpectedDowntimeDays += downtime
+					r.ExpectedRepairCost += repair
+					r.ExpectedLostRevenue += lost
+					facilityRunLoss[i] += repair + lost
+					yearLoss += repair + lost
+				}
+			}
+			if yearLoss > report.WorstYearLoss {
+				report.WorstYearLoss = yearLoss
+				report.WorstYear = opts.StartYear + year
+			}
+			runTotal += yearLoss
+		}
+		runLosses[run] = runTotal
+		for i := range reports {
+			reports[i].WorstCaseLoss = math.Max(reports[i].WorstCaseLoss, facilityRunLoss[i])
+		}
+	}
+
+	// Turn the sums into per-year expectations.
+	periods := float64(opts.Runs * opts.Years)
+	for i := range reports {
+		r := &reports[i]
+		for h := range r.ExpectedEventsPerYear {
+			r.ExpectedEventsPerYear[h] /= periods
+		}
+		r.ExpectedDowntimeDays /= periods
+		r.ExpectedRepairCost /= periods
+		r.ExpectedLostRevenue /= periods
+		r.ExpectedAnnualLoss = r.ExpectedRepairCost + r.ExpectedLostRevenue
+		report.ExpectedAnnualLoss += r.ExpectedAnnualLoss
+	}
+	report.Facilities = reports
+
+	sort.Float64s(runLosses)
+	report.LossP95 = percentile(runLosses, 0.95)
+	report.LossP99 = percentile(runLosses, 0.99)
+	report.WorstCaseLoss = runLosses[len(runLosses)-1]
+	return report
+}
+
+// percentile returns the p-th percentile of sorted values (nearest rank).
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
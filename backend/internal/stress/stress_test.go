@@ -0,0 +1,30 @@
+package stress
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLostRevenueFollowsFacilityRevenue(t *testing.T) {
+	// Miami: hurricane exposure, so some runs have downtime.
+	miami := Facility{Name: "Miami", Latitude: 25.76, Longitude: -80.19, AssetValue: 10000000, AnnualRevenue: 20000000}
+	opts := Options{StartYear: 2025, Years: 5, Runs: 200, Seed: 7}
+
+	base := Run([]Facility{miami}, opts).Facilities[0]
+	if base.ExpectedLostRevenue <= 0 {
+		t.Fatalf("no lost revenue: %+v", base)
+	}
+	miami.AnnualRevenue *= 2
+	doubled := Run([]Facility{miami}, opts).Facilities[0]
+	if math.Abs(doubled.ExpectedLostRevenue-2*base.ExpectedLostRevenue) > 1e-6*base.ExpectedLostRevenue {
+		t.Errorf("lost revenue %.0f at twice the revenue, want %.0f", doubled.ExpectedLostRevenue, 2*base.ExpectedLostRevenue)
+	}
+	if doubled.ExpectedRepairCost != base.ExpectedRepairCost {
+		t.Errorf("repair cost changed with revenue: %.0f, was %.0f", doubled.ExpectedRepairCost, base.ExpectedRepairCost)
+	}
+
+	miami.AnnualRevenue = 0
+	if idle := Run([]Facility{miami}, opts).Facilities[0]; idle.ExpectedLostRevenue != 0 {
+		t.Errorf("a facility without revenue lost %.0f", idle.ExpectedLostRevenue)
+	}
+}
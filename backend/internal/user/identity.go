@@ -0,0 +1,100 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Username rules. Usernames are shown to other players and used to log in,
+// but are never used as file names or database keys; user IDs are.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	idPattern       = regexp.MustCompile(`^u_[0-9a-f]{16}$`)
+)
+
+// reservedUsernames can't be registered because they would be mistaken for
+// the system or staff.
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true,
+	"support": true, "moderator": true, "instructor": true, "api": true,
+	"null": true, "undefined": true, "anonymous": true, "guest": true,
+}
+
+// ValidateUsername checks a new username: 3 to 32 letters, digits, '_', '.'
+// or '-', starting with a letter or digit, without "..", and not reserved.
+func ValidateUsername(username string) error {
+	switch {
+	case len(username) < MinUsernameLength || len(username) > MaxUsernameLength:
+		return fmt.Errorf("username must be %d to %d characters", MinUsernameLength, MaxUsernameLength)
+	case !usernamePattern.MatchString(username):
+		return fmt.Errorf("username may only contain letters, digits, '_', '.' and '-', and must start with a letter or digit")
+	case strings.Contains(username, ".."):
+		return fmt.Errorf("username may not contain \"..\"")
+	case reservedUsernames[strings.ToLower(username)]:
+		return fmt.Errorf("username %q is reserved", username)
+	case IsID(username):
+		return fmt.Errorf("username %q looks like a user ID", username)
+	}
+	return nil
+}
+
+// NewID returns a new opaque user ID: "u_" and 16 random hex digits.
+func NewID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "u_" + hex.EncodeToString(b), nil
+}
+
+// IsID reports whether s has the form of a user ID.
+func IsID(s string) bool {
+	return idPattern.MatchString(s)
+}
+
+// AvailableUsername turns a name from elsewhere, such as the local part of
+// an email address, into a valid username nobody has taken, adding a number
+// if needed.
+func AvailableUsername(suggestion string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range suggestion {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_' || r == '-' || (r == '.' && last != '.'):
+		default:
+			if last == 0 || last == '_' {
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	base := strings.Trim(b.String(), "_.-")
+	if len(base) > MaxUsernameLength-4 {
+		base = strings.TrimRight(base[:MaxUsernameLength-4], "_.-")
+	}
+	if len(base) < MinUsernameLength {
+		base = "player" + base
+	}
+	candidate := base
+	for n := 2; ValidateUsername(candidate) != nil || Exists(candidate); n++ {
+		candidate = fmt.Sprintf("%s%d", base, n)
+	}
+	return candidate
+}
+
+// HasPassword reports whether a user can log in with a password.
+func HasPassword(username string) bool {
+	hashed, ok := GetHashedPassword(username)
+	return ok && hashed != ""
+}
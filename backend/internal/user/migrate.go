@@ -0,0 +1,101 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+)
+
+// MigrateKeys moves data stored under usernames over to user IDs. Users
+// without an ID get one, then every cart, ledger, journal, game and result
+// still keyed by a username is renamed to that user's ID. Data left by a
+// username that was never registered gets a password-less account, so it
+// keeps its owner but nobody can log in as it or register the name. It is
+// safe to run on every start and returns the keys it renamed.
+func MigrateKeys() (map[string]string, error) {
+	st := store.Default()
+	mu.Lock()
+	defer mu.Unlock()
+
+	for username, u := range accounts {
+		if u.ID != "" {
+			continue
+		}
+		id, err := NewID()
+		if err != nil {
+			return nil, err
+		}
+		u.ID = id
+		if err := st.UpdateUser(u); err != nil {
+			return nil, fmt.Errorf("assigning an ID to %s: %v", username, err)
+		}
+		accounts[username] = u
+		byID[id] = username
+	}
+
+	legacy, err := legacyKeys(st)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]string)
+	for _, key := range legacy {
+		u, ok := accounts[key]
+		if !ok {
+			id, err := NewID()
+			if err != nil {
+				return nil, err
+			}
+			u = store.UserRecord{ID: id, Username: key}
+			if err := st.AddUser(u); err != nil {
+				fmt.Printf("Skipping data stored under %q: %v\n", key, err)
+				continue
+			}
+			fmt.Printf("Created a password-less account for unregistered owner %q\n", key)
+			accounts[key] = u
+			byID[id] = key
+		}
+		if err := st.RenameKey(key, u.ID); err != nil {
+			return renamed, fmt.Errorf("moving %s to %s: %v", key, u.ID, err)
+		}
+		renamed[key] = u.ID
+	}
+	return renamed, nil
+}
+
+// legacyKeys lists the keys in the store that aren't user IDs.
+func legacyKeys(st store.Store) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, load := range []func() (map[string][]byte, error){st.LoadCarts, st.LoadGames, st.LoadResults, st.LoadScenarios} {
+		docs, err := load()
+		if err != nil {
+			return nil, err
+		}
+		for key := range docs {
+			seen[key] = true
+		}
+	}
+	journals, err := st.JournalUsers()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range journals {
+		seen[key] = true
+	}
+	sessions, err := st.LoadSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		seen[s.UserID] = true
+	}
+
+	var keys []string
+	for key := range seen {
+		if !IsID(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
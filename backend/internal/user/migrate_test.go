@@ -0,0 +1,94 @@
+package user
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+)
+
+func TestMigrateKeys(t *testing.T) {
+	for _, backend := range []string{store.BackendFile, store.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			path := t.TempDir()
+			if backend == store.BackendSQLite {
+				path = filepath.Join(path, "test.db")
+			}
+			st, err := store.Open(backend, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+			store.SetDefault(st)
+
+			// A user registered before IDs existed, and data left by a
+			// name that was never registered.
+			registered, orphan := "legacy_"+backend, "orphan_"+backend
+			if err := st.AddUser(store.UserRecord{Username: registered, PasswordHash: "hash"}); err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range []error{
+				st.SaveCart(registered, []byte(`{"v":1}`)),
+				st.AppendLedger(registered, []byte(`{"seq":1}`)),
+				st.AppendJournal(registered, []byte(`{"seq":1}`)),
+				st.SaveGame(registered, []byte(`{"game":1}`)),
+				st.SaveResults(orphan, []byte(`{"results":1}`)),
+				st.SaveScenarios(registered, []byte(`[]`)),
+			} {
+				if step != nil {
+					t.Fatal(step)
+				}
+			}
+			if err := LoadUsers(); err != nil {
+				t.Fatal(err)
+			}
+
+			renamed, err := MigrateKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, ok := IDFor(registered)
+			if !ok || !IsID(id) || renamed[registered] != id {
+				t.Fatalf("%s got ID %q, renamed %v", registered, id, renamed)
+			}
+			orphanID, ok := IDFor(orphan)
+			if !ok || renamed[orphan] != orphanID {
+				t.Fatalf("%s got ID %q, renamed %v", orphan, orphanID, renamed)
+			}
+			if HasPassword(orphan) {
+				t.Errorf("%s can be logged into", orphan)
+			}
+
+			carts, _ := st.LoadCarts()
+			if string(carts[id]) != `{"v":1}` || carts[registered] != nil {
+				t.Errorf("carts after migrating: %q", carts)
+			}
+			if ledger, _ := st.LoadLedger(id); len(ledger) != 1 {
+				t.Errorf("ledger has %d entries under the new key", len(ledger))
+			}
+			if journal, _ := st.LoadJournal(id); len(journal) != 1 {
+				t.Errorf("journal has %d entries under the new key", len(journal))
+			}
+			if games, _ := st.LoadGames(); games[id] == nil {
+				t.Error("game was not moved")
+			}
+			if scenarios, _ := st.LoadScenarios(); scenarios[id] == nil || scenarios[registered] != nil {
+				t.Error("saved scenarios were not moved")
+			}
+			if results, _ := st.LoadResults(); results[orphanID] == nil {
+				t.Error("orphaned results were not moved")
+			}
+			users, _ := st.LoadUsers()
+			for _, u := range users {
+				if u.ID == "" {
+					t.Errorf("%s still has no ID in the store", u.Username)
+				}
+			}
+
+			// Running it again finds nothing left to do.
+			if again, err := MigrateKeys(); err != nil || len(again) != 0 {
+				t.Errorf("second run renamed %v (%v)", again, err)
+			}
+		})
+	}
+}
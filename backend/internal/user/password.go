@@ -0,0 +1,174 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password rules. bcrypt ignores everything past 72 bytes, so longer
+// passwords are refused rather than silently truncated.
+const (
+	MinPasswordLength = 10
+	MaxPasswordLength = 72
+)
+
+// ResetTokenTTL is how long a password reset token can be used.
+const ResetTokenTTL = 30 * time.Minute
+
+// commonPasswords are refused outright; they are the first guesses of any
+// attacker that gets past the lockout.
+var commonPasswords = map[string]bool{
+	"password123": true, "password1234": true, "1234567890": true, "12345678910": true,
+	"qwertyuiop": true, "qwerty12345": true, "iloveyou123": true, "letmein123": true,
+	"welcome123": true, "administrator": true, "abc1234567": true, "passw0rd123": true,
+	"datacenter1": true, "changeme123": true, "trustno1234": true, "football123": true,
+}
+
+// ValidatePassword checks a new password: 10 to 72 bytes, at least one
+// letter and one digit or symbol, not a common password and not containing
+// the username.
+func ValidatePassword(password, username string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be %d to %d characters", MinPasswordLength, MaxPasswordLength)
+	}
+	var letter, other bool
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			letter = true
+		} else if !unicode.IsSpace(r) {
+			other = true
+		}
+	}
+	if !letter || !other {
+		return fmt.Errorf("password must contain a letter and a digit or symbol")
+	}
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return fmt.Errorf("password is too common")
+	}
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return fmt.Errorf("password may not contain the username")
+	}
+	return nil
+}
+
+// HashPassword hashes a password for storage.
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// dummyHash is compared against when there is no password to check, so a
+// failed login takes as long whether or not the account exists. It is a
+// hash at bcrypt.DefaultCost, like real ones.
+const dummyHash = "$2a$10$/f5t3l7ZlEuKdGZxyG6ZCu.tfRYrkEQIcmIY11o56ZO6phjV4Gj9O"
+
+// CheckPassword reports whether password is the user's password. Unknown
+// users and accounts without a password never match.
+func CheckPassword(username, password string) bool {
+	hashed, ok := GetHashedPassword(username)
+	if !ok || hashed == "" {
+		bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+}
+
+// SetPassword checks a new password against ValidatePassword and stores its
+// hash.
+func SetPassword(username, password string) error {
+	if err := ValidatePassword(password, username); err != nil {
+		return err
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	u, ok := accounts[username]
+	if !ok {
+		return fmt.Errorf("user %s not found", username)
+	}
+	u.PasswordHash = hashed
+	if err := store.Default().UpdateUser(u); err != nil {
+		return err
+	}
+	accounts[username] = u
+	return nil
+}
+
+// resetToken is an outstanding password reset. Only a hash of the token is
+// kept, so a memory dump doesn't hand out working tokens.
+type resetToken struct {
+	username string
+	expires  time.Time
+}
+
+// Reset tokens live only in memory: a restart invalidates them, and the user
+// asks for another.
+var (
+	resetTokens = make(map[string]resetToken) // sha256(token) -> reset
+	resetMu     sync.Mutex
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateResetToken returns a one-time token that lets the user set a new
+// password, replacing any token they were given before. Accounts without a
+// password, such as those created for orphaned data, can't be reset.
+func CreateResetToken(username string) (string, error) {
+	if hashed, ok := GetHashedPassword(username); !ok || hashed == "" {
+		return "", fmt.Errorf("user %s can't reset a password", username)
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating reset token: %v", err)
+	}
+	token := hex.EncodeToString(b)
+
+	resetMu.Lock()
+	defer resetMu.Unlock()
+	now := time.Now()
+	for hash, rt := range resetTokens {
+		if rt.username == username || now.After(rt.expires) {
+			delete(resetTokens, hash)
+		}
+	}
+	resetTokens[hashToken(token)] = resetToken{username: username, expires: now.Add(ResetTokenTTL)}
+	return token, nil
+}
+
+// RedeemResetToken looks up the user a reset token belongs to and calls use
+// with their username. The token is used up only if use succeeds, so a
+// rejected new password doesn't cost the user their token.
+func RedeemResetToken(token string, use func(username string) error) error {
+	resetMu.Lock()
+	defer resetMu.Unlock()
+	hash := hashToken(token)
+	rt, ok := resetTokens[hash]
+	if !ok || time.Now().After(rt.expires) {
+		delete(resetTokens, hash)
+		return fmt.Errorf("invalid or expired reset token")
+	}
+	if err := use(rt.username); err != nil {
+		return err
+	}
+	delete(resetTokens, hash)
+	return nil
+}
@@ -0,0 +1,22 @@
+package user
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Unknown users are checked against dummyHash so they take as long to fail
+// as real ones; that only holds while it costs what a real hash does.
+func TestDummyHashCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("dummy hash has cost %d, real hashes %d", cost, bcrypt.DefaultCost)
+	}
+	if CheckPassword("no_such_user", "any-secret-1") {
+		t.Error("an unknown user's password matched")
+	}
+}
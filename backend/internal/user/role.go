@@ -0,0 +1,97 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
+)
+
+// Roles. A user without a role is a player. Each role can do everything the
+// roles before it can.
+const (
+	RolePlayer     = "player"
+	RoleInstructor = "instructor" // runs classrooms of players
+	RoleAdmin      = "admin"      // may act on other users' data and manage the server
+)
+
+// roleRank orders the roles from least to most privileged.
+var roleRank = map[string]int{RolePlayer: 0, RoleInstructor: 1, RoleAdmin: 2}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Account is what the rest of the server knows about a logged-in user.
+type Account struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// IsAdmin reports whether the account has the admin role.
+func (a Account) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+// HasRole reports whether the account has the given role or a more
+// privileged one.
+func (a Account) HasRole(role string) bool {
+	rank, ok := roleRank[role]
+	return ok && roleRank[a.Role] >= rank
+}
+
+// Lookup returns a user's account.
+func Lookup(username string) (Account, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	u, ok := accounts[username]
+	if !ok {
+		return Account{}, false
+	}
+	return accountFor(u), true
+}
+
+// List returns every account, sorted by username.
+func List() []Account {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Account, 0, len(accounts))
+	for _, u := range accounts {
+		list = append(list, accountFor(u))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	return list
+}
+
+func accountFor(u store.UserRecord) Account {
+	role := u.Role
+	if role == "" {
+		role = RolePlayer
+	}
+	return Account{ID: u.ID, Username: u.Username, Role: role}
+}
+
+// SetRole changes a user's role.
+func SetRole(username, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	u, ok := accounts[username]
+	if !ok {
+		return fmt.Errorf("user %s not found", username)
+	}
+	if u.ID == "" {
+		return fmt.Errorf("user %s has no ID yet", username)
+	}
+	u.Role = role
+	if err := store.Default().UpdateUser(u); err != nil {
+		return err
+	}
+	accounts[username] = u
+	return nil
+}
@@ -1,11 +1,11 @@
 package user
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 	"sync"
+
+	"github.com/Samhith-k/data-center-ecology-map/backend/internal/store"
 )
 
 // Credentials holds the incoming JSON fields
@@ -14,72 +14,108 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
-// in-memory map for user data
-var userPasswords = make(map[string]string)
-var mu sync.RWMutex
+// in-memory user data, by username and by ID
+var (
+	accounts = make(map[string]store.UserRecord)
+	byID     = make(map[string]string) // ID -> username
+	mu       sync.RWMutex
+)
 
-// LoadUserPasswords reads the file line by line, storing "username -> hashedPassword"
-func LoadUserPasswords(filename string) error {
-	file, err := os.Open(filename)
+// LoadUsers loads every user record from the store. Records without an ID
+// are kept as they are until MigrateKeys gives them one.
+func LoadUsers() error {
+	users, err := store.Default().LoadUsers()
 	if err != nil {
-		if os.IsNotExist(err) {
-			// If no file, that’s okay
-			return nil
-		}
 		return err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, ":")
-		if len(parts) == 2 {
-			username := parts[0]
-			hashed := parts[1]
-			userPasswords[username] = hashed
+	mu.Lock()
+	defer mu.Unlock()
+	for _, u := range users {
+		accounts[u.Username] = u
+		if u.ID != "" {
+			byID[u.ID] = u.Username
 		}
 	}
-	return scanner.Err()
+	return nil
 }
 
-// AddUser adds a new user with hashed password to userPasswords and to file
+// AddUser registers a new user with a hashed password and a fresh ID. The
+// username must pass ValidateUsername and may not differ from an existing
+// one only by case.
 func AddUser(username, hashedPass string) error {
+	if err := ValidateUsername(username); err != nil {
+		return err
+	}
 	mu.Lock()
 	defer mu.Unlock()
 
-	if _, exists := userPasswords[username]; exists {
+	if existsNoLock(username) {
 		return fmt.Errorf("user %s already exists", username)
 	}
-
-	// append to file
-	f, err := os.OpenFile("users.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	id, err := NewID()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	line := fmt.Sprintf("%s:%s\n", username, hashedPass)
-	if _, err := f.WriteString(line); err != nil {
+	u := store.UserRecord{ID: id, Username: username, PasswordHash: hashedPass}
+	if err := store.Default().AddUser(u); err != nil {
 		return err
 	}
 
 	// update in-memory
-	userPasswords[username] = hashedPass
+	accounts[username] = u
+	byID[id] = username
 	return nil
 }
 
-// Exists checks if user already in the map
+// Exists reports whether the username is taken, ignoring case.
 func Exists(username string) bool {
 	mu.RLock()
 	defer mu.RUnlock()
-	_, ok := userPasswords[username]
-	return ok
+	return existsNoLock(username)
+}
+
+func existsNoLock(username string) bool {
+	for existing := range accounts {
+		if strings.EqualFold(existing, username) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetHashedPassword returns the hashed password for a user, or false if not found
 func GetHashedPassword(username string) (string, bool) {
 	mu.RLock()
 	defer mu.RUnlock()
-	hp, ok := userPasswords[username]
-	return hp, ok
+	u, ok := accounts[username]
+	return u.PasswordHash, ok
+}
+
+// IDFor returns the ID everything the user owns is stored under.
+func IDFor(username string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	u, ok := accounts[username]
+	if !ok || u.ID == "" {
+		return "", false
+	}
+	return u.ID, true
+}
+
+// StoreKey is IDFor with an error for users who aren't registered, for
+// packages that persist per-user data.
+func StoreKey(username string) (string, error) {
+	id, ok := IDFor(username)
+	if !ok {
+		return "", fmt.Errorf("unknown user %q", username)
+	}
+	return id, nil
+}
+
+// UsernameFor returns the username for a user ID.
+func UsernameFor(id string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	username, ok := byID[id]
+	return username, ok
 }
@@ -78,7 +78,7 @@ function Game({ username, onLogout }) {
   const [potentialLocations, setPotentialLocations] = useState([]);
   const [selectedLocation, setSelectedLocation] = useState(null);
   const [builtDataCenters, setBuiltDataCenters] = useState([]);
-  const [budget, setBudget] = useState(10000000);
+  const [budget, setBudget] = useState(0);
   const [score, setScore] = useState(0);
   const [carbonFootprint, setCarbonFootprint] = useState(0);
   const [day, setDay] = useState(1);
@@ -111,6 +111,7 @@ function Game({ username, onLogout }) {
   const buildingOptions = [
     {
       id: 1,
+      tier: 'standard',
       name: 'Standard Data Center',
       cost: 2000000,
       energyEfficiency: 60,
@@ -120,6 +121,7 @@ function Game({ username, onLogout }) {
     },
     {
       id: 2,
+      tier: 'eco',
       name: 'Eco Optimized Center',
       cost: 3500000,
       energyEfficiency: 85,
@@ -129,6 +131,7 @@ function Game({ username, onLogout }) {
     },
     {
       id: 3,
+      tier: 'next-gen',
       name: 'Next-Gen Sustainable Facility',
       cost: 5000000,
       energyEfficiency: 95,
@@ -149,11 +152,19 @@ function Game({ username, onLogout }) {
         credentials: 'include'
       });
       if (!res.ok) {
-        // Possibly 404 if no cart found, so just skip
+        // Possibly 404 if no cart found; the budget is then the starting funds
         console.log("No existing cart found, or error fetching cart.");
+        const econRes = await fetch("http://localhost:8080/api/economy", { credentials: 'include' });
+        if (econRes.ok) {
+          const econ = await econRes.json();
+          setBudget(econ.starting_funds || 0);
+        }
         return;
       }
       const cartData = await res.json();
+      if (cartData && typeof cartData.money_left === 'number') {
+        setBudget(cartData.money_left);
+      }
       // cartData may have .items array
       if (cartData && cartData.items) {
         setCartItems(cartData.items);
@@ -166,21 +177,18 @@ function Game({ username, onLogout }) {
     }
   };
 
-  // 2) Add item to cart
-  const addToCart = async (location) => {
+  // 2) Add item to cart. The backend prices the site and tier and
+  // returns the new balance; returns false if the purchase was refused.
+  const addToCart = async (location, tier = 'standard') => {
     try {
-      let cost = location.land_cost || 100000;
       const itemPayload = {
         username,
+        tier,
         item: {
           latitude: location.position.lat,
           longitude: location.position.lng,
-          name: location.name || "Untitled",
-          land_price: `$${cost.toLocaleString()}`,
-          electricity: location.electricity_cost || "$0.07/kWh",
-          notes: location.description || "Data center location"
-        },
-        cost
+          name: location.name || "Untitled"
+        }
       };
 
       const res = await fetch("http://localhost:8080/cart/add", {
@@ -192,26 +200,36 @@ function Game({ username, onLogout }) {
       if (!res.ok) {
         throw new Error(`Failed to add item: ${res.status}`);
       }
+      const result = await res.json();
+      if (typeof result.money_left === 'number') {
+        setBudget(result.money_left);
+      }
       console.log("Item added to cart:", location);
       // Reload the cart
       fetchCart();
       fetchCarbonFootprint();
+      return true;
     } catch (err) {
       console.error("Error adding to cart:", err);
+      return false;
     }
   };
 
-  // 3) Remove item from cart
-  const removeCartItem = async (index) => {
+  // 3) Sell or demolish a cart item; the backend refunds part of its price
+  const removeCartItem = async (index, action = 'sell') => {
     try {
-      // Call /cart/item?username=XYZ&index=N
-      const res = await fetch(`http://localhost:8080/cart/item?username=${username}&index=${index}`, {
+      // Call /cart/item?username=XYZ&index=N&action=sell|demolish
+      const res = await fetch(`http://localhost:8080/cart/item?username=${username}&index=${index}&action=${action}`, {
         method: "DELETE",
         credentials: "include"
       });
       if (!res.ok) {
         throw new Error(`Failed to remove cart item: ${res.status}`);
       }
+      const result = await res.json();
+      if (typeof result.money_left === 'number') {
+        setBudget(result.money_left);
+      }
       console.log("Item removed from cart at index:", index);
       // Reload cart
       fetchCart();
@@ -439,15 +457,20 @@ function Game({ username, onLogout }) {
           throw new Error("Invalid JSON response from server");
         }
 
+        // Prefer the server-computed land cost; it is what /cart/add charges.
         let landCost = 3000000;
-        try {
-          const priceText = propertyData.land_price || "$3,000,000";
-          const priceMatch = priceText.match(/\$([0-9,]+)/);
-          if (priceMatch && priceMatch[1]) {
-            landCost = parseInt(priceMatch[1].replace(/,/g, ''));
+        if (typeof propertyData.land_cost === 'number') {
+          landCost = propertyData.land_cost;
+        } else {
+          try {
+            const priceText = propertyData.land_price || "$3,000,000";
+            const priceMatch = priceText.match(/\$([0-9,]+)/);
+            if (priceMatch && priceMatch[1]) {
+              landCost = parseInt(priceMatch[1].replace(/,/g, ''));
+            }
+          } catch (e) {
+            console.error("Error parsing land price:", e);
           }
-        } catch (e) {
-          console.error("Error parsing land price:", e);
         }
 
         const locationName = propertyData.location_name || "Potential Location";
@@ -524,22 +547,18 @@ function Game({ username, onLogout }) {
     }
   };
 
-  const handleBuild = (building) => {
+  const handleBuild = async (building) => {
     if (!selectedLocation) return;
     let totalCost = building.cost + selectedLocation.land_cost;
     if (budget >= totalCost) {
-      // existing build logic ...
-      setBudget(prev => prev - totalCost);
-      // If you want to track built data centers more thoroughly, you can do:
-      // setBuiltDataCenters([...builtDataCenters, { building, location: selectedLocation }]);
-      // setDay(day + 30); etc.
-
-      // Then ALSO add to cart
-      addToCart(selectedLocation);
-
-      setNotification({
+      // The backend debits the budget; addToCart updates it from the response.
+      const ok = await addToCart(selectedLocation, building.tier);
+      setNotification(ok ? {
         type: 'success',
         message: `Successfully built ${building.name} in ${selectedLocation.name}!`
+      } : {
+        type: 'error',
+        message: `Could not build ${building.name} in ${selectedLocation.name}.`
       });
     } else {
       setNotification({
@@ -1085,12 +1104,20 @@ function Game({ username, onLogout }) {
                       {item.land_price} | {item.electricity}
                     </div>
                   </div>
-                  <button
-                    className="btn btn-outline-danger btn-sm"
-                    onClick={() => removeCartItem(idx)}
-                  >
-                    Remove
-                  </button>
+                  <div>
+                    <button
+                      className="btn btn-outline-primary btn-sm me-1"
+                      onClick={() => removeCartItem(idx, 'sell')}
+                    >
+                      Sell
+                    </button>
+                    <button
+                      className="btn btn-outline-danger btn-sm"
+                      onClick={() => removeCartItem(idx, 'demolish')}
+                    >
+                      Demolish
+                    </button>
+                  </div>
                 </div>
               ))
             )}
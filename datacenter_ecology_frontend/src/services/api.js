@@ -14,8 +14,9 @@ const ApiService = {
       });
       
       if (!response.ok) {
-        const errorData = await response.json();
-        throw new Error(errorData.message || 'Registration failed');
+        // Errors are plain text, e.g. which password rule was broken
+        const message = await response.text();
+        throw new Error(message.trim() || 'Registration failed');
       }
       
       return await response.json();
@@ -82,6 +83,232 @@ const ApiService = {
       throw error;
     }
   },
+
+  logoutAll: async () => {
+    try {
+      const response = await fetch(`${API_URL}/logout/all`, {
+        method: 'POST',
+        credentials: 'include', // For cookies
+      });
+
+      if (!response.ok) {
+        throw new Error('Logout failed');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Logout error:', error);
+      throw error;
+    }
+  },
+
+  changePassword: async (currentPassword, newPassword) => {
+    try {
+      const response = await fetch(`${API_URL}/account/password`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+        credentials: 'include', // For cookies
+      });
+
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || 'Password change failed');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Password change error:', error);
+      throw error;
+    }
+  },
+
+  requestPasswordReset: async (username) => {
+    try {
+      const response = await fetch(`${API_URL}/account/password/reset/request`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ username }),
+      });
+
+      if (!response.ok) {
+        throw new Error('Password reset request failed');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Password reset request error:', error);
+      throw error;
+    }
+  },
+
+  getLoginProviders: async () => {
+    try {
+      const response = await fetch(`${API_URL}/auth/oidc/providers`);
+
+      if (!response.ok) {
+        throw new Error('Failed to fetch login providers');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Login provider error:', error);
+      throw error;
+    }
+  },
+
+  // Logging in with a provider leaves the app; the server sends the browser
+  // back to OIDC_RETURN_URL with ?oidc=login or ?oidc_error=...
+  loginWithProvider: (provider) => {
+    window.location.assign(`${API_URL}/auth/oidc/login?provider=${encodeURIComponent(provider)}`);
+  },
+
+  listIdentities: async () => {
+    try {
+      const response = await fetch(`${API_URL}/account/identities`, {
+        method: 'GET',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        throw new Error('Failed to fetch linked accounts');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Linked account error:', error);
+      throw error;
+    }
+  },
+
+  linkIdentity: async (provider) => {
+    try {
+      const response = await fetch(`${API_URL}/account/identities/link?provider=${encodeURIComponent(provider)}`, {
+        method: 'POST',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || 'Failed to link account');
+      }
+
+      const data = await response.json();
+      window.location.assign(data.auth_url);
+      return data;
+    } catch (error) {
+      console.error('Linked account error:', error);
+      throw error;
+    }
+  },
+
+  unlinkIdentity: async (provider, subject) => {
+    try {
+      const response = await fetch(
+        `${API_URL}/account/identities?provider=${encodeURIComponent(provider)}&subject=${encodeURIComponent(subject)}`,
+        {
+          method: 'DELETE',
+          credentials: 'include',
+        }
+      );
+
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || 'Failed to unlink account');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Linked account error:', error);
+      throw error;
+    }
+  },
+
+  listTokens: async () => {
+    try {
+      const response = await fetch(`${API_URL}/account/tokens`, {
+        method: 'GET',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        throw new Error('Failed to fetch API tokens');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('API token error:', error);
+      throw error;
+    }
+  },
+
+  // scope is 'read' or 'read-write'; the returned token is only shown once
+  createToken: async (name, scope, expiresInDays = 0) => {
+    try {
+      const response = await fetch(`${API_URL}/account/tokens`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ name, scope, expires_in_days: expiresInDays }),
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || 'Failed to create API token');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('API token error:', error);
+      throw error;
+    }
+  },
+
+  revokeToken: async (id) => {
+    try {
+      const response = await fetch(`${API_URL}/account/tokens?id=${encodeURIComponent(id)}`, {
+        method: 'DELETE',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        throw new Error('Failed to revoke API token');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('API token error:', error);
+      throw error;
+    }
+  },
+
+  resetPassword: async (token, newPassword) => {
+    try {
+      const response = await fetch(`${API_URL}/account/password/reset`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ token, new_password: newPassword }),
+      });
+
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || 'Password reset failed');
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Password reset error:', error);
+      throw error;
+    }
+  },
   
   // In src/services/api.js
 getAllDataCenters: async () => {
@@ -109,6 +336,157 @@ getAllDataCenters: async () => {
     }
   },
   
+  // Pareto frontier of candidate sites. filters may contain tier, objectives
+  // (comma-separated), max_cost, max_carbon, max_water, max_risk and frontier_only.
+  getParetoFrontier: async (filters = {}) => {
+    try {
+      const params = new URLSearchParams(filters);
+      const response = await fetch(`${API_URL}/api/pareto?${params.toString()}`, {
+        method: 'GET',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to fetch Pareto frontier: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Pareto frontier error:', error);
+      throw error;
+    }
+  },
+
+  // Start a new game (resets the cart). A seed of 0 picks a random one, and an
+  // empty carbon policy the server default (see /api/carbon-policies).
+  newGame: async (username, seed = 0, autoAdvance = false, carbonPolicy = '') => {
+    try {
+      const response = await fetch(`${API_URL}/game/new`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        credentials: 'include',
+        body: JSON.stringify({ username, seed, auto_advance: autoAdvance, carbon_policy: carbonPolicy }),
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to start game: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('New game error:', error);
+      throw error;
+    }
+  },
+
+  // Advance the game clock by a number of quarters.
+  advanceGame: async (username, ticks = 1) => {
+    try {
+      const response = await fetch(`${API_URL}/game/advance`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        credentials: 'include',
+        body: JSON.stringify({ username, ticks }),
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to advance game: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Advance game error:', error);
+      throw error;
+    }
+  },
+
+  // Finish the game: returns the final score, rank, objectives met and any
+  // achievements unlocked.
+  finishGame: async (username) => {
+    try {
+      const response = await fetch(`${API_URL}/game/finish`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        credentials: 'include',
+        body: JSON.stringify({ username }),
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to finish game: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Finish game error:', error);
+      throw error;
+    }
+  },
+
+  // Leaderboard of finished games. params may contain metric (score,
+  // eco_score, carbon_per_mw, budget_efficiency), scenario, seed, classroom,
+  // page and page_size.
+  getLeaderboard: async (params = {}) => {
+    try {
+      const query = new URLSearchParams(params);
+      const response = await fetch(`${API_URL}/api/leaderboard?${query.toString()}`, {
+        method: 'GET',
+        credentials: 'include',
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to fetch leaderboard: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Leaderboard error:', error);
+      throw error;
+    }
+  },
+
+  // Cover a share (0-1) of grid energy with renewable energy certificates.
+  setRECShare: async (username, share) => {
+    try {
+      const response = await fetch(`${API_URL}/game/recs`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        credentials: 'include',
+        body: JSON.stringify({ username, share }),
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to set REC share: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('REC share error:', error);
+      throw error;
+    }
+  },
+
+  // Order an upgrade for the facility at the given cart index. tier is the
+  // target tier of a tier upgrade (see /api/upgrades for the list).
+  orderUpgrade: async (username, index, upgrade, tier = '') => {
+    try {
+      const response = await fetch(`${API_URL}/cart/upgrade`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        credentials: 'include',
+        body: JSON.stringify({ username, index, upgrade, tier }),
+      });
+
+      if (!response.ok) {
+        throw new Error(`Failed to order upgrade: ${response.status}`);
+      }
+
+      return await response.json();
+    } catch (error) {
+      console.error('Upgrade error:', error);
+      throw error;
+    }
+  },
+
   // Add a new function to get detailed data for a specific location
   getLocationDetails: async (locationId) => {
     try {